@@ -10,75 +10,72 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
 
-	_ "github.com/cage1016/mask/cmd/docs/docs"
 	"github.com/cage1016/mask/internal/app/docs/transports"
+	appconfig "github.com/cage1016/mask/internal/pkg/config"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/openapi"
+	"github.com/cage1016/mask/internal/pkg/secrets"
 )
 
-const (
-	defServiceName string = "docs"
-	defLogLevel    string = "error"
-	defServiceHost string = "localhost"
-	defHTTPPort    string = "8180"
-	envServiceName string = "QS_DOCS_SERVICE_NAME"
-	envLogLevel    string = "QS_DOCS_LOG_LEVEL"
-	envServiceHost string = "QS_DOCS_SERVICE_HOST"
-	envHTTPPort    string = "PORT"
-)
+const envPrefix = "MASK_DOCS_"
 
 type config struct {
-	serviceName string
-	logLevel    string
-	serviceHost string
-	httpPort    string
+	ServiceName string        `config:"service_name" default:"docs"`
+	LogLevel    string        `config:"log_level" default:"error" oneof:"debug,info,warn,error,none"`
+	HTTPPort    string        `config:"port" env:"PORT" default:"8180" required:"true"`
+	PharmacyURL string        `config:"pharmacy_url" default:"localhost:8080" usage:"base URL of the pharmacy service"`
+	FeedbackURL string        `config:"feedback_url" default:"localhost:8080" usage:"base URL of the feedback service"`
+	SpecTimeout time.Duration `config:"spec_timeout" default:"5s" usage:"bound of fetching the OpenAPI document of every service"`
+	AdminToken  string        `config:"admin_token" secret:"true" usage:"bearer token guarding the admin endpoints"`
 }
 
-// Env reads specified environment variable. If no value has been found,
-// fallback is returned.
-func env(key string, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return fallback
+// info describes the document merged from the services.
+var info = openapi.Info{
+	Title:       "Mask API",
+	Description: "Backend API of mask.goodideas-studio.com.",
+	Contact: &openapi.Contact{
+		Name:  "API Support",
+		URL:   "https://github.com/tnstiger/mask-gdg/issues",
+		Email: "cage.chung@gmail.com",
+	},
+	License: &openapi.License{Name: "Apache 2.0", URL: "http://www.apache.org/licenses/LICENSE-2.0.html"},
 }
 
-// @title Mask API
-// @version 0.2.0
-// @description This is a Mask server celler server.
-// @termsOfService http://swagger.io/terms/
-
-// @contact.name API Support
-// @contact.url https://github.com/tnstiger/mask-gdg/issues
-// @contact.email cage.chung@gmail.com
-
-// @license.name Apache 2.0
-// @license.url http://www.apache.org/licenses/LICENSE-2.0.html
-
-// @host mask.goodideas-studio.com
-// @schemes https
-// @BasePath /
 func main() {
-	var logger log.Logger
-	{
-		logger = log.NewLogfmtLogger(os.Stderr)
-		logger = level.NewFilter(logger, level.AllowInfo())
-		logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-		logger = log.With(logger, "caller", log.DefaultCaller)
+	var cfg config
+	if err := appconfig.Load(&cfg, appconfig.Options{
+		Name:    os.Args[0],
+		Prefix:  envPrefix,
+		Args:    os.Args[1:],
+		Secrets: secrets.FromEnv(envPrefix),
+	}); err != nil {
+		if err == appconfig.ErrPrinted {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+		os.Exit(2)
 	}
-	cfg := loadConfig(logger)
-	logger = log.With(logger, "service", cfg.serviceName)
+
+	logger, logLevel, err := logging.New(os.Stderr, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %s\n", err)
+		os.Exit(1)
+	}
+	logger = log.With(logger, "service", cfg.ServiceName)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	//docs.SwaggerInfo.Host = cfg.serviceHost
-
-	h := transports.NewHTTPHandler()
+	// app.yaml names the services of the project the docs are deployed to
+	services := []string{os.ExpandEnv(cfg.PharmacyURL), os.ExpandEnv(cfg.FeedbackURL)}
+	h := transports.NewHTTPHandler(info, services, cfg.SpecTimeout, http.DefaultClient, logger)
+	h = logging.AdminHandler(logging.RequestIDHandler(h), logLevel, cfg.AdminToken, logger)
 
 	wg := &sync.WaitGroup{}
 
-	go startHTTPServer(ctx, wg, h, cfg.httpPort, logger)
+	go startHTTPServer(ctx, wg, h, cfg.HTTPPort, logger)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -90,14 +87,6 @@ func main() {
 	fmt.Println("main: all goroutines have told us they've finished")
 }
 
-func loadConfig(logger log.Logger) (cfg config) {
-	cfg.serviceName = env(envServiceName, defServiceName)
-	cfg.logLevel = env(envLogLevel, defLogLevel)
-	cfg.serviceHost = env(envServiceHost, defServiceHost)
-	cfg.httpPort = env(envHTTPPort, defHTTPPort)
-	return cfg
-}
-
 func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, handler http.Handler, port string, logger log.Logger) {
 	wg.Add(1)
 	defer wg.Done()
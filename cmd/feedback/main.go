@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,88 +11,122 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/gomurphyx/sqlx"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/cage1016/mask/internal/app/feedback/abuse"
 	"github.com/cage1016/mask/internal/app/feedback/endpoints"
 	"github.com/cage1016/mask/internal/app/feedback/nanoid"
 	feedbackPostgres "github.com/cage1016/mask/internal/app/feedback/postgres"
+	"github.com/cage1016/mask/internal/app/feedback/pseudonym"
 	"github.com/cage1016/mask/internal/app/feedback/service"
 	"github.com/cage1016/mask/internal/app/feedback/transports"
+	"github.com/cage1016/mask/internal/pkg/auth"
+	appconfig "github.com/cage1016/mask/internal/pkg/config"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
 	"github.com/cage1016/mask/internal/pkg/postgres"
+	"github.com/cage1016/mask/internal/pkg/secrets"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+	pb "github.com/cage1016/mask/pb/feedback"
+	"github.com/cage1016/mask/pkg/client"
 )
 
-const (
-	defServiceName   = "feedback"
-	defLogLevel      = "error"
-	defServiceHost   = "localhost"
-	defHTTPPort      = "8080"
-	defDBHost        = "/cloudsql/mask-9999:asia-east2:health-insurance-special-pharmacy"
-	defDBPort        = "5432"
-	defDBUser        = "postgres"
-	defDBPass        = "password"
-	defDBName        = "stores"
-	defDBSSLMode     = "disable"
-	defDBSSLCert     = ""
-	defDBSSLKey      = ""
-	defDBSSLRootCert = ""
-
-	envServiceName   = "MASK_FEEDBACK_SERVICE_NAME"
-	envLogLevel      = "MASK_FEEDBACK_LOG_LEVEL"
-	envServiceHost   = "MASK_FEEDBACK_SERVICE_HOST"
-	envHTTPPort      = "PORT"
-	envDBHost        = "MASK_FEEDBACK_DB_HOST"
-	envDBPort        = "MASK_FEEDBACK_DB_PORT"
-	envDBUser        = "MASK_FEEDBACK_DB_USER"
-	envDBPass        = "MASK_FEEDBACK_DB_PASS"
-	envDBName        = "MASK_FEEDBACK_DB"
-	envDBSSLMode     = "MASK_FEEDBACK_DB_SSL_MODE"
-	envDBSSLCert     = "MASK_FEEDBACK_DB_SSL_CERT"
-	envDBSSLKey      = "MASK_FEEDBACK_DB_SSL_KEY"
-	envDBSSLRootCert = "MASK_FEEDBACK_DB_SSL_ROOT_CERT"
-)
+const envPrefix = "MASK_FEEDBACK_"
 
 type config struct {
-	serviceName string
-	logLevel    string
-	serviceHost string
-	httpPort    string
-	dbConfig    postgres.Config
+	ServiceName    string          `config:"service_name" default:"feedback"`
+	LogLevel       string          `config:"log_level" default:"error" oneof:"debug,info,warn,error,none"`
+	ServiceHost    string          `config:"service_host" default:"localhost"`
+	HTTPPort       string          `config:"port" env:"PORT" default:"8080" required:"true"`
+	GRPCPort       string          `config:"grpc_port" usage:"port of the gRPC server for internal backends, disabled when empty"`
+	GRPCTLSCert    string          `config:"grpc_tls_cert" usage:"PEM certificate file the gRPC server serves TLS with, plaintext when empty"`
+	GRPCTLSKey     string          `config:"grpc_tls_key" usage:"PEM private key file of grpc_tls_cert"`
+	PseudonymKey   string          `config:"pseudonym_key" secret:"true" required:"true" usage:"HMAC key pseudonymizing user IDs in exports"`
+	TraceExporter  string          `config:"trace_exporter" oneof:",stdout,otlp"`
+	AdminToken     string          `config:"admin_token" secret:"true" usage:"bearer token guarding the admin endpoints and the export"`
+	PartitionDays  int             `config:"partition_days" default:"7" usage:"days of feedback partitions kept created ahead"`
+	PharmacyURL    string          `config:"pharmacy_url" usage:"base URL of the pharmacy service whose snapshot stock feedback is checked against, unchecked when empty"`
+	SnapshotTTL    time.Duration   `config:"snapshot_ttl" default:"1m" usage:"interval at which the pharmacy snapshot is fetched again"`
+	AbuseThreshold float64         `config:"abuse_threshold" default:"0.6" usage:"abuse score at which a user is shadow-banned"`
+	DB             postgres.Config `config:"db"`
 }
 
-// Env reads specified environment variable. If no value has been found,
-// fallback is returned.
-func env(key string, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// Validate requires the gRPC certificate and key together.
+func (c *config) Validate() error {
+	if (c.GRPCTLSCert == "") != (c.GRPCTLSKey == "") {
+		return errors.New("grpc_tls_cert and grpc_tls_key must be set together")
 	}
-	return fallback
+	return nil
 }
 
 func main() {
-	var logger log.Logger
-	{
-		logger = log.NewLogfmtLogger(os.Stderr)
-		logger = level.NewFilter(logger, level.AllowWarn())
-		logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-		logger = log.With(logger, "caller", log.DefaultCaller)
+	var cfg config
+	if err := appconfig.Load(&cfg, appconfig.Options{
+		Name:    os.Args[0],
+		Prefix:  envPrefix,
+		Args:    os.Args[1:],
+		Secrets: secrets.FromEnv(envPrefix),
+	}); err != nil {
+		if err == appconfig.ErrPrinted {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+		os.Exit(2)
 	}
-	cfg := loadConfig(logger)
-	logger = log.With(logger, "service", cfg.serviceName)
+
+	logger, logLevel, err := logging.New(os.Stderr, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %s\n", err)
+		os.Exit(1)
+	}
+	logger = log.With(logger, "service", cfg.ServiceName)
 	level.Info(logger).Log("version", service.Version, "commitHash", service.CommitHash, "buildTimeStamp", service.BuildTimeStamp)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	db := connectToDB(cfg.dbConfig, logger)
-	defer db.Close()
+	shutdownTracing, err := tracing.Init(ctx, cfg.ServiceName, cfg.TraceExporter)
+	if err != nil {
+		level.Error(logger).Log("method", "tracing.Init", "exporter", cfg.TraceExporter, "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
 
-	service := NewServer(db, logger)
-	endpoints := endpoints.New(service, logger)
+	db := connectToDB(ctx, cfg.DB, logger)
+	defer db.Close()
 
 	wg := &sync.WaitGroup{}
 
-	go startHTTPServer(ctx, wg, endpoints, cfg.httpPort, logger)
+	var stock abuse.Stock
+	if cfg.PharmacyURL != "" {
+		pharmacies, err := client.NewPharmacy(cfg.PharmacyURL, client.Config{})
+		if err != nil {
+			level.Error(logger).Log("pharmacy_url", cfg.PharmacyURL, "err", err)
+			os.Exit(1)
+		}
+		snapshot := abuse.NewSnapshotStock(pharmacies, cfg.SnapshotTTL, logger)
+		go refreshStock(ctx, wg, snapshot)
+		stock = snapshot
+	}
+
+	service := NewServer(db, cfg.PseudonymKey, stock, cfg.AbuseThreshold, logger)
+	endpoints := endpoints.New(service, cfg.AdminToken, logger)
+
+	grpcOptions, err := grpcServerOptions(cfg.AdminToken, cfg.GRPCTLSCert, cfg.GRPCTLSKey)
+	if err != nil {
+		level.Error(logger).Log("grpc_tls_cert", cfg.GRPCTLSCert, "err", err)
+		os.Exit(1)
+	}
+
+	h := logging.AdminHandler(transports.NewHTTPHandler(endpoints, cfg.AdminToken, logger), logLevel, cfg.AdminToken, logger)
+	go startHTTPServer(ctx, wg, h, cfg.HTTPPort, logger)
+	go startGRPCServer(ctx, wg, transports.NewGRPCServer(endpoints, logger), cfg.GRPCPort, grpcOptions, logger)
+	go maintainPartitions(ctx, wg, db, cfg.PartitionDays, logger)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -103,36 +138,15 @@ func main() {
 	fmt.Println("main: all goroutines have told us they've finished")
 }
 
-func loadConfig(logger log.Logger) (cfg config) {
-	dbConfig := postgres.Config{
-		Host:        env(envDBHost, defDBHost),
-		Port:        env(envDBPort, defDBPort),
-		User:        env(envDBUser, defDBUser),
-		Pass:        env(envDBPass, defDBPass),
-		Name:        env(envDBName, defDBName),
-		SSLMode:     env(envDBSSLMode, defDBSSLMode),
-		SSLCert:     env(envDBSSLCert, defDBSSLCert),
-		SSLKey:      env(envDBSSLKey, defDBSSLKey),
-		SSLRootCert: env(envDBSSLRootCert, defDBSSLRootCert),
-	}
-
-	cfg.dbConfig = dbConfig
-	cfg.serviceName = env(envServiceName, defServiceName)
-	cfg.logLevel = env(envLogLevel, defLogLevel)
-	cfg.serviceHost = env(envServiceHost, defServiceHost)
-	cfg.httpPort = env(envHTTPPort, defHTTPPort)
-	return cfg
-}
-
-func connectToDB(cfg postgres.Config, logger log.Logger) *sqlx.DB {
-	db, err := postgres.Connect(cfg)
+func connectToDB(ctx context.Context, cfg postgres.Config, logger log.Logger) *sqlx.DB {
+	db, err := postgres.Connect(ctx, cfg, logger)
 	if err != nil {
 		level.Error(logger).Log(
+			"driver", cfg.Driver,
 			"host", cfg.Host,
 			"port", cfg.Port,
 			"user", cfg.User,
 			"dbname", cfg.Name,
-			"password", cfg.Pass,
 			"sslmode", cfg.SSLMode,
 			"SSLCert", cfg.SSLCert,
 			"SSLKey", cfg.SSLKey,
@@ -144,14 +158,48 @@ func connectToDB(cfg postgres.Config, logger log.Logger) *sqlx.DB {
 	return db
 }
 
-func NewServer(db *sqlx.DB, logger log.Logger) service.FeedbacksvcService {
+func NewServer(db *sqlx.DB, pseudonymKey string, stock abuse.Stock, abuseThreshold float64, logger log.Logger) service.FeedbacksvcService {
 	repo := feedbackPostgres.New(db, logger)
 	idpNano := nanoid.New()
-	service := service.New(repo, idpNano, logger)
-	return service
+	pseudonyms := pseudonym.New(pseudonymKey)
+	suspects := feedbackPostgres.NewSuspectRepository(db, logger)
+	scorer := abuse.New(repo, suspects, stock, abuse.Config{Threshold: abuseThreshold})
+	svc := service.New(repo, suspects, idpNano, pseudonyms, scorer, logger)
+
+	stdprometheus.MustRegister(postgres.NewStatsCollector(db, "mask", "feedback"))
+	fieldKeys := []string{"method"}
+	svc = service.InstrumentingMiddleware(
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "mask",
+			Subsystem: "feedback",
+			Name:      "request_count",
+			Help:      "Number of requests received.",
+		}, fieldKeys),
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "mask",
+			Subsystem: "feedback",
+			Name:      "error_count",
+			Help:      "Number of requests that returned an error.",
+		}, fieldKeys),
+		kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: "mask",
+			Subsystem: "feedback",
+			Name:      "request_latency_seconds",
+			Help:      "Total duration of requests in seconds.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, fieldKeys),
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "mask",
+			Subsystem: "feedback",
+			Name:      "inserted_total",
+			Help:      "Number of feedbacks recorded.",
+		}, []string{}),
+	)(svc)
+	svc = service.TracingMiddleware(tracing.Tracer())(svc)
+	return svc
 }
 
-func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, endpoints endpoints.Endpoints, port string, logger log.Logger) {
+func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, handler http.Handler, port string, logger log.Logger) {
 	wg.Add(1)
 	defer wg.Done()
 
@@ -162,7 +210,7 @@ func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, endpoints endpoint
 
 	p := fmt.Sprintf(":%s", port)
 	// create a server
-	srv := &http.Server{Addr: p, Handler: transports.NewHTTPHandler(endpoints, logger)}
+	srv := &http.Server{Addr: p, Handler: handler}
 	level.Info(logger).Log("protocol", "HTTP", "exposed", port)
 	go func() {
 		// service connections
@@ -182,3 +230,85 @@ func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, endpoints endpoint
 
 	level.Info(logger).Log("protocol", "HTTP", "Shutdown", "http server gracefully stopped")
 }
+
+// grpcServerOptions traces every gRPC call, tags it with a request ID and
+// requires the admin token, as the gRPC server serves internal backends only.
+// It serves TLS when a certificate is configured.
+func grpcServerOptions(adminToken, certFile, keyFile string) ([]grpc.ServerOption, error) {
+	opts := []grpc.ServerOption{
+		tracing.GRPCServerOption(),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(), auth.UnaryServerInterceptor(adminToken)),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(), auth.StreamServerInterceptor(adminToken)),
+	}
+	if certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	return opts, nil
+}
+
+func startGRPCServer(ctx context.Context, wg *sync.WaitGroup, server pb.FeedbackServiceServer, port string, opts []grpc.ServerOption, logger log.Logger) {
+	wg.Add(1)
+	defer wg.Done()
+
+	if port == "" {
+		level.Info(logger).Log("protocol", "GRPC", "exposed", port, "msg", "gRPC server disabled")
+		return
+	}
+
+	p := fmt.Sprintf(":%s", port)
+	listener, err := net.Listen("tcp", p)
+	if err != nil {
+		level.Error(logger).Log("protocol", "GRPC", "listen", port, "err", err)
+		return
+	}
+
+	srv := grpc.NewServer(opts...)
+	pb.RegisterFeedbackServiceServer(srv, server)
+	level.Info(logger).Log("protocol", "GRPC", "exposed", port)
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			level.Info(logger).Log("Serve", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	srv.GracefulStop()
+
+	level.Info(logger).Log("protocol", "GRPC", "Shutdown", "grpc server gracefully stopped")
+}
+
+// maintainPartitions keeps the daily feedback partitions created days ahead,
+// so inserts never wait on DDL.
+func maintainPartitions(ctx context.Context, wg *sync.WaitGroup, db *sqlx.DB, days int, logger log.Logger) {
+	wg.Add(1)
+	defer wg.Done()
+
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := feedbackPostgres.CreatePartitions(ctx, db, time.Now(), days); err != nil {
+			level.Error(logger).Log("method", "feedbackPostgres.CreatePartitions", "days", days, "err", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshStock fetches the pharmacy snapshot every ttl, so scoring a report
+// never waits on the pharmacy service.
+func refreshStock(ctx context.Context, wg *sync.WaitGroup, stock *abuse.SnapshotStock) {
+	wg.Add(1)
+	defer wg.Done()
+
+	stock.Run(ctx)
+}
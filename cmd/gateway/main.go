@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/cage1016/mask/internal/app/gateway/graph"
+	"github.com/cage1016/mask/internal/app/gateway/transports"
+	appconfig "github.com/cage1016/mask/internal/pkg/config"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/secrets"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+	"github.com/cage1016/mask/pkg/client"
+)
+
+const envPrefix = "MASK_GATEWAY_"
+
+type config struct {
+	ServiceName    string        `config:"service_name" default:"gateway"`
+	LogLevel       string        `config:"log_level" default:"error" oneof:"debug,info,warn,error,none"`
+	HTTPPort       string        `config:"port" env:"PORT" default:"8280" required:"true"`
+	PharmacyURL    string        `config:"pharmacy_url" default:"localhost:8080" usage:"base URL of the pharmacy service, environment variables such as ${GOOGLE_CLOUD_PROJECT} expanded"`
+	FeedbackURL    string        `config:"feedback_url" default:"localhost:8080" usage:"base URL of the feedback service, environment variables such as ${GOOGLE_CLOUD_PROJECT} expanded"`
+	BackendTimeout time.Duration `config:"backend_timeout" default:"10s" usage:"bound of every call to the pharmacy and feedback services"`
+	IndexTTL       time.Duration `config:"index_ttl" default:"1m" usage:"age after which the pharmacy export backing lookups by id is downloaded again"`
+	TraceExporter  string        `config:"trace_exporter" oneof:",stdout,otlp"`
+	AdminToken     string        `config:"admin_token" secret:"true" usage:"bearer token guarding the admin endpoints"`
+}
+
+func main() {
+	var cfg config
+	if err := appconfig.Load(&cfg, appconfig.Options{
+		Name:    os.Args[0],
+		Prefix:  envPrefix,
+		Args:    os.Args[1:],
+		Secrets: secrets.FromEnv(envPrefix),
+	}); err != nil {
+		if err == appconfig.ErrPrinted {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+		os.Exit(2)
+	}
+
+	logger, logLevel, err := logging.New(os.Stderr, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %s\n", err)
+		os.Exit(1)
+	}
+	logger = log.With(logger, "service", cfg.ServiceName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shutdownTracing, err := tracing.Init(ctx, cfg.ServiceName, cfg.TraceExporter)
+	if err != nil {
+		level.Error(logger).Log("method", "tracing.Init", "exporter", cfg.TraceExporter, "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Backend calls carry the trace of the GraphQL request they resolve.
+	backend := client.Config{
+		Timeout:    cfg.BackendTimeout,
+		HTTPClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+	// app.yaml names the backends of the project the gateway is deployed to
+	cfg.PharmacyURL, cfg.FeedbackURL = os.ExpandEnv(cfg.PharmacyURL), os.ExpandEnv(cfg.FeedbackURL)
+	pharmacies, err := client.NewPharmacy(cfg.PharmacyURL, backend)
+	if err != nil {
+		level.Error(logger).Log("pharmacy_url", cfg.PharmacyURL, "err", err)
+		os.Exit(1)
+	}
+	feedback, err := client.NewFeedback(cfg.FeedbackURL, backend)
+	if err != nil {
+		level.Error(logger).Log("feedback_url", cfg.FeedbackURL, "err", err)
+		os.Exit(1)
+	}
+
+	resolver := graph.NewResolver(pharmacies, feedback, cfg.IndexTTL)
+	h := logging.AdminHandler(transports.NewHTTPHandler(resolver, logger), logLevel, cfg.AdminToken, logger)
+
+	wg := &sync.WaitGroup{}
+
+	go startHTTPServer(ctx, wg, h, cfg.HTTPPort, logger)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	<-c
+
+	cancel()
+	wg.Wait()
+
+	fmt.Println("main: all goroutines have told us they've finished")
+}
+
+func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, handler http.Handler, port string, logger log.Logger) {
+	wg.Add(1)
+	defer wg.Done()
+
+	if port == "" {
+		level.Error(logger).Log("protocol", "HTTP", "exposed", port, "err", "port is not assigned exist")
+		return
+	}
+
+	p := fmt.Sprintf(":%s", port)
+	// create a server
+	srv := &http.Server{Addr: p, Handler: handler}
+	level.Info(logger).Log("protocol", "HTTP", "exposed", port)
+	go func() {
+		// service connections
+		if err := srv.ListenAndServe(); err != nil {
+			level.Info(logger).Log("Listen", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	// shut down gracefully, but wait no longer than 5 seconds before halting
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// ignore error since it will be "Err shutting down server : context canceled"
+	srv.Shutdown(shutdownCtx)
+
+	level.Info(logger).Log("protocol", "HTTP", "Shutdown", "http server gracefully stopped")
+}
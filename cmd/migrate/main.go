@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	appconfig "github.com/cage1016/mask/internal/pkg/config"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	psql "github.com/cage1016/mask/internal/pkg/postgres"
+	"github.com/cage1016/mask/internal/pkg/secrets"
+)
+
+const (
+	envPrefix = "MASK_MIGRATE_"
+
+	usage = `usage: migrate [flags] <command>
+
+commands:
+  status         list migrations and when they were applied
+  up [N]         apply N pending migrations, all when N is omitted
+  down N         revert the N applied migrations with the greatest IDs;
+                 prefixes sort by name, so every pharmacy_ migration is
+                 reverted before any feedback_ one
+  redo           revert and re-apply the applied migration with the greatest ID
+  new <prefix>   create <dir>/<prefix>_<next>.sql, <next> zero-padded to 4 digits
+`
+
+	migrationTemplate = `-- +migrate Up
+
+-- +migrate Down
+`
+)
+
+var errUsage = errors.New("invalid arguments")
+
+type config struct {
+	LogLevel string      `config:"log_level" default:"warn" oneof:"debug,info,warn,error,none"`
+	Dir      string      `config:"dir" default:"internal/pkg/postgres/migrations" usage:"directory new migrations are written to"`
+	DB       psql.Config `config:"db"`
+}
+
+func main() {
+	var (
+		cfg  config
+		args []string
+	)
+	if err := appconfig.Load(&cfg, appconfig.Options{
+		Name:       os.Args[0],
+		Prefix:     envPrefix,
+		Args:       os.Args[1:],
+		Positional: &args,
+		Secrets:    secrets.FromEnv(envPrefix),
+	}); err != nil {
+		if err == appconfig.ErrPrinted {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+		os.Exit(2)
+	}
+
+	logger, _, err := logging.New(os.Stderr, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %s\n", err)
+		os.Exit(1)
+	}
+	logger = log.With(logger, "service", "migrate")
+
+	if err := run(context.Background(), cfg, args, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		if err == errUsage {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg config, args []string, logger log.Logger) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var (
+		cmd = args[0]
+		n   int
+		err error
+	)
+	switch cmd {
+	case "new":
+		// new only touches the filesystem
+		if len(args) != 2 {
+			return errUsage
+		}
+		return newMigration(cfg.Dir, args[1])
+	case "status", "redo":
+		if len(args) != 1 {
+			return errUsage
+		}
+	case "up":
+		if len(args) > 2 {
+			return errUsage
+		}
+		if len(args) == 2 {
+			if n, err = count(args[1]); err != nil {
+				return err
+			}
+		}
+	case "down":
+		if len(args) != 2 {
+			return errUsage
+		}
+		if n, err = count(args[1]); err != nil {
+			return err
+		}
+	default:
+		return errUsage
+	}
+
+	db, err := psql.Open(ctx, cfg.DB, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch cmd {
+	case "status":
+		return status(db.DB)
+	case "up":
+		applied, err := psql.MigrateUp(db.DB, n)
+		fmt.Printf("applied %d migrations\n", applied)
+		return err
+	case "down":
+		reverted, err := psql.MigrateDown(db.DB, n)
+		fmt.Printf("reverted %d migrations\n", reverted)
+		return err
+	default:
+		if _, err := psql.MigrateDown(db.DB, 1); err != nil {
+			return err
+		}
+		_, err := psql.MigrateUp(db.DB, 1)
+		return err
+	}
+}
+
+// count parses the N argument, which must be positive.
+func count(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, errUsage
+	}
+	return n, nil
+}
+
+func status(db *sql.DB) error {
+	migrations, err := psql.Status(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tAPPLIED")
+	for _, m := range migrations {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", m.ID, applied)
+	}
+	return w.Flush()
+}
+
+func newMigration(dir, prefix string) error {
+	if !regexp.MustCompile(`^[a-z][a-z0-9]*$`).MatchString(prefix) {
+		return errors.New("prefix must be lower case letters and digits")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, prefix+"_*.sql"))
+	if err != nil {
+		return err
+	}
+	next := 1
+	re := regexp.MustCompile(`_(\d+)\.sql$`)
+	for _, f := range files {
+		if m := re.FindStringSubmatch(f); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n >= next {
+				next = n + 1
+			}
+		}
+	}
+	name := fmt.Sprintf("%s_%0*d.sql", prefix, psql.MigrationDigits, next)
+	if len(name) != len(prefix)+1+psql.MigrationDigits+len(".sql") {
+		return errors.New(fmt.Sprintf("%s has run out of %d digit migration numbers, start a new prefix", prefix, psql.MigrationDigits))
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(migrationTemplate), 0644); err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
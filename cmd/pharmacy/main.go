@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,88 +12,120 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/gomurphyx/sqlx"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/nanoid"
+	"github.com/cage1016/mask/internal/app/pharmacy/notify"
 	"github.com/cage1016/mask/internal/app/pharmacy/postgres"
 	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/app/pharmacy/stream"
 	"github.com/cage1016/mask/internal/app/pharmacy/transports"
+	"github.com/cage1016/mask/internal/app/pharmacy/webhook"
+	"github.com/cage1016/mask/internal/pkg/auth"
+	appconfig "github.com/cage1016/mask/internal/pkg/config"
+	"github.com/cage1016/mask/internal/pkg/errors"
 	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
 	psql "github.com/cage1016/mask/internal/pkg/postgres"
+	"github.com/cage1016/mask/internal/pkg/secrets"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+	pb "github.com/cage1016/mask/pb/pharmacy"
 )
 
 const (
-	defServiceName   = "pharmacy"
-	defLogLevel      = "error"
-	defServiceHost   = "localhost"
-	defHTTPPort      = "8080"
-	defDBHost        = ""
-	defDBPort        = ""
-	defDBUser        = ""
-	defDBPass        = ""
-	defDBName        = ""
-	defDBSSLMode     = "disable"
-	defDBSSLCert     = ""
-	defDBSSLKey      = ""
-	defDBSSLRootCert = ""
-
-	envServiceName   = "SERVICE_NAME"
-	envLogLevel      = "LOG_LEVEL"
-	envServiceHost   = "SERVICE_HOST"
-	envHTTPPort      = "PORT"
-	envDBHost        = "DB_HOST"
-	envDBPort        = "DB_PORT"
-	envDBUser        = "DB_USER"
-	envDBPass        = "DB_PASS"
-	envDBName        = "DB"
-	envDBSSLMode     = "DB_SSL_MODE"
-	envDBSSLCert     = "DB_SSL_CERT"
-	envDBSSLKey      = "DB_SSL_KEY"
-	envDBSSLRootCert = "DB_SSL_ROOT_CERT"
+	envPrefix = "MASK_PHARMACY_"
+
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 5
+	webhookBackoff     = 2 * time.Second
 )
 
 type config struct {
-	serviceName string
-	logLevel    string
-	serviceHost string
-	httpPort    string
-	dbConfig    psql.Config
+	ServiceName   string      `config:"service_name" default:"pharmacy"`
+	LogLevel      string      `config:"log_level" default:"error" oneof:"debug,info,warn,error,none"`
+	ServiceHost   string      `config:"service_host" default:"localhost"`
+	HTTPPort      string      `config:"port" env:"PORT" default:"8080" required:"true"`
+	GRPCPort      string      `config:"grpc_port" usage:"port of the gRPC server for internal backends, disabled when empty"`
+	GRPCTLSCert   string      `config:"grpc_tls_cert" usage:"PEM certificate file the gRPC server serves TLS with, plaintext when empty"`
+	GRPCTLSKey    string      `config:"grpc_tls_key" usage:"PEM private key file of grpc_tls_cert"`
+	NotifyFile    string      `config:"notify_file" usage:"append watch notifications to this file instead of logging them"`
+	TraceExporter string      `config:"trace_exporter" oneof:",stdout,otlp"`
+	AdminToken    string      `config:"admin_token" secret:"true" usage:"bearer token guarding the admin endpoints"`
+	Repository    string      `config:"repository" default:"earthdistance" oneof:"earthdistance,postgis" usage:"implementation of the geographic pharmacy queries"`
+	DB            psql.Config `config:"db"`
 }
 
-// Env reads specified environment variable. If no value has been found,
-// fallback is returned.
-func env(key string, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// Validate requires the gRPC certificate and key together.
+func (c *config) Validate() error {
+	if (c.GRPCTLSCert == "") != (c.GRPCTLSKey == "") {
+		return errors.New("grpc_tls_cert and grpc_tls_key must be set together")
 	}
-	return fallback
+	return nil
 }
 
 func main() {
-	var logger log.Logger
-	{
-		logger = log.NewJSONLogger(os.Stderr)
-		logger = level.NewFilter(logger, level.AllowWarn())
-		logger = log.With(logger, "timestamp", log.DefaultTimestampUTC)
-		logger = log.With(logger, "caller", log.DefaultCaller)
-	}
-	cfg := loadConfig(logger)
-	logger = log.With(logger, "service", cfg.serviceName)
+	var cfg config
+	if err := appconfig.Load(&cfg, appconfig.Options{
+		Name:    os.Args[0],
+		Prefix:  envPrefix,
+		Args:    os.Args[1:],
+		Secrets: secrets.FromEnv(envPrefix),
+	}); err != nil {
+		if err == appconfig.ErrPrinted {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+		os.Exit(2)
+	}
+
+	logger, logLevel, err := logging.New(os.Stderr, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %s\n", err)
+		os.Exit(1)
+	}
+	logger = log.With(logger, "service", cfg.ServiceName)
 	level.Info(logger).Log("version", service.Version, "commitHash", service.CommitHash, "buildTimeStamp", service.BuildTimeStamp)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	db := connectToDB(cfg.dbConfig, logger)
+	shutdownTracing, err := tracing.Init(ctx, cfg.ServiceName, cfg.TraceExporter)
+	if err != nil {
+		level.Error(logger).Log("method", "tracing.Init", "exporter", cfg.TraceExporter, "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	db := connectToDB(ctx, cfg.DB, logger)
 	defer db.Close()
 
-	svc := NewServer(db, logger)
-	eps := endpoints.New(svc, logger)
+	repo := newPharmacyRepository(ctx, cfg.Repository, db, logger)
+	hub := stream.New()
+	webhooks := postgres.NewWebhookRepository(db, logger)
+	dispatcher := webhook.New(webhooks, webhook.NewClient(webhookTimeout), webhookMaxAttempts, webhookBackoff, logger)
+	svc := NewServer(db, repo, webhooks, dispatcher, cfg.NotifyFile, hub, logger)
+	eps := endpoints.New(svc, cfg.AdminToken, logger)
+
+	grpcOptions, err := grpcServerOptions(cfg.AdminToken, cfg.GRPCTLSCert, cfg.GRPCTLSKey)
+	if err != nil {
+		level.Error(logger).Log("grpc_tls_cert", cfg.GRPCTLSCert, "err", err)
+		os.Exit(1)
+	}
 
 	wg := &sync.WaitGroup{}
 
-	go startHTTPServer(ctx, wg, eps, cfg.httpPort, logger)
+	h := logging.AdminHandler(transports.NewHTTPHandler(eps, cfg.AdminToken, logger), logLevel, cfg.AdminToken, logger)
+	go startHTTPServer(ctx, wg, h, cfg.HTTPPort, logger)
+	go startGRPCServer(ctx, wg, transports.NewGRPCServer(eps, logger), cfg.GRPCPort, grpcOptions, logger)
 	go tickerFunc(ctx, wg, svc, logger)
+	go dispatchWebhooks(ctx, wg, dispatcher)
+	go listenFeedback(ctx, wg, cfg.DB, hub, logger)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -103,36 +137,15 @@ func main() {
 	fmt.Println("main: all goroutines have told us they've finished")
 }
 
-func loadConfig(_ log.Logger) (cfg config) {
-	dbConfig := psql.Config{
-		Host:        env(envDBHost, defDBHost),
-		Port:        env(envDBPort, defDBPort),
-		User:        env(envDBUser, defDBUser),
-		Pass:        env(envDBPass, defDBPass),
-		Name:        env(envDBName, defDBName),
-		SSLMode:     env(envDBSSLMode, defDBSSLMode),
-		SSLCert:     env(envDBSSLCert, defDBSSLCert),
-		SSLKey:      env(envDBSSLKey, defDBSSLKey),
-		SSLRootCert: env(envDBSSLRootCert, defDBSSLRootCert),
-	}
-
-	cfg.dbConfig = dbConfig
-	cfg.serviceName = env(envServiceName, defServiceName)
-	cfg.logLevel = env(envLogLevel, defLogLevel)
-	cfg.serviceHost = env(envServiceHost, defServiceHost)
-	cfg.httpPort = env(envHTTPPort, defHTTPPort)
-	return cfg
-}
-
-func connectToDB(cfg psql.Config, logger log.Logger) *sqlx.DB {
-	db, err := psql.Connect(cfg)
+func connectToDB(ctx context.Context, cfg psql.Config, logger log.Logger) *sqlx.DB {
+	db, err := psql.Connect(ctx, cfg, logger)
 	if err != nil {
 		level.Error(logger).Log(
+			"driver", cfg.Driver,
 			"host", cfg.Host,
 			"port", cfg.Port,
 			"user", cfg.User,
 			"dbname", cfg.Name,
-			"password", cfg.Pass,
 			"sslmode", cfg.SSLMode,
 			"SSLCert", cfg.SSLCert,
 			"SSLKey", cfg.SSLKey,
@@ -144,12 +157,65 @@ func connectToDB(cfg psql.Config, logger log.Logger) *sqlx.DB {
 	return db
 }
 
-func NewServer(db *sqlx.DB, logger log.Logger) service.PharmacyService {
-	repo := postgres.New(db, logger)
-	return service.New(repo, logger)
+func newPharmacyRepository(ctx context.Context, kind string, db *sqlx.DB, logger log.Logger) model.PharmacyRepository {
+	if kind != "postgis" {
+		return postgres.New(db, logger)
+	}
+
+	repo, err := postgres.NewPostGIS(ctx, db, logger)
+	if err != nil {
+		level.Error(logger).Log("method", "postgres.NewPostGIS", "err", err)
+		os.Exit(1)
+	}
+	return repo
 }
 
-func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, endpoints endpoints.Endpoints, port string, logger log.Logger) {
+func NewServer(db *sqlx.DB, repo model.PharmacyRepository, webhooks model.WebhookRepository, publisher service.EventPublisher, notifyFile string, hub service.Hub, logger log.Logger) service.PharmacyService {
+	watches := postgres.NewWatchRepository(db, logger)
+	idpNano := nanoid.New()
+
+	var sender service.NotificationSender
+	if notifyFile != "" {
+		sender = notify.NewFileSender(notifyFile)
+	} else {
+		sender = notify.NewLogSender(logger)
+	}
+	svc := service.New(repo, webhooks, watches, idpNano, publisher, sender, hub, logger)
+
+	stdprometheus.MustRegister(psql.NewStatsCollector(db, "mask", "pharmacy"))
+	fieldKeys := []string{"method"}
+	svc = service.InstrumentingMiddleware(
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "mask",
+			Subsystem: "pharmacy",
+			Name:      "request_count",
+			Help:      "Number of requests received.",
+		}, fieldKeys),
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "mask",
+			Subsystem: "pharmacy",
+			Name:      "error_count",
+			Help:      "Number of requests that returned an error.",
+		}, fieldKeys),
+		kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: "mask",
+			Subsystem: "pharmacy",
+			Name:      "request_latency_seconds",
+			Help:      "Total duration of requests in seconds.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, fieldKeys),
+		kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "mask",
+			Subsystem: "pharmacy",
+			Name:      "snapshot_age_seconds",
+			Help:      "Age of the latest pharmacy snapshot in seconds.",
+		}, []string{}),
+	)(svc)
+	svc = service.TracingMiddleware(tracing.Tracer())(svc)
+	return svc
+}
+
+func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, handler http.Handler, port string, logger log.Logger) {
 	wg.Add(1)
 	defer wg.Done()
 
@@ -160,7 +226,7 @@ func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, endpoints endpoint
 
 	p := fmt.Sprintf(":%s", port)
 	// create a server
-	srv := &http.Server{Addr: p, Handler: transports.NewHTTPHandler(endpoints, logger)}
+	srv := &http.Server{Addr: p, Handler: handler}
 	level.Info(logger).Log("protocol", "HTTP", "exposed", port)
 	go func() {
 		// service connections
@@ -181,6 +247,57 @@ func startHTTPServer(ctx context.Context, wg *sync.WaitGroup, endpoints endpoint
 	level.Info(logger).Log("protocol", "HTTP", "Shutdown", "http server gracefully stopped")
 }
 
+// grpcServerOptions traces every gRPC call, tags it with a request ID and
+// requires the admin token, as the gRPC server serves internal backends only.
+// It serves TLS when a certificate is configured.
+func grpcServerOptions(adminToken, certFile, keyFile string) ([]grpc.ServerOption, error) {
+	opts := []grpc.ServerOption{
+		tracing.GRPCServerOption(),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(), auth.UnaryServerInterceptor(adminToken)),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(), auth.StreamServerInterceptor(adminToken)),
+	}
+	if certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	return opts, nil
+}
+
+func startGRPCServer(ctx context.Context, wg *sync.WaitGroup, server pb.PharmacyServiceServer, port string, opts []grpc.ServerOption, logger log.Logger) {
+	wg.Add(1)
+	defer wg.Done()
+
+	if port == "" {
+		level.Info(logger).Log("protocol", "GRPC", "exposed", port, "msg", "gRPC server disabled")
+		return
+	}
+
+	p := fmt.Sprintf(":%s", port)
+	listener, err := net.Listen("tcp", p)
+	if err != nil {
+		level.Error(logger).Log("protocol", "GRPC", "listen", port, "err", err)
+		return
+	}
+
+	srv := grpc.NewServer(opts...)
+	pb.RegisterPharmacyServiceServer(srv, server)
+	level.Info(logger).Log("protocol", "GRPC", "exposed", port)
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			level.Info(logger).Log("Serve", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	srv.GracefulStop()
+
+	level.Info(logger).Log("protocol", "GRPC", "Shutdown", "grpc server gracefully stopped")
+}
+
 func tickerFunc(ctx context.Context, wg *sync.WaitGroup, svc service.PharmacyService, logger log.Logger) {
 	wg.Add(1)
 	// tell the caller we've stopped
@@ -199,3 +316,44 @@ func tickerFunc(ctx context.Context, wg *sync.WaitGroup, svc service.PharmacySer
 		}
 	}
 }
+
+// dispatchWebhooks delivers the stock events of new snapshots until ctx is
+// done.
+func dispatchWebhooks(ctx context.Context, wg *sync.WaitGroup, dispatcher *webhook.Dispatcher) {
+	wg.Add(1)
+	defer wg.Done()
+
+	dispatcher.Run(ctx)
+}
+
+// listenFeedback forwards feedback announced by the feedback service to the
+// live stream hub.
+func listenFeedback(ctx context.Context, wg *sync.WaitGroup, cfg psql.Config, hub service.Hub, logger log.Logger) {
+	wg.Add(1)
+	defer wg.Done()
+
+	listener, err := psql.Listen(cfg, psql.FeedbackChannel)
+	if err != nil {
+		level.Error(logger).Log("method", "psql.Listen", "err", err)
+		return
+	}
+	defer listener.Close()
+
+	for {
+		select {
+		case n := <-listener.Notify:
+			// nil is sent after the listener reconnected
+			if n == nil {
+				continue
+			}
+			var e model.FeedbackEvent
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				level.Error(logger).Log("method", "json.Unmarshal", "err", err)
+				continue
+			}
+			hub.Feedback(e)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
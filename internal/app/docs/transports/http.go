@@ -1,20 +1,97 @@
 package transports
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-kit/kit/log"
 	"github.com/go-zoo/bone"
 	"github.com/rs/cors"
-
 	httpSwagger "github.com/swaggo/http-swagger"
+
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/openapi"
 )
 
-// NewHTTPHandler returns a handler that makes a set of endpoints available on
-// predefined paths.
-func NewHTTPHandler() http.Handler { // Zipkin HTTP Server Trace can either be instantiated per endpoint with a
+// specPath is the path of the merged document, relative to which the
+// Swagger UI loads it.
+const specPath = "/docs/openapi.json"
+
+// NewHTTPHandler returns a handler that serves at /docs/openapi.json the
+// OpenAPI documents of services, each a base URL serving /openapi.json,
+// merged into one described by info, and the Swagger UI over it.
+func NewHTTPHandler(info openapi.Info, services []string, timeout time.Duration, client *http.Client, logger log.Logger) http.Handler {
+	spec := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		docs := fetchDocuments(ctx, client, services, logging.WithRequestID(r.Context(), logger))
+		openapi.Handler(openapi.Merge(info, docs...)).ServeHTTP(w, r)
+	})
+
 	m := bone.New()
-	m.Get("/*", httpSwagger.Handler(
-		httpSwagger.URL("https://mask.goodideas-studio.com/docs/doc.json"), //The url pointing to API definition"
-	))
+	m.Get(specPath, spec)
+	// doc.json is where the Swagger UI looked for the swag generated spec.
+	m.Get("/docs/doc.json", spec)
+	m.Get("/*", httpSwagger.Handler(httpSwagger.URL(specPath)))
 	return cors.AllowAll().Handler(m)
 }
+
+// fetchDocuments fetches the documents of services concurrently, in the
+// order of services. A service that does not answer is left out, so the
+// others are still documented.
+func fetchDocuments(ctx context.Context, client *http.Client, services []string, logger log.Logger) []*openapi.Document {
+	docs := make([]*openapi.Document, len(services))
+	var wg sync.WaitGroup
+	for i, s := range services {
+		wg.Add(1)
+		go func(i int, s string) {
+			defer wg.Done()
+			doc, err := fetchDocument(ctx, client, s)
+			if err != nil {
+				level.Warn(logger).Log("method", "fetchDocument", "service", s, "err", err)
+				return
+			}
+			docs[i] = doc
+		}(i, s)
+	}
+	wg.Wait()
+
+	out := docs[:0]
+	for _, doc := range docs {
+		if doc != nil {
+			out = append(out, doc)
+		}
+	}
+	return out
+}
+
+func fetchDocument(ctx context.Context, client *http.Client, service string) (*openapi.Document, error) {
+	if !strings.HasPrefix(service, "http") {
+		service = "http://" + service
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(service, "/")+"/openapi.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	var doc openapi.Document
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
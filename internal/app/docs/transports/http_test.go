@@ -0,0 +1,61 @@
+package transports
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	feedback "github.com/cage1016/mask/internal/app/feedback/transports"
+	pharmacy "github.com/cage1016/mask/internal/app/pharmacy/transports"
+	"github.com/cage1016/mask/internal/pkg/openapi"
+)
+
+func serve(t *testing.T, doc *openapi.Document) string {
+	srv := httptest.NewServer(openapi.Handler(doc))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestMergedDocument(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	p, f := pharmacy.OpenAPI(), feedback.OpenAPI()
+	services := []string{serve(t, p), down.URL, serve(t, f)}
+	h := NewHTTPHandler(openapi.Info{Title: "Mask API"}, services, time.Second, http.DefaultClient, log.NewNopLogger())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/api/pharmacies", "/api/v2/pharmacies/watches/{user_id}", "/api/feedback", "/api/v2/feedback/pharmacies/{pharmacy_id}"} {
+		if _, ok := doc.Paths[p]; !ok {
+			t.Errorf("got no path %s", p)
+		}
+	}
+	for _, s := range []string{"pharmacy.model.Pharmacy", "feedback.model.Feedback"} {
+		if _, ok := doc.Components.Schemas[s]; !ok {
+			t.Errorf("got no schema %s", s)
+		}
+	}
+	if want := p.Info.Title + " " + p.Info.Version + ", " + f.Info.Title + " " + f.Info.Version; doc.Info.Version != want {
+		t.Errorf("got version %q, want %q", doc.Info.Version, want)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/index.html", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Errorf("swagger ui: got status %d, want the UI over %s", rec.Code, specPath)
+	}
+}
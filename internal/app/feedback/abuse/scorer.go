@@ -0,0 +1,247 @@
+// Package abuse scores feedback for coordinated or contradictory reporting,
+// so the reports of suspicious users can be shadow-banned: still stored, but
+// no longer listed on pharmacies or streamed.
+package abuse
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+// Claim is what an option claims about the stock of a pharmacy.
+type Claim int
+
+const (
+	// None claims nothing, e.g. custom feedback.
+	None Claim = iota
+	// Out claims the masks are gone.
+	Out
+	// In claims the masks are still handed out.
+	In
+)
+
+// DefaultClaims are the claims of the options seeded by the migrations.
+var DefaultClaims = map[string]Claim{
+	"ddCp1m88O4g5SU1GDJRPi": Out, // 當天已售完
+	"uYrYL~7Gd65IN2wWsWa9A": Out, // 號碼牌已發送完畢
+	"nAn6pj8UkrXST1syShrzV": In,  // 發放號碼牌
+}
+
+// Weights of the signals in the score of a report.
+const (
+	stockWeight   = 0.4
+	peerWeight    = 0.35
+	historyWeight = 0.25
+)
+
+const (
+	// pageLimit bounds the feedback read for a signal.
+	pageLimit = 100
+	// earthRadiusKm is the mean radius of the earth.
+	earthRadiusKm = 6371.0
+	// minTravelKm ignores moves within a neighbourhood, which GPS noise and
+	// reports seconds apart would otherwise turn into impossible speeds.
+	minTravelKm = 1.0
+)
+
+// Config tunes the scorer. Zero fields take their defaults.
+type Config struct {
+	// Claims maps option ids to their claims, DefaultClaims by default.
+	Claims map[string]Claim
+	// Window is how recent the reports of other users must be to disagree,
+	// 2h by default.
+	Window time.Duration
+	// MinPeers is the number of other users a report must be compared with
+	// for disagreement to count, 2 by default.
+	MinPeers int
+	// DailyReports is the number of reports a day beyond which a user looks
+	// like a bot, 10 by default.
+	DailyReports uint64
+	// MaxSpeed in km/h is the fastest a user travels between reports,
+	// 120 by default.
+	MaxSpeed float64
+	// Plenty is the stock a sold out claim contradicts, 100 by default.
+	Plenty uint64
+	// Alpha weighs a report against the past score of its user, 0.3 by
+	// default.
+	Alpha float64
+	// Threshold is the score at which a user is banned, 0.6 by default.
+	Threshold float64
+	// MinReports is the number of reports a user is scored on before being
+	// banned, 3 by default.
+	MinReports uint64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Claims == nil {
+		c.Claims = DefaultClaims
+	}
+	if c.Window <= 0 {
+		c.Window = 2 * time.Hour
+	}
+	if c.MinPeers <= 0 {
+		c.MinPeers = 2
+	}
+	if c.DailyReports == 0 {
+		c.DailyReports = 10
+	}
+	if c.MaxSpeed <= 0 {
+		c.MaxSpeed = 120
+	}
+	if c.Plenty == 0 {
+		c.Plenty = 100
+	}
+	if c.Alpha <= 0 || c.Alpha > 1 {
+		c.Alpha = 0.3
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 0.6
+	}
+	if c.MinReports == 0 {
+		c.MinReports = 3
+	}
+	return c
+}
+
+var _ service.Scorer = (*scorer)(nil)
+
+type scorer struct {
+	feedbacks model.FeedbackRepository
+	suspects  model.SuspectRepository
+	stock     Stock
+	cfg       Config
+}
+
+// New instantiates a scorer comparing feedback with the history of its user
+// in feedbacks, the reports of other users and, unless stock is nil, the
+// stock of the latest pharmacy snapshot. The standing of users is kept in
+// suspects.
+func New(feedbacks model.FeedbackRepository, suspects model.SuspectRepository, stock Stock, cfg Config) service.Scorer {
+	return &scorer{
+		feedbacks: feedbacks,
+		suspects:  suspects,
+		stock:     stock,
+		cfg:       cfg.withDefaults(),
+	}
+}
+
+// Score scores f, a report about to be stored, between 0 and 1 and folds it
+// into the moving average of its user. A user stays banned until an admin
+// removes their standing.
+func (s *scorer) Score(ctx context.Context, f model.Feedback) (model.Suspect, error) {
+	now := time.Now()
+	date := now.In(util.Location).Format(service.QueryDatefmt)
+	claim := s.cfg.Claims[f.OptionID]
+
+	stock := s.stockSignal(f.PharmacyID, claim)
+	peers, err := s.peerSignal(ctx, f, claim, date, now)
+	if err != nil {
+		return model.Suspect{}, err
+	}
+	history, err := s.historySignal(ctx, f, date, now)
+	if err != nil {
+		return model.Suspect{}, err
+	}
+	score := stockWeight*stock + peerWeight*peers + historyWeight*history
+
+	return s.suspects.Record(ctx, f.UserID, score, model.Scoring{
+		Alpha:      s.cfg.Alpha,
+		Threshold:  s.cfg.Threshold,
+		MinReports: s.cfg.MinReports,
+	})
+}
+
+// stockSignal is 1 when claim contradicts the snapshot: masks claimed gone
+// from a pharmacy holding plenty, or handed out by one holding none.
+func (s *scorer) stockSignal(pharmacyID string, claim Claim) float64 {
+	if s.stock == nil || claim == None {
+		return 0
+	}
+
+	adult, child, ok := s.stock.Stock(pharmacyID)
+	if !ok {
+		return 0
+	}
+	total := adult + child
+	if (claim == Out && total >= s.cfg.Plenty) || (claim == In && total == 0) {
+		return 1
+	}
+	return 0
+}
+
+// peerSignal is the share of the other users who recently claimed the
+// opposite of claim about the same pharmacy, by their latest claim. Banned
+// users are not listed, so they cannot outvote honest ones.
+func (s *scorer) peerSignal(ctx context.Context, f model.Feedback, claim Claim, date string, now time.Time) (float64, error) {
+	if claim == None {
+		return 0, nil
+	}
+
+	page, err := s.feedbacks.RetrieveByPharmacyID(ctx, f.PharmacyID, date, 0, pageLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	claims := map[string]Claim{}
+	for _, p := range page.Items {
+		if p.UserID == f.UserID || now.Sub(p.CreatedAt) > s.cfg.Window {
+			continue
+		}
+		c := s.cfg.Claims[p.OptionID]
+		if _, ok := claims[p.UserID]; ok || c == None {
+			continue
+		}
+		// Items are newest first, so the first claim of a user is the latest.
+		claims[p.UserID] = c
+	}
+	if len(claims) < s.cfg.MinPeers {
+		return 0, nil
+	}
+
+	var disagree int
+	for _, c := range claims {
+		if c != claim {
+			disagree++
+		}
+	}
+	return float64(disagree) / float64(len(claims)), nil
+}
+
+// historySignal rates the reports of the user today: 1 when f follows the
+// previous one faster than anyone travels, else how far the user is beyond
+// DailyReports, up to 1 at twice as many.
+func (s *scorer) historySignal(ctx context.Context, f model.Feedback, date string, now time.Time) (float64, error) {
+	page, err := s.feedbacks.RetrieveByUserID(ctx, f.UserID, date, 0, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(page.Items) == 0 {
+		return 0, nil
+	}
+
+	prev := page.Items[0]
+	km := distance(prev.Longitude, prev.Latitude, f.Longitude, f.Latitude)
+	if km > minTravelKm && km/math.Max(now.Sub(prev.CreatedAt).Hours(), 1e-6) > s.cfg.MaxSpeed {
+		return 1, nil
+	}
+
+	reports := page.Total + 1
+	if reports <= s.cfg.DailyReports {
+		return 0, nil
+	}
+	return math.Min(1, float64(reports-s.cfg.DailyReports)/float64(s.cfg.DailyReports)), nil
+}
+
+// distance is the great circle distance in km between two points.
+func distance(lng1, lat1, lng2, lat2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
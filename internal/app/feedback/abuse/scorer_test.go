@@ -0,0 +1,250 @@
+package abuse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/feedback/memory"
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+const (
+	soldOut  = "ddCp1m88O4g5SU1GDJRPi"
+	handout  = "nAn6pj8UkrXST1syShrzV"
+	custom   = "IRESxM58KC~dqg5XLCH~n"
+	pharmacy = "5901012345"
+)
+
+// stocks serves fixed stocks by pharmacy.
+type stocks map[string]stock
+
+func (s stocks) Stock(id string) (uint64, uint64, bool) {
+	st, ok := s[id]
+	return st.adult, st.child, ok
+}
+
+type env struct {
+	feedbacks model.FeedbackRepository
+	suspects  model.SuspectRepository
+	scorer    service.Scorer
+}
+
+func newEnv(stock Stock) env {
+	feedbacks := memory.New()
+	suspects := memory.NewSuspectRepository(feedbacks)
+	return env{feedbacks, suspects, New(feedbacks, suspects, stock, Config{})}
+}
+
+// submit scores and stores a report the way the service does.
+func (e env) submit(t *testing.T, user, option string, lng, lat float64) model.Suspect {
+	t.Helper()
+	f := model.Feedback{ID: user + option, UserID: user, PharmacyID: pharmacy, OptionID: option, Longitude: lng, Latitude: lat}
+	s, err := e.scorer.Score(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.feedbacks.Insert(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
+
+func TestScoreStockContradiction(t *testing.T) {
+	e := newEnv(stocks{pharmacy: {adult: 200, child: 50}})
+
+	if s := e.submit(t, "liar", soldOut, 121.5, 25.0); !near(s.Score, stockWeight) {
+		t.Errorf("got score %v for sold out at a stocked pharmacy, want %v", s.Score, stockWeight)
+	}
+	if s := e.submit(t, "honest", handout, 121.5, 25.0); s.Score != 0 {
+		t.Errorf("got score %v for a handout at a stocked pharmacy, want 0", s.Score)
+	}
+	if s := e.submit(t, "chatty", custom, 121.5, 25.0); s.Score != 0 {
+		t.Errorf("got score %v for custom feedback, want 0", s.Score)
+	}
+}
+
+func TestScoreBansCoordinatedUser(t *testing.T) {
+	e := newEnv(stocks{pharmacy: {adult: 200}})
+	e.submit(t, "a", handout, 121.5, 25.0)
+	e.submit(t, "b", handout, 121.5, 25.0)
+
+	var s model.Suspect
+	for i := 0; i < 3; i++ {
+		s = e.submit(t, "liar", soldOut, 121.5, 25.0)
+		if i < 2 && s.Banned {
+			t.Fatalf("banned after %d reports, want at least 3", i+1)
+		}
+	}
+	if want := stockWeight + peerWeight; !near(s.Score, want) || !s.Banned || s.Reports != 3 {
+		t.Fatalf("got %+v, want score %v, banned after 3 reports", s, want)
+	}
+
+	date := time.Now().In(util.Location).Format(service.QueryDatefmt)
+	page, err := e.feedbacks.RetrieveByPharmacyID(context.Background(), pharmacy, date, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range page.Items {
+		if f.UserID == "liar" {
+			t.Errorf("banned user listed on the pharmacy: %+v", f)
+		}
+	}
+	if page.Total != 2 {
+		t.Errorf("got %d feedback on the pharmacy, want the 2 of honest users", page.Total)
+	}
+
+	// The banned user no longer counts as a peer to disagree with.
+	if s := e.submit(t, "c", handout, 121.5, 25.0); s.Score != 0 {
+		t.Errorf("got score %v for agreeing with peers and stock, want 0", s.Score)
+	}
+}
+
+func TestScorePeersNeedQuorum(t *testing.T) {
+	e := newEnv(nil)
+	e.submit(t, "a", handout, 121.5, 25.0)
+
+	if s := e.submit(t, "b", soldOut, 121.5, 25.0); s.Score != 0 {
+		t.Errorf("got score %v disagreeing with a single peer, want 0", s.Score)
+	}
+	if s := e.submit(t, "c", soldOut, 121.5, 25.0); !near(s.Score, peerWeight*0.5) {
+		t.Errorf("got score %v disagreeing with one of two peers, want %v", s.Score, peerWeight*0.5)
+	}
+}
+
+func TestScoreImpossibleTravel(t *testing.T) {
+	e := newEnv(nil)
+	e.submit(t, "user", custom, 121.5, 25.0)
+
+	if s := e.submit(t, "user", custom, 121.5, 25.001); s.Score != 0 {
+		t.Errorf("got score %v reporting from the same neighbourhood, want 0", s.Score)
+	}
+	// Kaohsiung is some 300km from Taipei.
+	s := e.submit(t, "user", custom, 120.3, 22.6)
+	// Folded into the clean reports before it with the default alpha.
+	if want := 0.3 * historyWeight; !near(s.Score, want) {
+		t.Errorf("got score %v reporting from another city at once, want %v", s.Score, want)
+	}
+}
+
+func TestScoreDailyVolume(t *testing.T) {
+	e := newEnv(nil)
+	for i := 0; i < 14; i++ {
+		e.feedbacks.Insert(context.Background(), model.Feedback{ID: string(rune('a' + i)), UserID: "bot", PharmacyID: pharmacy, OptionID: custom, Longitude: 121.5, Latitude: 25.0})
+	}
+
+	// The 15th report of the day is half as many beyond the 10 allowed.
+	s := e.submit(t, "bot", custom, 121.5, 25.0)
+	if want := historyWeight * 0.5; !near(s.Score, want) {
+		t.Errorf("got score %v for the 15th report, want %v", s.Score, want)
+	}
+}
+
+// exporter exports its body, or fails with err.
+type exporter struct {
+	mu    sync.Mutex
+	body  string
+	err   error
+	calls int
+}
+
+func (e *exporter) Export(_ context.Context, format string) (io.ReadCloser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls++
+	if format != "ndjson" {
+		return nil, errors.New("unexpected format " + format)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return ioutil.NopCloser(strings.NewReader(e.body)), nil
+}
+
+func (e *exporter) set(body string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.body, e.err = body, err
+}
+
+func (e *exporter) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func TestSnapshotStock(t *testing.T) {
+	ex := &exporter{err: errors.New("unavailable")}
+	st := NewSnapshotStock(ex, time.Hour, log.NewNopLogger())
+	ctx := context.Background()
+
+	st.refresh(ctx)
+	if _, _, ok := st.Stock(pharmacy); ok {
+		t.Fatal("got stock without a snapshot")
+	}
+
+	ex.set(`{"id":"5901012345","name":"a","maskAdult":120,"maskChild":30}
+{"id":"5901054321","maskAdult":0,"maskChild":0}
+`, nil)
+	st.refresh(ctx)
+	if adult, child, ok := st.Stock(pharmacy); !ok || adult != 120 || child != 30 {
+		t.Fatalf("got %d, %d, %v, want 120, 30", adult, child, ok)
+	}
+	if _, _, ok := st.Stock("0000000000"); ok {
+		t.Error("got stock of an unknown pharmacy")
+	}
+
+	// A failed refresh keeps the stale snapshot.
+	ex.set("", errors.New("unavailable"))
+	st.refresh(ctx)
+	if adult, _, ok := st.Stock(pharmacy); !ok || adult != 120 {
+		t.Errorf("got %d, %v after a failed refresh, want the stale stock", adult, ok)
+	}
+	if n := ex.count(); n != 3 {
+		t.Errorf("got %d exports, want one a refresh", n)
+	}
+}
+
+func TestSnapshotStockRun(t *testing.T) {
+	ex := &exporter{body: `{"id":"5901012345","maskAdult":120,"maskChild":30}` + "\n"}
+	st := NewSnapshotStock(ex, 10*time.Millisecond, log.NewNopLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		st.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for ex.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := ex.count(); n < 3 {
+		t.Errorf("got %d exports, want one every ttl", n)
+	}
+	if _, _, ok := st.Stock(pharmacy); !ok {
+		t.Error("got no stock after Run fetched the snapshot")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return once ctx was done")
+	}
+}
@@ -0,0 +1,114 @@
+package abuse
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/pkg/level"
+)
+
+// Stock specifies an API for reading the mask stock of pharmacies. It is
+// read while reports are scored, so it must not wait on I/O.
+type Stock interface {
+	// Stock returns the adult and child masks of a pharmacy, ok false when
+	// the pharmacy is unknown.
+	Stock(pharmacyID string) (adult, child uint64, ok bool)
+}
+
+// Exporter exports the latest pharmacy snapshot, as the pharmacy client does.
+type Exporter interface {
+	Export(ctx context.Context, format string) (io.ReadCloser, error)
+}
+
+type stock struct {
+	adult, child uint64
+}
+
+var _ Stock = (*SnapshotStock)(nil)
+
+// SnapshotStock is the stock of the latest pharmacy snapshot, refreshed in
+// the background by Run.
+type SnapshotStock struct {
+	exporter Exporter
+	ttl      time.Duration
+	logger   log.Logger
+
+	mu     sync.RWMutex
+	stocks map[string]stock
+}
+
+// NewSnapshotStock returns the stock of the snapshot exported by exporter,
+// fetched by Run every ttl. Pharmacies are unknown until the first fetch
+// succeeds; a failed fetch keeps the stale stock.
+func NewSnapshotStock(exporter Exporter, ttl time.Duration, logger log.Logger) *SnapshotStock {
+	return &SnapshotStock{exporter: exporter, ttl: ttl, logger: logger}
+}
+
+func (s *SnapshotStock) Stock(pharmacyID string) (uint64, uint64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, ok := s.stocks[pharmacyID]
+	return st.adult, st.child, ok
+}
+
+// Run fetches the snapshot when it starts and every ttl until ctx is done.
+func (s *SnapshotStock) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.ttl)
+	defer ticker.Stop()
+
+	for {
+		s.refresh(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh replaces the stock with the one fetched, which is decoded before
+// the lock is taken, so readers never wait on the download.
+func (s *SnapshotStock) refresh(ctx context.Context) {
+	stocks, err := s.fetch(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			level.Warn(s.logger).Log("method", "s.fetch", "err", err)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.stocks = stocks
+	s.mu.Unlock()
+}
+
+func (s *SnapshotStock) fetch(ctx context.Context) (map[string]stock, error) {
+	rc, err := s.exporter.Export(ctx, "ndjson")
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	stocks := map[string]stock{}
+	dec := json.NewDecoder(rc)
+	for {
+		var p struct {
+			ID        string `json:"id"`
+			MaskAdult uint64 `json:"maskAdult"`
+			MaskChild uint64 `json:"maskChild"`
+		}
+		if err := dec.Decode(&p); err == io.EOF {
+			return stocks, nil
+		} else if err != nil {
+			return nil, err
+		}
+		stocks[p.ID] = stock{adult: p.MaskAdult, child: p.MaskChild}
+	}
+}
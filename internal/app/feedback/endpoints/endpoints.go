@@ -8,6 +8,9 @@ import (
 
 	"github.com/cage1016/mask/internal/app/feedback/model"
 	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/auth"
+	"github.com/cage1016/mask/internal/pkg/export"
+	"github.com/cage1016/mask/internal/pkg/tracing"
 )
 
 // Endpoints collects all of the endpoints that compose the feedbacksvc service. It's
@@ -18,15 +21,21 @@ type Endpoints struct {
 	PharmacyFeedBacksEndpoint endpoint.Endpoint `json:""`
 	UserFeedBacksEndpoint     endpoint.Endpoint `json:""`
 	FeedBackEndpoint          endpoint.Endpoint `json:""`
+	ExportEndpoint            endpoint.Endpoint `json:""`
+	SuspectsEndpoint          endpoint.Endpoint `json:""`
+	UnbanEndpoint             endpoint.Endpoint `json:""`
 }
 
 // New return a new instance of the endpoint that wraps the provided service.
-func New(svc service.FeedbacksvcService, logger log.Logger) (ep Endpoints) {
+// The export, which hands out the feedback of every user, and the review of
+// the banned users require adminToken.
+func New(svc service.FeedbacksvcService, adminToken string, logger log.Logger) (ep Endpoints) {
 	var optionsEndpoint endpoint.Endpoint
 	{
 		method := "options"
 		optionsEndpoint = MakeOptionsEndpoint(svc)
 		optionsEndpoint = LoggingMiddleware(log.With(logger, "method", method))(optionsEndpoint)
+		optionsEndpoint = tracing.EndpointMiddleware(method)(optionsEndpoint)
 		ep.OptionsEndpoint = optionsEndpoint
 	}
 
@@ -35,6 +44,7 @@ func New(svc service.FeedbacksvcService, logger log.Logger) (ep Endpoints) {
 		method := "stores"
 		storesEndpoint = MakePharmacyFeedBacksEndpoint(svc)
 		storesEndpoint = LoggingMiddleware(log.With(logger, "method", method))(storesEndpoint)
+		storesEndpoint = tracing.EndpointMiddleware(method)(storesEndpoint)
 		ep.PharmacyFeedBacksEndpoint = storesEndpoint
 	}
 
@@ -43,6 +53,7 @@ func New(svc service.FeedbacksvcService, logger log.Logger) (ep Endpoints) {
 		method := "users"
 		usersEndpoint = MakeUserFeedBacksEndpoint(svc)
 		usersEndpoint = LoggingMiddleware(log.With(logger, "method", method))(usersEndpoint)
+		usersEndpoint = tracing.EndpointMiddleware(method)(usersEndpoint)
 		ep.UserFeedBacksEndpoint = usersEndpoint
 	}
 
@@ -51,9 +62,40 @@ func New(svc service.FeedbacksvcService, logger log.Logger) (ep Endpoints) {
 		method := "feedBack"
 		feedBackEndpoint = MakeFeedBackEndpoint(svc)
 		feedBackEndpoint = LoggingMiddleware(log.With(logger, "method", method))(feedBackEndpoint)
+		feedBackEndpoint = tracing.EndpointMiddleware(method)(feedBackEndpoint)
 		ep.FeedBackEndpoint = feedBackEndpoint
 	}
 
+	var exportEndpoint endpoint.Endpoint
+	{
+		method := "export"
+		exportEndpoint = MakeExportEndpoint(svc)
+		exportEndpoint = auth.Middleware(adminToken)(exportEndpoint)
+		exportEndpoint = LoggingMiddleware(log.With(logger, "method", method))(exportEndpoint)
+		exportEndpoint = tracing.EndpointMiddleware(method)(exportEndpoint)
+		ep.ExportEndpoint = exportEndpoint
+	}
+
+	var suspectsEndpoint endpoint.Endpoint
+	{
+		method := "suspects"
+		suspectsEndpoint = MakeSuspectsEndpoint(svc)
+		suspectsEndpoint = auth.Middleware(adminToken)(suspectsEndpoint)
+		suspectsEndpoint = LoggingMiddleware(log.With(logger, "method", method))(suspectsEndpoint)
+		suspectsEndpoint = tracing.EndpointMiddleware(method)(suspectsEndpoint)
+		ep.SuspectsEndpoint = suspectsEndpoint
+	}
+
+	var unbanEndpoint endpoint.Endpoint
+	{
+		method := "unban"
+		unbanEndpoint = MakeUnbanEndpoint(svc)
+		unbanEndpoint = auth.Middleware(adminToken)(unbanEndpoint)
+		unbanEndpoint = LoggingMiddleware(log.With(logger, "method", method))(unbanEndpoint)
+		unbanEndpoint = tracing.EndpointMiddleware(method)(unbanEndpoint)
+		ep.UnbanEndpoint = unbanEndpoint
+	}
+
 	return ep
 }
 
@@ -162,3 +204,71 @@ func (e Endpoints) FeedBack(ctx context.Context, userID, pharmacyID, optionID, d
 	response := resp.(FeedBackResponse)
 	return response.ID, nil
 }
+
+// MakeExportEndpoint returns an endpoint that invokes Export on the service.
+// Primarily useful in a server.
+func MakeExportEndpoint(svc service.FeedbacksvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ExportRequest)
+		if err := req.validate(); err != nil {
+			return ExportResponse{}, err
+		}
+		cursor, err := svc.Export(ctx, req.From, req.To)
+		return ExportResponse{Format: req.Format, Cursor: cursor}, err
+	}
+}
+
+// Export implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Export(ctx context.Context, from string, to string) (cursor model.FeedbackCursor, err error) {
+	resp, err := e.ExportEndpoint(ctx, ExportRequest{From: from, To: to, Format: export.FormatNDJSON})
+	if err != nil {
+		return
+	}
+	response := resp.(ExportResponse)
+	return response.Cursor, nil
+}
+
+// MakeSuspectsEndpoint returns an endpoint that invokes Suspects on the service.
+// Primarily useful in a server.
+func MakeSuspectsEndpoint(svc service.FeedbacksvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SuspectsRequest)
+		if err := req.validate(); err != nil {
+			return SuspectsResponse{}, err
+		}
+		res, err := svc.Suspects(ctx, req.Offset, req.Limit)
+		return SuspectsResponse{Res: res}, err
+	}
+}
+
+// Suspects implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Suspects(ctx context.Context, offset, limit uint64) (res model.SuspectPage, err error) {
+	resp, err := e.SuspectsEndpoint(ctx, SuspectsRequest{Offset: offset, Limit: limit})
+	if err != nil {
+		return
+	}
+	response := resp.(SuspectsResponse)
+	return response.Res, nil
+}
+
+// MakeUnbanEndpoint returns an endpoint that invokes Unban on the service.
+// Primarily useful in a server.
+func MakeUnbanEndpoint(svc service.FeedbacksvcService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UnbanRequest)
+		if err := req.validate(); err != nil {
+			return UnbanResponse{}, err
+		}
+		err := svc.Unban(ctx, req.UserID)
+		return UnbanResponse{}, err
+	}
+}
+
+// Unban implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Unban(ctx context.Context, userID string) (err error) {
+	_, err = e.UnbanEndpoint(ctx, UnbanRequest{UserID: userID})
+	return err
+}
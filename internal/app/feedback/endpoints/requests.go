@@ -5,11 +5,14 @@ import (
 
 	"github.com/cage1016/mask/internal/app/feedback/service"
 	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/export"
 )
 
 const (
 	maxLimitSize = 100
 
+	maxExportDays = 93
+
 	customOptionID = "IRESxM58KC~dqg5XLCH~n"
 )
 
@@ -97,3 +100,59 @@ func (r FeedBackRequest) validate() error {
 
 	return nil // TBA
 }
+
+// ExportRequest collects the request parameters for the Export method.
+type ExportRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Format string `json:"format"`
+}
+
+func (r ExportRequest) validate() error {
+	if r.Format != export.FormatCSV && r.Format != export.FormatNDJSON {
+		return errors.Wrap(service.ErrMalformedEntity, export.ErrUnsupportedFormat)
+	}
+
+	from, err := time.Parse(service.QueryDatefmt, r.From)
+	if err != nil {
+		return errors.Wrap(service.ErrMalformedEntity, err)
+	}
+
+	to, err := time.Parse(service.QueryDatefmt, r.To)
+	if err != nil {
+		return errors.Wrap(service.ErrMalformedEntity, err)
+	}
+
+	if to.Before(from) || to.Sub(from) >= maxExportDays*24*time.Hour {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("to must be within 93 days after from"))
+	}
+
+	return nil
+}
+
+// SuspectsRequest collects the request parameters for the Suspects method.
+type SuspectsRequest struct {
+	Offset uint64 `json:"offset"`
+	Limit  uint64 `json:"limit"`
+}
+
+func (r SuspectsRequest) validate() error {
+	if r.Limit <= 0 || r.Limit > maxLimitSize {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("limit must between 1 - 100"))
+	}
+
+	return nil
+}
+
+// UnbanRequest collects the request parameters for the Unban method.
+type UnbanRequest struct {
+	UserID string `json:"user_id"`
+}
+
+func (r UnbanRequest) validate() error {
+	if r.UserID == "" {
+		return service.ErrMalformedEntity
+	}
+
+	return nil
+}
@@ -1,12 +1,14 @@
 package endpoints
 
 import (
+	"fmt"
 	"net/http"
 
 	httptransport "github.com/go-kit/kit/transport/http"
 
 	"github.com/cage1016/mask/internal/app/feedback/model"
 	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/export"
 	"github.com/cage1016/mask/internal/pkg/responses"
 )
 
@@ -15,17 +17,37 @@ var (
 
 	_ httptransport.StatusCoder = (*OptionsResponse)(nil)
 
+	_ responses.Enveloper = (*OptionsResponse)(nil)
+
 	_ httptransport.Headerer = (*PharmacyFeedBacksResponse)(nil)
 
 	_ httptransport.StatusCoder = (*PharmacyFeedBacksResponse)(nil)
 
+	_ responses.Enveloper = (*PharmacyFeedBacksResponse)(nil)
+
 	_ httptransport.Headerer = (*UserFeedBacksResponse)(nil)
 
 	_ httptransport.StatusCoder = (*UserFeedBacksResponse)(nil)
 
+	_ responses.Enveloper = (*UserFeedBacksResponse)(nil)
+
 	_ httptransport.Headerer = (*FeedBackResponse)(nil)
 
 	_ httptransport.StatusCoder = (*FeedBackResponse)(nil)
+
+	_ responses.Enveloper = (*FeedBackResponse)(nil)
+
+	_ httptransport.Headerer = (*ExportResponse)(nil)
+
+	_ httptransport.Headerer = (*SuspectsResponse)(nil)
+
+	_ httptransport.StatusCoder = (*SuspectsResponse)(nil)
+
+	_ responses.Enveloper = (*SuspectsResponse)(nil)
+
+	_ httptransport.Headerer = (*UnbanResponse)(nil)
+
+	_ httptransport.StatusCoder = (*UnbanResponse)(nil)
 )
 
 // OptionsResponse collects the response values for the Options method.
@@ -46,6 +68,10 @@ func (r OptionsResponse) Response() interface{} {
 	return responses.DataRes{APIVersion: service.Version, Data: r}
 }
 
+func (r OptionsResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: service.Version, Data: r.Items}
+}
+
 // PharmacyFeedBacksResponse collects the response values for the PharmacyFeedBacks method.
 type PharmacyFeedBacksResponse struct {
 	Res model.FeedbackItemPage `json:"items"`
@@ -64,6 +90,14 @@ func (r PharmacyFeedBacksResponse) Response() interface{} {
 	return responses.DataRes{APIVersion: service.Version, Data: r.Res}
 }
 
+func (r PharmacyFeedBacksResponse) Envelope() responses.Envelope {
+	return responses.Envelope{
+		APIVersion: service.Version,
+		Data:       r.Res.Items,
+		Paging:     responses.NewPaging(len(r.Res.Items), r.Res.Offset, r.Res.Limit, r.Res.Total),
+	}
+}
+
 // UserFeedBacksResponse collects the response values for the UserFeedBacks method.
 type UserFeedBacksResponse struct {
 	Res model.FeedbackItemPage `json:"res"`
@@ -82,6 +116,14 @@ func (r UserFeedBacksResponse) Response() interface{} {
 	return responses.DataRes{APIVersion: service.Version, Data: r.Res}
 }
 
+func (r UserFeedBacksResponse) Envelope() responses.Envelope {
+	return responses.Envelope{
+		APIVersion: service.Version,
+		Data:       r.Res.Items,
+		Paging:     responses.NewPaging(len(r.Res.Items), r.Res.Offset, r.Res.Limit, r.Res.Total),
+	}
+}
+
 // FeedBackResponse collects the response values for the InsertFeedBack method.
 type FeedBackResponse struct {
 	Err error  `json:"-"`
@@ -99,3 +141,60 @@ func (r FeedBackResponse) Headers() http.Header {
 func (r FeedBackResponse) Response() interface{} {
 	return responses.DataRes{APIVersion: service.Version, Data: r}
 }
+
+func (r FeedBackResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: service.Version, Data: r}
+}
+
+// ExportResponse collects the response values for the Export method.
+type ExportResponse struct {
+	Format string               `json:"-"`
+	Cursor model.FeedbackCursor `json:"-"`
+	Err    error                `json:"-"`
+}
+
+func (r ExportResponse) Headers() http.Header {
+	return http.Header{
+		"Content-Type":        []string{export.ContentType(r.Format)},
+		"Content-Disposition": []string{fmt.Sprintf(`attachment; filename="feedback.%s"`, r.Format)},
+	}
+}
+
+// SuspectsResponse collects the response values for the Suspects method.
+type SuspectsResponse struct {
+	Res model.SuspectPage `json:"res"`
+	Err error             `json:"-"`
+}
+
+func (r SuspectsResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r SuspectsResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+func (r SuspectsResponse) Response() interface{} {
+	return responses.DataRes{APIVersion: service.Version, Data: r.Res}
+}
+
+func (r SuspectsResponse) Envelope() responses.Envelope {
+	return responses.Envelope{
+		APIVersion: service.Version,
+		Data:       r.Res.Items,
+		Paging:     responses.NewPaging(len(r.Res.Items), r.Res.Offset, r.Res.Limit, r.Res.Total),
+	}
+}
+
+// UnbanResponse collects the response values for the Unban method.
+type UnbanResponse struct {
+	Err error `json:"-"`
+}
+
+func (r UnbanResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+func (r UnbanResponse) Headers() http.Header {
+	return http.Header{}
+}
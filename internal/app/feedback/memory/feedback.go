@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+// dayFormat is the format of the days feedbacks are retrieved by.
+const dayFormat = "2006_0102"
+
+// defaultOptions are the options seeded by the feedback migrations.
+var defaultOptions = []model.Option{
+	{ID: "IRESxM58KC~dqg5XLCH~n", Name: "自訂"},
+	{ID: "ddCp1m88O4g5SU1GDJRPi", Name: "當天已售完"},
+	{ID: "uYrYL~7Gd65IN2wWsWa9A", Name: "號碼牌已發送完畢"},
+	{ID: "nAn6pj8UkrXST1syShrzV", Name: "發放號碼牌"},
+}
+
+var _ model.FeedbackRepository = (*feedbackRepository)(nil)
+
+type feedbackRepository struct {
+	mu        sync.RWMutex
+	feedbacks []model.Feedback
+	options   []model.Option
+	// banned is shared with the suspect repository of NewSuspectRepository.
+	banned func(userID string) bool
+}
+
+// New instantiates an in-memory implementation of the feedback repository,
+// holding the options seeded by the migrations.
+func New() model.FeedbackRepository {
+	return &feedbackRepository{options: defaultOptions, banned: func(string) bool { return false }}
+}
+
+// Insert stores the feedback created now, like the column default does.
+func (r *feedbackRepository) Insert(_ context.Context, feedback model.Feedback) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feedback.CreatedAt = time.Now()
+	r.feedbacks = append(r.feedbacks, feedback)
+	return feedback.ID, nil
+}
+
+func (r *feedbackRepository) RetrieveByUserID(_ context.Context, userID string, date string, offset uint64, limit uint64) (model.FeedbackItemPage, error) {
+	return r.retrieve(func(f model.Feedback) bool { return f.UserID == userID }, date, offset, limit)
+}
+
+func (r *feedbackRepository) RetrieveByPharmacyID(_ context.Context, pharmacyID string, date string, offset uint64, limit uint64) (model.FeedbackItemPage, error) {
+	return r.retrieve(func(f model.Feedback) bool { return f.PharmacyID == pharmacyID && !r.banned(f.UserID) }, date, offset, limit)
+}
+
+// retrieve pages through the feedbacks of one day matching match, newest
+// first.
+func (r *feedbackRepository) retrieve(match func(model.Feedback) bool, date string, offset, limit uint64) (model.FeedbackItemPage, error) {
+	from, err := time.ParseInLocation(dayFormat, date, util.Location)
+	if err != nil {
+		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+	}
+
+	var matched []model.Feedback
+	for _, f := range r.between(from, from.AddDate(0, 0, 1)) {
+		if match(f) {
+			matched = append(matched, f)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+
+	items := []model.Feedback{}
+	if offset < uint64(len(matched)) {
+		end := offset + limit
+		if end > uint64(len(matched)) {
+			end = uint64(len(matched))
+		}
+		items = append(items, matched[offset:end]...)
+	}
+
+	return model.FeedbackItemPage{
+		Items: items,
+		PageMetadata: model.PageMetadata{
+			Total:  uint64(len(matched)),
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, nil
+}
+
+// between returns the feedbacks created in [from, to), oldest first.
+func (r *feedbackRepository) between(from, to time.Time) []model.Feedback {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var feedbacks []model.Feedback
+	for _, f := range r.feedbacks {
+		if !f.CreatedAt.Before(from) && f.CreatedAt.Before(to) {
+			feedbacks = append(feedbacks, f)
+		}
+	}
+	sort.SliceStable(feedbacks, func(i, j int) bool { return feedbacks[i].CreatedAt.Before(feedbacks[j].CreatedAt) })
+	return feedbacks
+}
+
+func (r *feedbackRepository) ListOption(context.Context) ([]model.Option, error) {
+	return append([]model.Option(nil), r.options...), nil
+}
+
+func (r *feedbackRepository) Export(_ context.Context, from, to time.Time) (model.FeedbackCursor, error) {
+	return &feedbackCursor{items: r.between(from, to.AddDate(0, 0, 1)), i: -1}, nil
+}
+
+type feedbackCursor struct {
+	items []model.Feedback
+	i     int
+}
+
+func (c *feedbackCursor) Next() bool {
+	if c.i+1 >= len(c.items) {
+		return false
+	}
+	c.i++
+	return true
+}
+
+func (c *feedbackCursor) Feedback() (model.Feedback, error) {
+	return c.items[c.i], nil
+}
+
+func (c *feedbackCursor) Err() error {
+	return nil
+}
+
+func (c *feedbackCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,17 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/cage1016/mask/internal/app/feedback/memory"
+	"github.com/cage1016/mask/internal/app/feedback/repotest"
+)
+
+func TestFeedbackRepository(t *testing.T) {
+	repotest.Run(t, memory.New())
+}
+
+func TestSuspectRepository(t *testing.T) {
+	repo := memory.New()
+	repotest.RunSuspects(t, repo, memory.NewSuspectRepository(repo))
+}
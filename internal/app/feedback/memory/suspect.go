@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+)
+
+var _ model.SuspectRepository = (*suspectRepository)(nil)
+
+type suspectRepository struct {
+	mu       sync.RWMutex
+	suspects map[string]model.Suspect
+}
+
+// NewSuspectRepository instantiates an in-memory implementation of suspect
+// repository. feedbacks, a repository returned by New, leaves out the
+// feedback of the users it bans, like the tables of one database do.
+func NewSuspectRepository(feedbacks model.FeedbackRepository) model.SuspectRepository {
+	r := &suspectRepository{suspects: make(map[string]model.Suspect)}
+	feedbacks.(*feedbackRepository).banned = func(userID string) bool {
+		s, _ := r.Retrieve(context.Background(), userID)
+		return s.Banned
+	}
+	return r
+}
+
+func (r *suspectRepository) Retrieve(_ context.Context, userID string) (model.Suspect, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s, ok := r.suspects[userID]; ok {
+		return s, nil
+	}
+	return model.Suspect{UserID: userID}, nil
+}
+
+// Record folds the score under the lock, so concurrent reports of a user all
+// count, and stores the suspect updated now, like the upsert does.
+func (r *suspectRepository) Record(_ context.Context, userID string, score float64, scoring model.Scoring) (model.Suspect, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.suspects[userID]
+	if !ok {
+		s = model.Suspect{UserID: userID}
+	}
+	s = scoring.Fold(s, score)
+	s.UpdatedAt = time.Now()
+	r.suspects[userID] = s
+	return s, nil
+}
+
+func (r *suspectRepository) RetrieveBanned(_ context.Context, offset, limit uint64) (model.SuspectPage, error) {
+	r.mu.RLock()
+	var banned []model.Suspect
+	for _, s := range r.suspects {
+		if s.Banned {
+			banned = append(banned, s)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(banned, func(i, j int) bool {
+		if banned[i].Score != banned[j].Score {
+			return banned[i].Score > banned[j].Score
+		}
+		return banned[i].UserID < banned[j].UserID
+	})
+
+	items := []model.Suspect{}
+	if offset < uint64(len(banned)) {
+		end := offset + limit
+		if end > uint64(len(banned)) {
+			end = uint64(len(banned))
+		}
+		items = append(items, banned[offset:end]...)
+	}
+
+	return model.SuspectPage{
+		Items: items,
+		PageMetadata: model.PageMetadata{
+			Total:  uint64(len(banned)),
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, nil
+}
+
+func (r *suspectRepository) Remove(_ context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.suspects[userID]; !ok {
+		return model.ErrSuspectNotFound
+	}
+	delete(r.suspects, userID)
+	return nil
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/cage1016/mask/internal/pkg/util"
@@ -13,6 +14,9 @@ type Option struct {
 	Name string `json:"name" db:"name"`
 }
 
+// createdAtLayout is the JSON and CSV layout of Feedback.CreatedAt.
+const createdAtLayout = "2006-01-02T15:04:05-0700"
+
 type Feedback struct {
 	ID          string    `json:"id" db:"id"`
 	UserID      string    `json:"userId" db:"user_id"`
@@ -31,10 +35,57 @@ func (p *Feedback) MarshalJSON() ([]byte, error) {
 		CreatedAt string `json:"createdAt"`
 	}{
 		Alias:     (*Alias)(p),
-		CreatedAt: p.CreatedAt.In(util.Location).Format("2006-01-02T15:04:05-0700"),
+		CreatedAt: p.CreatedAt.In(util.Location).Format(createdAtLayout),
 	})
 }
 
+// UnmarshalJSON reads the created time written by MarshalJSON.
+func (p *Feedback) UnmarshalJSON(b []byte) error {
+	type Alias Feedback
+
+	aux := &struct {
+		*Alias
+		CreatedAt string `json:"createdAt"`
+	}{
+		Alias: (*Alias)(p),
+	}
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+
+	t, err := time.Parse(createdAtLayout, aux.CreatedAt)
+	if err != nil {
+		return err
+	}
+	p.CreatedAt = t
+	return nil
+}
+
+// FeedbackCSVHeader lists the CSV columns written by Feedback.CSVRecord.
+var FeedbackCSVHeader = []string{"id", "user_id", "pharmacy_id", "option_id", "description", "longitude", "latitude", "created_at"}
+
+func (p Feedback) CSVRecord() []string {
+	return []string{
+		p.ID,
+		p.UserID,
+		p.PharmacyID,
+		p.OptionID,
+		p.Description,
+		strconv.FormatFloat(p.Longitude, 'f', -1, 64),
+		strconv.FormatFloat(p.Latitude, 'f', -1, 64),
+		p.CreatedAt.In(util.Location).Format(createdAtLayout),
+	}
+}
+
+// FeedbackCursor iterates over feedbacks streamed from the repository. It
+// must be closed once consumed.
+type FeedbackCursor interface {
+	Next() bool
+	Feedback() (Feedback, error)
+	Err() error
+	Close() error
+}
+
 type FeedbackItemPage struct {
 	PageMetadata
 	Items []Feedback `json:"items"`
@@ -53,4 +104,7 @@ type FeedbackRepository interface {
 
 	// ListOption
 	ListOption(context.Context) ([]Option, error)
+
+	// Export streams every feedback created between two days, inclusive.
+	Export(context.Context, time.Time, time.Time) (FeedbackCursor, error)
 }
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// ErrSuspectNotFound indicates a user that was never scored.
+var ErrSuspectNotFound = errors.New("suspect not found")
+
+// Suspect is the abuse score of a user, from 0 to 1. The feedback of a
+// banned user is still recorded and shown to the user, but left out of the
+// feedback of pharmacies and of the live stream.
+type Suspect struct {
+	UserID string  `json:"userId" db:"user_id"`
+	Score  float64 `json:"score" db:"score"`
+	// Reports counts the feedbacks the score was computed from.
+	Reports   uint64    `json:"reports" db:"reports"`
+	Banned    bool      `json:"banned" db:"banned"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Scoring tunes how Record folds the score of a report into the standing of
+// its user.
+type Scoring struct {
+	// Alpha weighs the report against the past score of the user.
+	Alpha float64
+	// Threshold is the score at which the user is banned.
+	Threshold float64
+	// MinReports is the number of reports a user is scored on before being
+	// banned.
+	MinReports uint64
+}
+
+// Fold returns s updated with the score of one more report. The first report
+// sets the score, the next ones move it by Alpha. A user stays banned once
+// banned.
+func (sc Scoring) Fold(s Suspect, score float64) Suspect {
+	if s.Reports == 0 {
+		s.Score = score
+	} else {
+		s.Score = sc.Alpha*score + (1-sc.Alpha)*s.Score
+	}
+	s.Reports++
+	s.Banned = s.Banned || (s.Reports >= sc.MinReports && s.Score >= sc.Threshold)
+	return s
+}
+
+type SuspectPage struct {
+	PageMetadata
+	Items []Suspect `json:"items"`
+}
+
+// SuspectRepository persists the abuse scores of users.
+type SuspectRepository interface {
+	// Retrieve returns the score of a user, the zero score of the user when
+	// it was never scored.
+	Retrieve(context.Context, string) (Suspect, error)
+
+	// Record folds the score of a report into the standing of a user as
+	// Scoring.Fold does, atomically, and returns the updated standing.
+	Record(context.Context, string, float64, Scoring) (Suspect, error)
+
+	// RetrieveBanned returns the banned users, highest score first.
+	RetrieveBanned(context.Context, uint64, uint64) (SuspectPage, error)
+
+	// Remove clears the standing of a user, which unbans them and scores
+	// their next report as their first.
+	Remove(context.Context, string) error
+}
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+)
+
+var _ model.FeedbackCursor = (*feedbackCursor)(nil)
+
+type feedbackCursor struct {
+	rows *sqlx.Rows
+}
+
+func (c feedbackCursor) Next() bool {
+	return c.rows.Next()
+}
+
+func (c feedbackCursor) Feedback() (model.Feedback, error) {
+	var f model.Feedback
+	err := c.rows.StructScan(&f)
+	return f, err
+}
+
+func (c feedbackCursor) Err() error {
+	return c.rows.Err()
+}
+
+func (c feedbackCursor) Close() error {
+	return c.rows.Close()
+}
@@ -2,22 +2,29 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/gomurphyx/sqlx"
 
-	"github.com/cage1016/mask/internal/pkg/util"
 	"github.com/cage1016/mask/internal/app/feedback/model"
 	"github.com/cage1016/mask/internal/pkg/errors"
 	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/postgres"
+	"github.com/cage1016/mask/internal/pkg/util"
 )
 
 var _ model.FeedbackRepository = (*feedbackRepository)(nil)
 
+// dayFormat is the format of the days feedbacks are retrieved by.
+const dayFormat = "2006_0102"
+
 var (
 	ErrInsertOrUpdateToFeedbackDB = errors.New("insert or update DB failed")
+	ErrExportFeedbackFromDB       = errors.New("export feedback from DB failed")
 )
 
 type feedbackRepository struct {
@@ -32,50 +39,87 @@ func New(db *sqlx.DB, log log.Logger) model.FeedbackRepository {
 }
 
 func (f feedbackRepository) Insert(ctx context.Context, feedback model.Feedback) (string, error) {
-	nt := fmt.Sprintf("feedback_%s", time.Now().In(util.Location).Format("2006_0102"))
-	level.Info(f.log).Log("table", nt)
+	q := `INSERT INTO public.feedback (id, user_id, pharmacy_id, option_id, description, longitude, latitude)
+			VALUES (:id, :user_id, :pharmacy_id, :option_id, :description, :longitude, :latitude);`
+	if _, err := f.db.NamedExecContext(ctx, q, feedback); err != nil {
+		level.Error(logging.WithRequestID(ctx, f.log)).Log("method", "s.db.NamedExecContext", "err", err)
+		return "", errors.Wrap(ErrInsertOrUpdateToFeedbackDB, err)
+	}
+
+	f.notifyInserted(ctx, feedback)
+	return feedback.ID, nil
+}
 
-	err := f.GetLatestFeedbackTableName(ctx, nt)
+// notifyInserted announces the feedback to live stream listeners, unless its
+// user is banned. Failing to notify never fails the insert.
+func (f feedbackRepository) notifyInserted(ctx context.Context, feedback model.Feedback) {
+	payload, err := json.Marshal(struct {
+		ID          string    `json:"id"`
+		PharmacyID  string    `json:"pharmacyId"`
+		OptionID    string    `json:"optionId"`
+		Description string    `json:"description"`
+		CreatedAt   time.Time `json:"createdAt"`
+	}{feedback.ID, feedback.PharmacyID, feedback.OptionID, feedback.Description, time.Now()})
 	if err != nil {
-		return "", err
+		level.Error(logging.WithRequestID(ctx, f.log)).Log("method", "json.Marshal", "err", err)
+		return
 	}
 
-	q := fmt.Sprintf(`INSERT INTO public.%s (id, user_id, pharmacy_id, option_id, description, longitude, latitude)
-						VALUES (:id, :user_id, :pharmacy_id, :option_id, :description, :longitude, :latitude);`, nt)
-	if _, err := f.db.NamedExecContext(ctx, q, feedback); err != nil {
-		level.Error(f.log).Log("method", "s.db.NamedExecContext", "err", err)
-		return "", errors.Wrap(ErrInsertOrUpdateToFeedbackDB, err)
+	q := `SELECT pg_notify($1, $2) WHERE NOT EXISTS (SELECT 1 FROM feedback_suspects WHERE user_id = $3 AND banned);`
+	if _, err := f.db.ExecContext(ctx, q, postgres.FeedbackChannel, string(payload), feedback.UserID); err != nil {
+		level.Error(logging.WithRequestID(ctx, f.log)).Log("method", "f.db.ExecContext", "sql", "pg_notify", "err", err)
 	}
-	return feedback.ID, nil
 }
 
 func (f feedbackRepository) RetrieveByUserID(ctx context.Context, userID string, date string, offset uint64, limit uint64) (model.FeedbackItemPage, error) {
-	lt := struct {
-		Exists bool `db:"exists"`
-	}{}
+	return f.retrieve(ctx, "user_id", userID, date, offset, limit, false)
+}
 
-	nt := fmt.Sprintf("feedback_%s", date)
-	q := `SELECT EXISTS(SELECT tablename FROM pg_catalog.pg_tables WHERE tablename = $1);`
-	if err := f.db.GetContext(ctx, &lt, q, nt); err != nil {
-		level.Error(f.log).Log("method", "f.db.GetContext", "sql", q, "nt", nt, "err", err)
-		return model.FeedbackItemPage{}, err
-	}
+func (f feedbackRepository) RetrieveByPharmacyID(ctx context.Context, pharmacyID string, date string, offset uint64, limit uint64) (model.FeedbackItemPage, error) {
+	return f.retrieve(ctx, "pharmacy_id", pharmacyID, date, offset, limit, true)
+}
 
-	if !lt.Exists {
-		return model.FeedbackItemPage{Items: []model.Feedback{}}, nil
+// retrieve pages through the feedbacks of one day whose column equals value,
+// leaving out those of banned users when hideBanned is set. column is never
+// user input, value and date are bound as parameters.
+func (f feedbackRepository) retrieve(ctx context.Context, column, value, date string, offset, limit uint64, hideBanned bool) (model.FeedbackItemPage, error) {
+	from, err := time.ParseInLocation(dayFormat, date, util.Location)
+	if err != nil {
+		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+	}
+	params := map[string]interface{}{
+		"value":  value,
+		"from":   from,
+		"to":     from.AddDate(0, 0, 1),
+		"limit":  limit,
+		"offset": offset,
 	}
 
+	q, err := listQuery(column, hideBanned)
+	if err != nil {
+		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+	}
 	items := []model.Feedback{}
-	q = fmt.Sprintf(`select * from %s where user_id = $1 order by created_at desc limit $2 offset $3`, nt)
-	if err := f.db.SelectContext(ctx, &items, q, userID, limit, offset); err != nil {
-		level.Error(f.log).Log("method", "f.db.SelectContext", "sql", q, "userID", userID, "limit", limit, "offset", offset)
+	rows, err := f.db.NamedQueryContext(ctx, q, params)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, f.log)).Log("method", "f.db.NamedQueryContext", "sql", q, column, value, "limit", limit, "offset", offset, "err", err)
 		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
 	}
+	defer rows.Close()
 
-	cq := fmt.Sprintf(`select count(*) from %s where user_id = :user_id	`, nt)
-	total, err := total(ctx, f.db, cq, map[string]interface{}{
-		"user_id": userID,
-	})
+	for rows.Next() {
+		var item model.Feedback
+		if err := rows.StructScan(&item); err != nil {
+			return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+		}
+		items = append(items, item)
+	}
+
+	cq, err := countQuery(column, hideBanned)
+	if err != nil {
+		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+	}
+	total, err := total(ctx, f.db, cq, params)
 	if err != nil {
 		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
 	}
@@ -90,45 +134,32 @@ func (f feedbackRepository) RetrieveByUserID(ctx context.Context, userID string,
 	}, nil
 }
 
-func (f feedbackRepository) RetrieveByPharmacyID(ctx context.Context, pharmacyID string, date string, offset uint64, limit uint64) (model.FeedbackItemPage, error) {
-	lt := struct {
-		Exists bool `db:"exists"`
-	}{}
-
-	nt := fmt.Sprintf("feedback_%s", date)
-	q := `SELECT EXISTS(SELECT tablename FROM pg_catalog.pg_tables WHERE tablename = $1);`
-	if err := f.db.GetContext(ctx, &lt, q, nt); err != nil {
-		level.Error(f.log).Log("method", "f.db.GetContext", "sql", q, "nt", nt, "err", err)
-		return model.FeedbackItemPage{}, err
-	}
-
-	if !lt.Exists {
-		return model.FeedbackItemPage{Items: []model.Feedback{}}, nil
-	}
-
-	items := []model.Feedback{}
-	q = fmt.Sprintf(`select * from %s where pharmacy_id = $1 order by created_at desc limit $2 offset $3`, nt)
-	if err := f.db.SelectContext(ctx, &items, q, pharmacyID, limit, offset); err != nil {
-		level.Error(f.log).Log("method", "f.db.SelectContext", "sql", q, "pharmacyID", pharmacyID, "limit", limit, "offset", offset)
-		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+// listQuery selects a page of one day of feedbacks by column, served by the
+// feedback_<column>_created_at_idx indexes.
+func listQuery(column string, hideBanned bool) (string, error) {
+	quoted, err := postgres.QuoteIdentifier(column)
+	if err != nil {
+		return "", err
 	}
+	return fmt.Sprintf(`select * from feedback where %s = :value and created_at >= :from and created_at < :to%s
+		order by created_at desc limit :limit offset :offset`, quoted, bannedFilter(hideBanned)), nil
+}
 
-	cq := fmt.Sprintf(`select count(*) from %s where pharmacy_id = :pharmacy_id`, nt)
-	total, err := total(ctx, f.db, cq, map[string]interface{}{
-		"pharmacy_id": pharmacyID,
-	})
+func countQuery(column string, hideBanned bool) (string, error) {
+	quoted, err := postgres.QuoteIdentifier(column)
 	if err != nil {
-		return model.FeedbackItemPage{Items: []model.Feedback{}}, err
+		return "", err
 	}
+	return fmt.Sprintf(`select count(*) from feedback where %s = :value and created_at >= :from and created_at < :to%s`, quoted, bannedFilter(hideBanned)), nil
+}
 
-	return model.FeedbackItemPage{
-		Items: items,
-		PageMetadata: model.PageMetadata{
-			Total:  total,
-			Limit:  limit,
-			Offset: offset,
-		},
-	}, nil
+// bannedFilter is the condition leaving out the feedbacks of banned users.
+func bannedFilter(hideBanned bool) string {
+	if !hideBanned {
+		return ""
+	}
+	return `
+		and not exists (select 1 from feedback_suspects s where s.user_id = feedback.user_id and s.banned)`
 }
 
 func (f feedbackRepository) ListOption(ctx context.Context) ([]model.Option, error) {
@@ -140,43 +171,21 @@ func (f feedbackRepository) ListOption(ctx context.Context) ([]model.Option, err
 	return options, nil
 }
 
-func (f feedbackRepository) GetLatestFeedbackTableName(ctx context.Context, nt string) error {
-	lt := struct {
-		Exists bool `db:"exists"`
-	}{}
-
-	q := `SELECT EXISTS(SELECT tablename FROM pg_catalog.pg_tables WHERE tablename = $1);`
-	if err := f.db.GetContext(ctx, &lt, q, nt); err != nil {
-		level.Error(f.log).Log("method", "f.db.GetContext", "sql", q, "nt", nt, "err", err)
-		return err
-	}
-
-	if lt.Exists {
-		return nil
-	}
-
-	q = fmt.Sprintf(`create table if not exists %s
-		(
-			id   varchar(21)  not null
-				constraint %s_pkey
-					primary key,
-			user_id varchar(30) default ''::character varying not null,
-			pharmacy_id varchar(10) default ''::character varying not null,
-			option_id varchar(21) default ''::character varying not null,
-			description varchar(1024) default ''::character varying not null,
-			longitude double precision default 0.0 not null,
-			latitude double precision default 0.0 not null,
-			created_at timestamp with time zone default now() not null
-		);
-
-		alter table %s owner to postgres;`, nt, nt, nt)
-
-	if _, err := f.db.ExecContext(ctx, q); err != nil {
-		level.Error(f.log).Log("method", "f.db.ExecContext", "sql", q, "err", err)
-		return err
+func (f feedbackRepository) Export(ctx context.Context, from, to time.Time) (model.FeedbackCursor, error) {
+	q := `select * from feedback where created_at >= $1 and created_at < $2 order by created_at`
+	rows, err := f.db.QueryxContext(ctx, q, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, f.log)).Log("method", "f.db.QueryxContext", "sql", q, "err", err)
+		return nil, errors.Wrap(ErrExportFeedbackFromDB, err)
 	}
+	return feedbackCursor{rows}, nil
+}
 
-	return nil
+// CreatePartitions creates the daily feedback partitions of the given number
+// of days starting at from, skipping those that already exist.
+func CreatePartitions(ctx context.Context, db *sqlx.DB, from time.Time, days int) error {
+	_, err := db.ExecContext(ctx, `select feedback_create_partitions($1::date, $2)`, from.In(util.Location).Format("2006-01-02"), days)
+	return err
 }
 
 func total(ctx context.Context, db *sqlx.DB, query string, params map[string]interface{}) (uint64, error) {
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/feedback/repotest"
+	"github.com/cage1016/mask/internal/pkg/postgres/pgtest"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+// The benchmarks run against the pgtest database, like the conformance
+// suite, seeded with MASK_TEST_FEEDBACK_ROWS feedbacks per day.
+const (
+	seedDays    = 7
+	seedUsers   = 5000
+	seedStores  = 6000
+	defaultRows = 50000
+)
+
+var seeded bool
+
+func testDB(tb testing.TB) *sqlx.DB {
+	db := pgtest.Require(tb)
+
+	// the database may have been migrated days ago
+	if err := CreatePartitions(context.Background(), db, time.Now(), 1); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// seededDB is testDB seeded once per run with the benchmark feedbacks.
+func seededDB(tb testing.TB) *sqlx.DB {
+	db := testDB(tb)
+	if !seeded {
+		seed(tb, db)
+		seeded = true
+	}
+	return db
+}
+
+// seed replaces the benchmark rows, spread over seedDays days ending today,
+// and refreshes the planner statistics.
+func seed(tb testing.TB, db *sqlx.DB) {
+	rows := defaultRows
+	if s := os.Getenv(pgtest.Prefix + "FEEDBACK_ROWS"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		rows = n
+	}
+
+	ctx := context.Background()
+	from := today().AddDate(0, 0, -(seedDays - 1))
+	if err := CreatePartitions(ctx, db, from, seedDays); err != nil {
+		tb.Fatal(err)
+	}
+
+	stmts := []struct {
+		q    string
+		args []interface{}
+	}{
+		{`delete from feedback where id like 'bench-%'`, nil},
+		{`insert into feedback (id, user_id, pharmacy_id, option_id, description, created_at)
+			select 'bench-' || i,
+				'u' || (i % $1),
+				lpad((i % $2)::text, 10, '0'),
+				'ddCp1m88O4g5SU1GDJRPi',
+				'',
+				$3::timestamptz + (i % $4) * interval '1 day' + (i % 86400) * interval '1 second'
+			from generate_series(1, $5::int) i`,
+			[]interface{}{seedUsers, seedStores, from, seedDays, rows * seedDays}},
+		{`analyze feedback`, nil},
+	}
+	for _, s := range stmts {
+		if _, err := db.ExecContext(ctx, s.q, s.args...); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func today() time.Time {
+	y, m, d := time.Now().In(util.Location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, util.Location)
+}
+
+func params(value string) map[string]interface{} {
+	return map[string]interface{}{
+		"value":  value,
+		"from":   today(),
+		"to":     today().AddDate(0, 0, 1),
+		"limit":  20,
+		"offset": 0,
+	}
+}
+
+// scans collects the node types of a JSON query plan touching a relation.
+func scans(plan interface{}, found map[string]string) {
+	switch p := plan.(type) {
+	case []interface{}:
+		for _, n := range p {
+			scans(n, found)
+		}
+	case map[string]interface{}:
+		if rel, ok := p["Relation Name"].(string); ok {
+			found[rel] = p["Node Type"].(string)
+		}
+		for _, k := range []string{"Plan", "Plans"} {
+			if n, ok := p[k]; ok {
+				scans(n, found)
+			}
+		}
+	}
+}
+
+func TestFeedbackRepository(t *testing.T) {
+	repotest.Run(t, New(testDB(t), log.NewNopLogger()))
+}
+
+func TestSuspectRepository(t *testing.T) {
+	db := testDB(t)
+	repotest.RunSuspects(t, New(db, log.NewNopLogger()), NewSuspectRepository(db, log.NewNopLogger()))
+}
+
+func TestListQueriesUseIndexes(t *testing.T) {
+	db := seededDB(t)
+
+	for _, c := range []struct {
+		column, value string
+		hideBanned    bool
+	}{
+		{"user_id", "u42", false},
+		{"pharmacy_id", "0000000042", true},
+	} {
+		for _, build := range []func(string, bool) (string, error){listQuery, countQuery} {
+			q, err := build(c.column, c.hideBanned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, args, err := sqlx.Named(`explain (format json) `+q, params(c.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out string
+			if err := db.GetContext(context.Background(), &out, db.Rebind(query), args...); err != nil {
+				t.Fatal(err)
+			}
+			var plan interface{}
+			if err := json.Unmarshal([]byte(out), &plan); err != nil {
+				t.Fatal(err)
+			}
+
+			found := map[string]string{}
+			scans(plan, found)
+			// the banned filter probes the suspects by primary key
+			delete(found, "feedback_suspects")
+			if len(found) != 1 {
+				t.Errorf("%s: expected partition pruning to one day, scanned %v", c.column, found)
+			}
+			for rel, node := range found {
+				switch node {
+				case "Index Scan", "Index Only Scan", "Bitmap Heap Scan":
+				default:
+					t.Errorf("%s: %s on %s, want an index scan\n%s", c.column, node, rel, out)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkRetrieveByUserID(b *testing.B) {
+	repo := New(seededDB(b), log.NewNopLogger())
+	date := today().Format(dayFormat)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.RetrieveByUserID(ctx, "u"+strconv.Itoa(i%seedUsers), date, 0, 20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRetrieveByPharmacyID(b *testing.B) {
+	repo := New(seededDB(b), log.NewNopLogger())
+	date := today().Format(dayFormat)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := strconv.Itoa(i % seedStores)
+		for len(id) < 10 {
+			id = "0" + id
+		}
+		if _, err := repo.RetrieveByPharmacyID(ctx, id, date, 0, 20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
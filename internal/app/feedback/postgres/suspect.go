@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+)
+
+var (
+	ErrSaveSuspectToDB       = errors.New("save suspect to DB failed")
+	ErrRetrieveSuspectFromDB = errors.New("retrieve suspect from DB failed")
+)
+
+var _ model.SuspectRepository = (*suspectRepository)(nil)
+
+type suspectRepository struct {
+	db  *sqlx.DB
+	log log.Logger
+}
+
+// NewSuspectRepository instantiates a PostgreSQL implementation of suspect
+// repository. The feedback repository of the same database leaves out the
+// feedback of the users it bans.
+func NewSuspectRepository(db *sqlx.DB, log log.Logger) model.SuspectRepository {
+	return &suspectRepository{db, log}
+}
+
+func (s suspectRepository) Retrieve(ctx context.Context, userID string) (model.Suspect, error) {
+	var suspect model.Suspect
+	q := `SELECT user_id, score, reports, banned, updated_at FROM feedback_suspects WHERE user_id = $1;`
+	if err := s.db.GetContext(ctx, &suspect, q, userID); err == sql.ErrNoRows {
+		return model.Suspect{UserID: userID}, nil
+	} else if err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.GetContext", "err", err)
+		return model.Suspect{}, errors.Wrap(ErrRetrieveSuspectFromDB, err)
+	}
+	return suspect, nil
+}
+
+// Record folds the score in the upsert, which locks the row of the user, so
+// concurrent reports of a user all count. The SET expressions read the row
+// as it was before the update.
+func (s suspectRepository) Record(ctx context.Context, userID string, score float64, scoring model.Scoring) (model.Suspect, error) {
+	q := `INSERT INTO feedback_suspects (user_id, score, reports, banned, updated_at)
+			VALUES ($1, $2, 1, 1 >= $5::bigint AND $2 >= $4, now())
+			ON CONFLICT (user_id) DO UPDATE SET
+				score = $3 * $2 + (1 - $3) * feedback_suspects.score,
+				reports = feedback_suspects.reports + 1,
+				banned = feedback_suspects.banned OR (feedback_suspects.reports + 1 >= $5::bigint
+					AND $3 * $2 + (1 - $3) * feedback_suspects.score >= $4),
+				updated_at = now()
+			RETURNING user_id, score, reports, banned, updated_at;`
+	var suspect model.Suspect
+	if err := s.db.GetContext(ctx, &suspect, q, userID, score, scoring.Alpha, scoring.Threshold, scoring.MinReports); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.GetContext", "err", err)
+		return model.Suspect{}, errors.Wrap(ErrSaveSuspectToDB, err)
+	}
+	return suspect, nil
+}
+
+func (s suspectRepository) RetrieveBanned(ctx context.Context, offset, limit uint64) (model.SuspectPage, error) {
+	items := []model.Suspect{}
+	q := `SELECT user_id, score, reports, banned, updated_at FROM feedback_suspects WHERE banned
+			ORDER BY score DESC, user_id LIMIT $1 OFFSET $2;`
+	if err := s.db.SelectContext(ctx, &items, q, limit, offset); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return model.SuspectPage{Items: []model.Suspect{}}, errors.Wrap(ErrRetrieveSuspectFromDB, err)
+	}
+
+	var total uint64
+	if err := s.db.GetContext(ctx, &total, `SELECT count(*) FROM feedback_suspects WHERE banned;`); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.GetContext", "err", err)
+		return model.SuspectPage{Items: []model.Suspect{}}, errors.Wrap(ErrRetrieveSuspectFromDB, err)
+	}
+
+	return model.SuspectPage{
+		Items: items,
+		PageMetadata: model.PageMetadata{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, nil
+}
+
+func (s suspectRepository) Remove(ctx context.Context, userID string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM feedback_suspects WHERE user_id = $1;`, userID)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.ExecContext", "err", err)
+		return errors.Wrap(ErrSaveSuspectToDB, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrSuspectNotFound
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package pseudonym
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/cage1016/mask/internal/app/feedback/service"
+)
+
+var _ service.Pseudonymizer = (*hmacPseudonymizer)(nil)
+
+type hmacPseudonymizer struct {
+	key []byte
+}
+
+// New instantiates a HMAC-SHA256 pseudonymizer. The same key always yields
+// the same pseudonym for a user, so exports can still be joined per user.
+func New(key string) service.Pseudonymizer {
+	return &hmacPseudonymizer{key: []byte(key)}
+}
+
+func (p *hmacPseudonymizer) Pseudonymize(id string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,354 @@
+// Package repotest is the conformance suite every model.FeedbackRepository
+// implementation must pass.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+const dayFormat = "2006_0102"
+
+// Run checks repo against the behaviour shared by the implementations. The
+// feedbacks it inserts use random IDs, so it tolerates repositories already
+// holding data.
+func Run(t *testing.T, repo model.FeedbackRepository) {
+	t.Run("ListOption", func(t *testing.T) { testListOption(t, repo) })
+	t.Run("Retrieve", func(t *testing.T) { testRetrieve(t, repo) })
+	t.Run("Export", func(t *testing.T) { testExport(t, repo) })
+}
+
+func testListOption(t *testing.T, repo model.FeedbackRepository) {
+	options, err := repo.ListOption(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]bool{}
+	for _, o := range options {
+		ids[o.ID] = true
+	}
+	for _, id := range []string{"IRESxM58KC~dqg5XLCH~n", "ddCp1m88O4g5SU1GDJRPi", "uYrYL~7Gd65IN2wWsWa9A", "nAn6pj8UkrXST1syShrzV"} {
+		if !ids[id] {
+			t.Errorf("option %s is missing from %v", id, options)
+		}
+	}
+}
+
+// fixture inserts three feedbacks of one user, two of them for the same
+// pharmacy, and returns them in insertion order.
+func fixture(t *testing.T, repo model.FeedbackRepository) []model.Feedback {
+	n := rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(1e9)
+	user := fmt.Sprintf("user-%09d", n)
+	pharmacies := []string{fmt.Sprintf("p%09d", n), fmt.Sprintf("p%09d", n), fmt.Sprintf("q%09d", n)}
+
+	var feedbacks []model.Feedback
+	for i, pharmacyID := range pharmacies {
+		f := model.Feedback{
+			ID:          fmt.Sprintf("f%09d-%d", n, i),
+			UserID:      user,
+			PharmacyID:  pharmacyID,
+			OptionID:    "ddCp1m88O4g5SU1GDJRPi",
+			Description: fmt.Sprintf("feedback %d", i),
+			Longitude:   121.5,
+			Latitude:    25.0,
+		}
+		id, err := repo.Insert(context.Background(), f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != f.ID {
+			t.Fatalf("Insert returned %q, want %q", id, f.ID)
+		}
+		feedbacks = append(feedbacks, f)
+		// keep created_at strictly increasing
+		time.Sleep(2 * time.Millisecond)
+	}
+	return feedbacks
+}
+
+func ids(items []model.Feedback) []string {
+	ids := make([]string, len(items))
+	for i, f := range items {
+		ids[i] = f.ID
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testRetrieve(t *testing.T, repo model.FeedbackRepository) {
+	ctx := context.Background()
+	feedbacks := fixture(t, repo)
+	today := time.Now().In(util.Location).Format(dayFormat)
+	f0, f1, f2 := feedbacks[0], feedbacks[1], feedbacks[2]
+
+	cases := []struct {
+		desc   string
+		get    func() (model.FeedbackItemPage, error)
+		ids    []string
+		total  uint64
+		offset uint64
+		limit  uint64
+	}{
+		{
+			desc:  "user, newest first",
+			get:   func() (model.FeedbackItemPage, error) { return repo.RetrieveByUserID(ctx, f0.UserID, today, 0, 10) },
+			ids:   []string{f2.ID, f1.ID, f0.ID},
+			total: 3, limit: 10,
+		},
+		{
+			desc:  "user, second page",
+			get:   func() (model.FeedbackItemPage, error) { return repo.RetrieveByUserID(ctx, f0.UserID, today, 1, 1) },
+			ids:   []string{f1.ID},
+			total: 3, offset: 1, limit: 1,
+		},
+		{
+			desc:  "user, past the last page",
+			get:   func() (model.FeedbackItemPage, error) { return repo.RetrieveByUserID(ctx, f0.UserID, today, 3, 10) },
+			ids:   []string{},
+			total: 3, offset: 3, limit: 10,
+		},
+		{
+			desc: "pharmacy",
+			get: func() (model.FeedbackItemPage, error) {
+				return repo.RetrieveByPharmacyID(ctx, f0.PharmacyID, today, 0, 10)
+			},
+			ids:   []string{f1.ID, f0.ID},
+			total: 2, limit: 10,
+		},
+		{
+			desc: "another day",
+			get: func() (model.FeedbackItemPage, error) {
+				return repo.RetrieveByUserID(ctx, f0.UserID, "2000_0101", 0, 10)
+			},
+			ids:   []string{},
+			total: 0, limit: 10,
+		},
+		{
+			desc:  "unknown user",
+			get:   func() (model.FeedbackItemPage, error) { return repo.RetrieveByUserID(ctx, f0.UserID+"x", today, 0, 10) },
+			ids:   []string{},
+			total: 0, limit: 10,
+		},
+	}
+
+	for _, tc := range cases {
+		page, err := tc.get()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tc.desc, err)
+			continue
+		}
+		if page.Items == nil {
+			t.Errorf("%s: nil items, want an empty slice", tc.desc)
+		}
+		if got := ids(page.Items); !equal(got, tc.ids) {
+			t.Errorf("%s: got %v, want %v", tc.desc, got, tc.ids)
+		}
+		want := model.PageMetadata{Total: tc.total, Offset: tc.offset, Limit: tc.limit}
+		if page.PageMetadata != want {
+			t.Errorf("%s: got page %+v, want %+v", tc.desc, page.PageMetadata, want)
+		}
+	}
+
+	if page, err := repo.RetrieveByUserID(ctx, f0.UserID, today, 0, 10); err == nil && len(page.Items) > 0 {
+		got := page.Items[len(page.Items)-1]
+		if got.PharmacyID != f0.PharmacyID || got.OptionID != f0.OptionID || got.Description != f0.Description ||
+			got.Longitude != f0.Longitude || got.Latitude != f0.Latitude || got.CreatedAt.IsZero() {
+			t.Errorf("got %+v, want the fields of %+v and a creation time", got, f0)
+		}
+	}
+
+	if _, err := repo.RetrieveByUserID(ctx, f0.UserID, "2020-03-01", 0, 10); err == nil {
+		t.Error("malformed date: expected an error")
+	}
+}
+
+func testExport(t *testing.T, repo model.FeedbackRepository) {
+	feedbacks := fixture(t, repo)
+	y, m, d := time.Now().In(util.Location).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, util.Location)
+
+	cursor, err := repo.Export(context.Background(), today, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cursor.Close()
+
+	var (
+		exported []string
+		last     time.Time
+	)
+	for cursor.Next() {
+		f, err := cursor.Feedback()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.CreatedAt.Before(last) {
+			t.Errorf("%s exported after a newer feedback", f.ID)
+		}
+		last = f.CreatedAt
+		exported = append(exported, f.ID)
+	}
+	if err := cursor.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fixture must appear in order; other feedbacks of the day may be
+	// interleaved
+	want := ids(feedbacks)
+	for _, id := range exported {
+		if len(want) > 0 && id == want[0] {
+			want = want[1:]
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("feedbacks %v missing from the export", want)
+	}
+
+	cursor, err = repo.Export(context.Background(), today.AddDate(-30, 0, 0), today.AddDate(-30, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		f, _ := cursor.Feedback()
+		for _, id := range ids(feedbacks) {
+			if f.ID == id {
+				t.Errorf("%s exported out of range", id)
+			}
+		}
+	}
+}
+
+// RunSuspects checks suspects against the behaviour shared by the
+// implementations, and that feedbacks, a repository of the same store, leaves
+// out the feedback of banned users from the feedback of pharmacies only.
+func RunSuspects(t *testing.T, feedbacks model.FeedbackRepository, suspects model.SuspectRepository) {
+	ctx := context.Background()
+	f0 := fixture(t, feedbacks)[0]
+	user := f0.UserID
+	today := time.Now().In(util.Location).Format(dayFormat)
+	scoring := model.Scoring{Alpha: 0.5, Threshold: 0.6, MinReports: 2}
+
+	s, err := suspects.Retrieve(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (model.Suspect{UserID: user}); s != want {
+		t.Errorf("unscored: got %+v, want %+v", s, want)
+	}
+	before, err := suspects.RetrieveBanned(ctx, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first report sets the score, the second one bans the user, the
+	// third one keeps them banned although it brings the score down
+	for i, want := range []model.Suspect{
+		{UserID: user, Score: 0.8, Reports: 1},
+		{UserID: user, Score: 0.9, Reports: 2, Banned: true},
+		{UserID: user, Score: 0.45, Reports: 3, Banned: true},
+	} {
+		got, err := suspects.Record(ctx, user, []float64{0.8, 1, 0}[i], scoring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.UpdatedAt.IsZero() {
+			t.Errorf("report %d: got no update time", i+1)
+		}
+		got.UpdatedAt = time.Time{}
+		if got.UserID != want.UserID || got.Reports != want.Reports || got.Banned != want.Banned || math.Abs(got.Score-want.Score) > 1e-9 {
+			t.Errorf("report %d: got %+v, want %+v", i+1, got, want)
+		}
+	}
+
+	page, err := suspects.RetrieveBanned(ctx, 0, before.Total+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != before.Total+1 || !hasSuspect(page.Items, user) {
+		t.Errorf("got banned %+v, want %s among %d", page, user, before.Total+1)
+	}
+	checkBanned(t, feedbacks, f0.PharmacyID, user, today, true)
+
+	if err := suspects.Remove(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := suspects.Remove(ctx, user); err != model.ErrSuspectNotFound {
+		t.Errorf("removed twice: got %v, want %v", err, model.ErrSuspectNotFound)
+	}
+	if s, err := suspects.Retrieve(ctx, user); err != nil || s.Reports != 0 || s.Banned {
+		t.Errorf("removed: got %+v, %v, want the zero score", s, err)
+	}
+	checkBanned(t, feedbacks, f0.PharmacyID, user, today, false)
+
+	// concurrent reports of a user all count
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := suspects.Record(ctx, user, 0, scoring); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if s, err := suspects.Retrieve(ctx, user); err != nil || s.Reports != 20 {
+		t.Errorf("concurrent reports: got %+v, %v, want 20 reports", s, err)
+	}
+	if err := suspects.Remove(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasSuspect(suspects []model.Suspect, userID string) bool {
+	for _, s := range suspects {
+		if s.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBanned checks the feedback of user is left out of the pharmacy of the
+// fixture only while they are banned.
+func checkBanned(t *testing.T, feedbacks model.FeedbackRepository, pharmacyID, user, today string, banned bool) {
+	t.Helper()
+	ctx := context.Background()
+
+	page, err := feedbacks.RetrieveByPharmacyID(ctx, pharmacyID, today, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(page.Items); banned && (n != 0 || page.Total != 0) || !banned && (n != 2 || page.Total != 2) {
+		t.Errorf("banned %t: got pharmacy feedbacks %v of %d", banned, ids(page.Items), page.Total)
+	}
+
+	page, err = feedbacks.RetrieveByUserID(ctx, user, today, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 3 {
+		t.Errorf("banned %t: got user feedbacks %v, want all of them", banned, ids(page.Items))
+	}
+}
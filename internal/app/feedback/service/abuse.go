@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+)
+
+// Scorer specifies an API for scoring feedback for abuse before it is stored.
+type Scorer interface {
+	// Score rates f against the history of its user and returns the updated
+	// standing of the user, banned once their reports look coordinated or
+	// contradictory. The feedback of a banned user is still stored, but no
+	// longer listed on pharmacies or streamed.
+	Score(ctx context.Context, f model.Feedback) (model.Suspect, error)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+)
+
+type instrumentingMiddleware struct {
+	requestCount   metrics.Counter
+	errorCount     metrics.Counter
+	requestLatency metrics.Histogram
+	insertCount    metrics.Counter
+	next           FeedbacksvcService
+}
+
+// InstrumentingMiddleware returns a service middleware that counts requests
+// and errors and observes the latency of every method. Successfully recorded
+// feedbacks are counted by insertCount.
+func InstrumentingMiddleware(requestCount, errorCount metrics.Counter, requestLatency metrics.Histogram, insertCount metrics.Counter) Middleware {
+	return func(next FeedbacksvcService) FeedbacksvcService {
+		return instrumentingMiddleware{requestCount, errorCount, requestLatency, insertCount, next}
+	}
+}
+
+func (im instrumentingMiddleware) observe(method string, begin time.Time, err error) {
+	im.requestCount.With("method", method).Add(1)
+	im.requestLatency.With("method", method).Observe(time.Since(begin).Seconds())
+	if err != nil {
+		im.errorCount.With("method", method).Add(1)
+	}
+}
+
+func (im instrumentingMiddleware) Options(ctx context.Context) (items []model.Option, err error) {
+	defer func(begin time.Time) { im.observe("Options", begin, err) }(time.Now())
+	return im.next.Options(ctx)
+}
+
+func (im instrumentingMiddleware) PharmacyFeedBacks(ctx context.Context, pharmacyID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error) {
+	defer func(begin time.Time) { im.observe("PharmacyFeedBacks", begin, err) }(time.Now())
+	return im.next.PharmacyFeedBacks(ctx, pharmacyID, date, offset, limit)
+}
+
+func (im instrumentingMiddleware) UserFeedBacks(ctx context.Context, userID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error) {
+	defer func(begin time.Time) { im.observe("UserFeedBacks", begin, err) }(time.Now())
+	return im.next.UserFeedBacks(ctx, userID, date, offset, limit)
+}
+
+func (im instrumentingMiddleware) InsertFeedBack(ctx context.Context, userID, pharmacyID, optionID, description string, Longitude, Latitude float64) (id string, err error) {
+	defer func(begin time.Time) {
+		im.observe("InsertFeedBack", begin, err)
+		if err == nil {
+			im.insertCount.Add(1)
+		}
+	}(time.Now())
+	return im.next.InsertFeedBack(ctx, userID, pharmacyID, optionID, description, Longitude, Latitude)
+}
+
+func (im instrumentingMiddleware) Export(ctx context.Context, from string, to string) (cursor model.FeedbackCursor, err error) {
+	defer func(begin time.Time) { im.observe("Export", begin, err) }(time.Now())
+	return im.next.Export(ctx, from, to)
+}
+
+func (im instrumentingMiddleware) Suspects(ctx context.Context, offset, limit uint64) (res model.SuspectPage, err error) {
+	defer func(begin time.Time) { im.observe("Suspects", begin, err) }(time.Now())
+	return im.next.Suspects(ctx, offset, limit)
+}
+
+func (im instrumentingMiddleware) Unban(ctx context.Context, userID string) (err error) {
+	defer func(begin time.Time) { im.observe("Unban", begin, err) }(time.Now())
+	return im.next.Unban(ctx, userID)
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
 
 	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
 )
 
 type loggingMiddleware struct {
@@ -24,7 +25,7 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 
 func (lm loggingMiddleware) Options(ctx context.Context) (items []model.Option, err error) {
 	defer func() {
-		lm.logger.Log("method", "Options", "err", err)
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Options", "err", err)
 	}()
 
 	return lm.next.Options(ctx)
@@ -32,7 +33,7 @@ func (lm loggingMiddleware) Options(ctx context.Context) (items []model.Option,
 
 func (lm loggingMiddleware) PharmacyFeedBacks(ctx context.Context, pharmacyID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error) {
 	defer func() {
-		lm.logger.Log("method", "PharmacyFeedBacks", "pharmacyID", pharmacyID, "date", date, "offset", offset, "limit", limit, "err", err)
+		logging.WithRequestID(ctx, lm.logger).Log("method", "PharmacyFeedBacks", "pharmacyID", pharmacyID, "date", date, "offset", offset, "limit", limit, "err", err)
 	}()
 
 	return lm.next.PharmacyFeedBacks(ctx, pharmacyID, date, offset, limit)
@@ -40,7 +41,7 @@ func (lm loggingMiddleware) PharmacyFeedBacks(ctx context.Context, pharmacyID st
 
 func (lm loggingMiddleware) UserFeedBacks(ctx context.Context, userID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error) {
 	defer func() {
-		lm.logger.Log("method", "UserFeedBacks", "userID", userID, "date", date, "offset", offset, "limit", limit, "err", err)
+		logging.WithRequestID(ctx, lm.logger).Log("method", "UserFeedBacks", "userID", userID, "date", date, "offset", offset, "limit", limit, "err", err)
 	}()
 
 	return lm.next.UserFeedBacks(ctx, userID, date, offset, limit)
@@ -48,8 +49,32 @@ func (lm loggingMiddleware) UserFeedBacks(ctx context.Context, userID string, da
 
 func (lm loggingMiddleware) InsertFeedBack(ctx context.Context, userID, pharmacyID, optionID, description string, Longitude, Latitude float64) (id string, err error) {
 	defer func() {
-		lm.logger.Log("method", "InsertFeedBack", "userID", userID, "pharmacyID", pharmacyID, "optionID", optionID, "description", description, "Longitude", Longitude, "Latitude", Latitude, "err", err)
+		logging.WithRequestID(ctx, lm.logger).Log("method", "InsertFeedBack", "userID", userID, "pharmacyID", pharmacyID, "optionID", optionID, "description", description, "Longitude", Longitude, "Latitude", Latitude, "err", err)
 	}()
 
 	return lm.next.InsertFeedBack(ctx, userID, pharmacyID, optionID, description, Longitude, Latitude)
 }
+
+func (lm loggingMiddleware) Export(ctx context.Context, from string, to string) (cursor model.FeedbackCursor, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Export", "from", from, "to", to, "err", err)
+	}()
+
+	return lm.next.Export(ctx, from, to)
+}
+
+func (lm loggingMiddleware) Suspects(ctx context.Context, offset, limit uint64) (res model.SuspectPage, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Suspects", "offset", offset, "limit", limit, "err", err)
+	}()
+
+	return lm.next.Suspects(ctx, offset, limit)
+}
+
+func (lm loggingMiddleware) Unban(ctx context.Context, userID string) (err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Unban", "userID", userID, "err", err)
+	}()
+
+	return lm.next.Unban(ctx, userID)
+}
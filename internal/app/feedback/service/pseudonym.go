@@ -0,0 +1,8 @@
+package service
+
+// Pseudonymizer specifies an API for replacing user identifiers in exported
+// data with stable, non-reversible pseudonyms.
+type Pseudonymizer interface {
+	// Pseudonymize returns the pseudonym of id.
+	Pseudonymize(id string) string
+}
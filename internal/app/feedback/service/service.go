@@ -2,10 +2,14 @@ package service
 
 import (
 	"context"
+	"time"
+
 	"github.com/go-kit/kit/log"
 
 	"github.com/cage1016/mask/internal/app/feedback/model"
 	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/util"
 )
 
 const QueryDatefmt = "2006_0102"
@@ -27,27 +31,36 @@ type Middleware func(FeedbacksvcService) FeedbacksvcService
 type FeedbacksvcService interface {
 	// [method=get,expose=true,router=api/feedback/options]
 	Options(ctx context.Context) (items []model.Option, err error)
-	// [method=get,expose=true,router=api/feedback/pharmacies/:pharmacie_id]
+	// [method=get,expose=true,router=api/feedback/pharmacies/:pharmacy_id]
 	PharmacyFeedBacks(ctx context.Context, PharmacyID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error)
 	// [method=get,expose=true,router=api/feedback/users/:user_id]
 	UserFeedBacks(ctx context.Context, userID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error)
 	// [method=post,expose=true,router=api/feedback]
 	InsertFeedBack(ctx context.Context, userID, pharmacyID, optionID, description string, Longitude, Latitude float64) (id string, err error)
+	// [method=get,expose=true,router=api/feedback/export]
+	Export(ctx context.Context, from string, to string) (cursor model.FeedbackCursor, err error)
+	// [method=get,expose=true,router=api/feedback/suspects]
+	Suspects(ctx context.Context, offset, limit uint64) (res model.SuspectPage, err error)
+	// [method=delete,expose=true,router=api/feedback/suspects/:user_id]
+	Unban(ctx context.Context, userID string) (err error)
 }
 
 // the concrete implementation of service interface
 type stubFeedbacksvcService struct {
-	logger  log.Logger
-	repo    model.FeedbackRepository
-	idpNano NanoIdentityProvider
+	logger     log.Logger
+	repo       model.FeedbackRepository
+	suspects   model.SuspectRepository
+	idpNano    NanoIdentityProvider
+	pseudonyms Pseudonymizer
+	scorer     Scorer
 }
 
 // New return a new instance of the service.
 // If you want to add service middleware this is the place to put them.
-func New(repo model.FeedbackRepository, idpNano NanoIdentityProvider, logger log.Logger) (s FeedbacksvcService) {
+func New(repo model.FeedbackRepository, suspects model.SuspectRepository, idpNano NanoIdentityProvider, pseudonyms Pseudonymizer, scorer Scorer, logger log.Logger) (s FeedbacksvcService) {
 	var svc FeedbacksvcService
 	{
-		svc = &stubFeedbacksvcService{repo: repo, idpNano: idpNano, logger: logger}
+		svc = &stubFeedbacksvcService{repo: repo, suspects: suspects, idpNano: idpNano, pseudonyms: pseudonyms, scorer: scorer, logger: logger}
 		svc = LoggingMiddleware(logger)(svc)
 	}
 	return svc
@@ -71,7 +84,7 @@ func (fe *stubFeedbacksvcService) UserFeedBacks(ctx context.Context, userID stri
 // Implement the business logic of InsertFeedBack
 func (fe *stubFeedbacksvcService) InsertFeedBack(ctx context.Context, userID, pharmacyID, optionID, description string, Longitude, Latitude float64) (id string, err error) {
 	nid, _ := fe.idpNano.ID()
-	return fe.repo.Insert(ctx, model.Feedback{
+	f := model.Feedback{
 		ID:          nid,
 		UserID:      userID,
 		PharmacyID:  pharmacyID,
@@ -79,5 +92,57 @@ func (fe *stubFeedbacksvcService) InsertFeedBack(ctx context.Context, userID, ph
 		Description: description,
 		Longitude:   Longitude,
 		Latitude:    Latitude,
-	})
+	}
+
+	// Scoring must not stand in the way of honest users, so the feedback is
+	// stored even when it fails.
+	suspect, err := fe.scorer.Score(ctx, f)
+	if err != nil {
+		level.Warn(fe.logger).Log("method", "Score", "user_id", userID, "err", err)
+	} else if suspect.Banned {
+		level.Info(fe.logger).Log("method", "Score", "user_id", userID, "score", suspect.Score, "banned", true)
+	}
+
+	return fe.repo.Insert(ctx, f)
+}
+
+// Implement the business logic of Export
+func (fe *stubFeedbacksvcService) Export(ctx context.Context, from string, to string) (cursor model.FeedbackCursor, err error) {
+	f, err := time.ParseInLocation(QueryDatefmt, from, util.Location)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedEntity, err)
+	}
+
+	t, err := time.ParseInLocation(QueryDatefmt, to, util.Location)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedEntity, err)
+	}
+
+	c, err := fe.repo.Export(ctx, f, t)
+	if err != nil {
+		return nil, err
+	}
+	return pseudonymCursor{c, fe.pseudonyms}, nil
+}
+
+// Implement the business logic of Suspects
+func (fe *stubFeedbacksvcService) Suspects(ctx context.Context, offset, limit uint64) (res model.SuspectPage, err error) {
+	return fe.suspects.RetrieveBanned(ctx, offset, limit)
+}
+
+// Implement the business logic of Unban
+func (fe *stubFeedbacksvcService) Unban(ctx context.Context, userID string) (err error) {
+	return fe.suspects.Remove(ctx, userID)
+}
+
+// pseudonymCursor replaces user identifiers of every feedback it yields.
+type pseudonymCursor struct {
+	model.FeedbackCursor
+	pseudonyms Pseudonymizer
+}
+
+func (c pseudonymCursor) Feedback() (model.Feedback, error) {
+	f, err := c.FeedbackCursor.Feedback()
+	f.UserID = c.pseudonyms.Pseudonymize(f.UserID)
+	return f, err
 }
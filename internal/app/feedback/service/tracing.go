@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+)
+
+type tracingMiddleware struct {
+	tracer trace.Tracer
+	next   FeedbacksvcService
+}
+
+// TracingMiddleware returns a service middleware that wraps every method in a
+// span, child of the endpoint span carried by ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next FeedbacksvcService) FeedbacksvcService {
+		return tracingMiddleware{tracer, next}
+	}
+}
+
+func (tm tracingMiddleware) Options(ctx context.Context) (items []model.Option, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Options")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Options(ctx)
+}
+
+func (tm tracingMiddleware) PharmacyFeedBacks(ctx context.Context, pharmacyID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.PharmacyFeedBacks")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.PharmacyFeedBacks(ctx, pharmacyID, date, offset, limit)
+}
+
+func (tm tracingMiddleware) UserFeedBacks(ctx context.Context, userID string, date string, offset, limit uint64) (res model.FeedbackItemPage, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.UserFeedBacks")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.UserFeedBacks(ctx, userID, date, offset, limit)
+}
+
+func (tm tracingMiddleware) InsertFeedBack(ctx context.Context, userID, pharmacyID, optionID, description string, Longitude, Latitude float64) (id string, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.InsertFeedBack")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.InsertFeedBack(ctx, userID, pharmacyID, optionID, description, Longitude, Latitude)
+}
+
+func (tm tracingMiddleware) Export(ctx context.Context, from string, to string) (cursor model.FeedbackCursor, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Export")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Export(ctx, from, to)
+}
+
+func (tm tracingMiddleware) Suspects(ctx context.Context, offset, limit uint64) (res model.SuspectPage, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Suspects")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Suspects(ctx, offset, limit)
+}
+
+func (tm tracingMiddleware) Unban(ctx context.Context, userID string) (err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Unban")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Unban(ctx, userID)
+}
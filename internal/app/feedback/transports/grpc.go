@@ -0,0 +1,277 @@
+package transports
+
+import (
+	"context"
+	"time"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/mask/internal/app/feedback/endpoints"
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/export"
+	"github.com/cage1016/mask/internal/pkg/util"
+	pb "github.com/cage1016/mask/pb/feedback"
+)
+
+type grpcServer struct {
+	pb.UnimplementedFeedbackServiceServer
+
+	options           grpctransport.Handler
+	pharmacyFeedBacks grpctransport.Handler
+	userFeedBacks     grpctransport.Handler
+	insertFeedBack    grpctransport.Handler
+	export            endpoint.Endpoint
+}
+
+// NewGRPCServer makes a set of endpoints available as a gRPC FeedbackService.
+func NewGRPCServer(endpoints endpoints.Endpoints, logger log.Logger) pb.FeedbackServiceServer {
+	options := []grpctransport.ServerOption{
+		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(kitjwt.GRPCToContext()),
+	}
+
+	return &grpcServer{
+		options: grpctransport.NewServer(
+			endpoints.OptionsEndpoint,
+			decodeGRPCOptionsRequest,
+			encodeGRPCOptionsResponse,
+			options...,
+		),
+		pharmacyFeedBacks: grpctransport.NewServer(
+			endpoints.PharmacyFeedBacksEndpoint,
+			decodeGRPCPharmacyFeedBacksRequest,
+			encodeGRPCPharmacyFeedBacksResponse,
+			options...,
+		),
+		userFeedBacks: grpctransport.NewServer(
+			endpoints.UserFeedBacksEndpoint,
+			decodeGRPCUserFeedBacksRequest,
+			encodeGRPCUserFeedBacksResponse,
+			options...,
+		),
+		insertFeedBack: grpctransport.NewServer(
+			endpoints.FeedBackEndpoint,
+			decodeGRPCInsertFeedBackRequest,
+			encodeGRPCInsertFeedBackResponse,
+			options...,
+		),
+		export: endpoints.ExportEndpoint,
+	}
+}
+
+func (s *grpcServer) Options(ctx context.Context, req *pb.OptionsRequest) (rep *pb.OptionsReply, err error) {
+	_, rp, err := s.options.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.OptionsReply)
+	return rep, nil
+}
+
+func (s *grpcServer) PharmacyFeedBacks(ctx context.Context, req *pb.PharmacyFeedBacksRequest) (rep *pb.FeedbackPage, err error) {
+	_, rp, err := s.pharmacyFeedBacks.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.FeedbackPage)
+	return rep, nil
+}
+
+func (s *grpcServer) UserFeedBacks(ctx context.Context, req *pb.UserFeedBacksRequest) (rep *pb.FeedbackPage, err error) {
+	_, rp, err := s.userFeedBacks.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.FeedbackPage)
+	return rep, nil
+}
+
+func (s *grpcServer) InsertFeedBack(ctx context.Context, req *pb.InsertFeedBackRequest) (rep *pb.InsertFeedBackReply, err error) {
+	_, rp, err := s.insertFeedBack.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.InsertFeedBackReply)
+	return rep, nil
+}
+
+// Export streams the export cursor one feedback per message. go-kit only
+// serves unary calls, so the endpoint is invoked directly, with the token
+// the server options would otherwise move into the context.
+func (s *grpcServer) Export(req *pb.ExportRequest, stream pb.FeedbackService_ExportServer) error {
+	ctx := stream.Context()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = kitjwt.GRPCToContext()(ctx, md)
+	}
+	rp, err := s.export(ctx, endpoints.ExportRequest{
+		From:   req.GetFrom(),
+		To:     req.GetTo(),
+		Format: export.FormatNDJSON,
+	})
+	if err != nil {
+		return grpcEncodeError(err)
+	}
+
+	cursor := rp.(endpoints.ExportResponse).Cursor
+	defer cursor.Close()
+
+	for cursor.Next() {
+		f, err := cursor.Feedback()
+		if err != nil {
+			return grpcEncodeError(err)
+		}
+		if err := stream.Send(toPBFeedback(f)); err != nil {
+			return err
+		}
+	}
+	return grpcEncodeError(cursor.Err())
+}
+
+// decodeGRPCOptionsRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCOptionsRequest(_ context.Context, _ interface{}) (interface{}, error) {
+	return endpoints.OptionsRequest{}, nil
+}
+
+// encodeGRPCOptionsResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCOptionsResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.OptionsResponse)
+
+	rep := &pb.OptionsReply{Items: make([]*pb.Option, 0, len(reply.Items))}
+	for _, o := range reply.Items {
+		rep.Items = append(rep.Items, &pb.Option{Id: o.ID, Name: o.Name})
+	}
+	return rep, nil
+}
+
+// decodeGRPCPharmacyFeedBacksRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCPharmacyFeedBacksRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.PharmacyFeedBacksRequest)
+	return endpoints.PharmacyFeedBacksRequest{
+		PharmacyID: req.GetPharmacyId(),
+		Date:       grpcDate(req.GetDate()),
+		Offset:     req.GetOffset(),
+		Limit:      grpcLimit(req.GetLimit()),
+	}, nil
+}
+
+// encodeGRPCPharmacyFeedBacksResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCPharmacyFeedBacksResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.PharmacyFeedBacksResponse)
+	return toPBFeedbackPage(reply.Res), nil
+}
+
+// decodeGRPCUserFeedBacksRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCUserFeedBacksRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UserFeedBacksRequest)
+	return endpoints.UserFeedBacksRequest{
+		UserID: req.GetUserId(),
+		Date:   grpcDate(req.GetDate()),
+		Offset: req.GetOffset(),
+		Limit:  grpcLimit(req.GetLimit()),
+	}, nil
+}
+
+// encodeGRPCUserFeedBacksResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCUserFeedBacksResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.UserFeedBacksResponse)
+	return toPBFeedbackPage(reply.Res), nil
+}
+
+// decodeGRPCInsertFeedBackRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCInsertFeedBackRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.InsertFeedBackRequest)
+	return endpoints.FeedBackRequest{
+		UserID:      req.GetUserId(),
+		PharmacyID:  req.GetPharmacyId(),
+		OptionID:    req.GetOptionId(),
+		Description: req.GetDescription(),
+		Longitude:   req.GetLongitude(),
+		Latitude:    req.GetLatitude(),
+	}, nil
+}
+
+// encodeGRPCInsertFeedBackResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCInsertFeedBackResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.FeedBackResponse)
+	return &pb.InsertFeedBackReply{Id: reply.ID}, nil
+}
+
+// grpcDate defaults an empty date to today, like the HTTP routes do.
+func grpcDate(date string) string {
+	if date == "" {
+		return time.Now().In(util.Location).Format(service.QueryDatefmt)
+	}
+	return date
+}
+
+// grpcLimit defaults an unset limit, like the HTTP routes do.
+func grpcLimit(limit uint64) uint64 {
+	if limit == 0 {
+		return defLimit
+	}
+	return limit
+}
+
+func toPBFeedbackPage(page model.FeedbackItemPage) *pb.FeedbackPage {
+	rep := &pb.FeedbackPage{
+		Total:  page.Total,
+		Offset: page.Offset,
+		Limit:  page.Limit,
+		Items:  make([]*pb.FeedbackItem, 0, len(page.Items)),
+	}
+	for _, f := range page.Items {
+		rep.Items = append(rep.Items, toPBFeedback(f))
+	}
+	return rep
+}
+
+func toPBFeedback(f model.Feedback) *pb.FeedbackItem {
+	return &pb.FeedbackItem{
+		Id:          f.ID,
+		UserId:      f.UserID,
+		PharmacyId:  f.PharmacyID,
+		OptionId:    f.OptionID,
+		Description: f.Description,
+		Longitude:   f.Longitude,
+		Latitude:    f.Latitude,
+		CreatedAt:   f.CreatedAt.In(util.Location).Format(time.RFC3339),
+	}
+}
+
+// grpcEncodeError maps service errors to gRPC status codes, the way
+// httpEncodeError maps them to HTTP ones.
+func grpcEncodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	code := codes.Internal
+	if errorVal, ok := err.(errors.Error); ok {
+		switch {
+		case errors.Contains(errorVal, service.ErrMalformedEntity),
+			errors.Contains(errorVal, service.ErrInvalidQueryParams):
+			code = codes.InvalidArgument
+		}
+	}
+
+	if err == kitjwt.ErrTokenContextMissing || err == kitjwt.ErrTokenInvalid {
+		code = codes.Unauthenticated
+	}
+	return status.Error(code, err.Error())
+}
@@ -11,13 +11,22 @@ import (
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-zoo/bone"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"io"
 	"net/http"
 
 	"github.com/cage1016/mask/internal/app/feedback/endpoints"
+	"github.com/cage1016/mask/internal/app/feedback/model"
 	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/auth"
 	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/export"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/openapi"
 	"github.com/cage1016/mask/internal/pkg/responses"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+	"github.com/cage1016/mask/internal/pkg/util"
 )
 
 const (
@@ -27,100 +36,106 @@ const (
 	defLimit  = 10
 )
 
-// ShowFeedback godoc
-// @Summary feedback options
-// @Description The endpoint for Retailbase to fetch feedback options
-// @Tags feedback
-// @Accept json
-// @Produce json
-// @Success 200 {object} endpoints.OptionsResponse
-// @Failure 400 {object} responses.ErrorRes
-// @Failure 500 {object} responses.ErrorRes
-// @Router /api/feedback/options [get]
+// OptionsHandler routes GET /api/feedback/options, also under /api/v2.
 func OptionsHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
-	m.Get("/api/feedback/options", httptransport.NewServer(
+	h := httptransport.NewServer(
 		endpoints.OptionsEndpoint,
 		decodeHTTPOptionsRequest,
 		encodeJSONResponse,
 		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
-	))
+	)
+	m.Get("/api/feedback/options", h)
+	m.Get("/api/v2/feedback/options", h)
 
 }
 
-// ShowFeedback godoc
-// @Summary specific pharmacy feedbacks
-// @Description The endpoint for Retailbase to fetch specific pharmacy feedbacks
-// @Tags feedback
-// @Accept json
-// @Produce json
-// @Param   offset     query    int     true        "Offset"
-// @Param   limit      query    int     true        "limit"
-// @Param   date      query    string     true       "date, yyyy_mmdd"
-// @Param pharmacy_id path string true "Pharmacy ID"
-// @Success 200 {object} model.FeedbackItemPage
-// @Failure 400 {object} responses.ErrorRes
-// @Failure 500 {object} responses.ErrorRes
-// @Router /api/feedback/pharmacies/{pharmacy_id} [get]
+// PharmacyFeedBacksHandler routes GET /api/feedback/pharmacies/:pharmacy_id, also under /api/v2.
 func PharmacyFeedBacksHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
-	m.Get("/api/feedback/pharmacies/:pharmacy_id", httptransport.NewServer(
+	h := httptransport.NewServer(
 		endpoints.PharmacyFeedBacksEndpoint,
 		decodeHTTPPharmacyFeedBacksRequest,
 		encodeJSONResponse,
 		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
-	))
+	)
+	m.Get("/api/feedback/pharmacies/:pharmacy_id", h)
+	m.Get("/api/v2/feedback/pharmacies/:pharmacy_id", h)
 
 }
 
-// ShowFeedback godoc
-// @Summary specific user feedbacks
-// @Description The endpoint for Retailbase to fetch specific user feedbacks
-// @Tags feedback
-// @Accept json
-// @Produce json
-// @Param user_id path string true "User ID"
-// @Param   offset     query    int     true        "Offset"
-// @Param   limit      query    int     true        "limit"
-// @Param   date      query    string     true       "date, yyyy_mmdd"
-// @Success 200 {object} model.FeedbackItemPage
-// @Failure 400 {object} responses.ErrorRes
-// @Failure 500 {object} responses.ErrorRes
-// @Router /api/feedback/users/{user_id} [get]
+// UserFeedBacksHandler routes GET /api/feedback/users/:user_id, also under /api/v2.
 func UserFeedBacksHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
-	m.Get("/api/feedback/users/:user_id", httptransport.NewServer(
+	h := httptransport.NewServer(
 		endpoints.UserFeedBacksEndpoint,
 		decodeHTTPUserFeedBacksRequest,
 		encodeJSONResponse,
 		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
-	))
+	)
+	m.Get("/api/feedback/users/:user_id", h)
+	m.Get("/api/v2/feedback/users/:user_id", h)
 
 }
 
-// ShowFeedback godoc
-// @Summary specific pharmacy feedbacks
-// @Description The endpoint for Retailbase to fetch specific pharmacy feedbacks
-// @Tags feedback
-// @Accept json
-// @Produce json
-// @Param user body endpoints.FeedBackRequest true "Feedback"
-// @Success 200 {object} endpoints.FeedBackResponse
-// @Failure 400 {object} responses.ErrorRes
-// @Failure 500 {object} responses.ErrorRes
-// @Router /api/feedback [post]
+// FeedBackHandler routes POST /api/feedback, also under /api/v2.
 func FeedBackHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
-	m.Post("/api/feedback", httptransport.NewServer(
+	h := httptransport.NewServer(
 		endpoints.FeedBackEndpoint,
 		decodeHTTPFeedBackRequest,
 		encodeJSONResponse,
 		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
-	))
+	)
+	m.Post("/api/feedback", h)
+	m.Post("/api/v2/feedback", h)
+}
+
+// ExportHandler routes GET /api/feedback/export, also under /api/v2.
+func ExportHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.ExportEndpoint,
+		decodeHTTPExportRequest,
+		encodeExportResponse(logger),
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Get("/api/feedback/export", h)
+	m.Get("/api/v2/feedback/export", h)
+}
+
+// SuspectsHandler routes GET /api/feedback/suspects, also under /api/v2.
+func SuspectsHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.SuspectsEndpoint,
+		decodeHTTPSuspectsRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Get("/api/feedback/suspects", h)
+	m.Get("/api/v2/feedback/suspects", h)
+}
+
+// UnbanHandler routes DELETE /api/feedback/suspects/:user_id, also under /api/v2.
+func UnbanHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.UnbanEndpoint,
+		decodeHTTPUnbanRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Delete("/api/feedback/suspects/:user_id", h)
+	m.Delete("/api/v2/feedback/suspects/:user_id", h)
 }
 
 // NewHTTPHandler returns a handler that makes a set of endpoints available on
-// predefined paths.
-func NewHTTPHandler(endpoints endpoints.Endpoints, logger log.Logger) http.Handler { // Zipkin HTTP Server Trace can either be instantiated per endpoint with a
+// predefined paths. /metrics requires adminToken.
+func NewHTTPHandler(endpoints endpoints.Endpoints, adminToken string, logger log.Logger) http.Handler {
+	m := newMux(endpoints, adminToken, logger)
+	return cors.AllowAll().Handler(logging.RequestIDHandler(tracing.HTTPHandler(m, "/metrics")))
+}
+
+// newMux registers the routes of NewHTTPHandler.
+func newMux(endpoints endpoints.Endpoints, adminToken string, logger log.Logger) *bone.Mux {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(httpEncodeError),
 		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerBefore(responses.VersionToContext),
 	}
 
 	m := bone.New()
@@ -128,7 +143,12 @@ func NewHTTPHandler(endpoints endpoints.Endpoints, logger log.Logger) http.Handl
 	PharmacyFeedBacksHandler(m, endpoints, options, logger)
 	UserFeedBacksHandler(m, endpoints, options, logger)
 	FeedBackHandler(m, endpoints, options, logger)
-	return cors.AllowAll().Handler(m)
+	ExportHandler(m, endpoints, options, logger)
+	SuspectsHandler(m, endpoints, options, logger)
+	UnbanHandler(m, endpoints, options, logger)
+	m.Get("/openapi.json", openapi.Handler(OpenAPI()))
+	m.Get("/metrics", auth.Handler(adminToken, promhttp.Handler()))
+	return m
 }
 
 // decodeHTTPOptionsRequest is a transport/http.DecodeRequestFunc that decodes a
@@ -147,7 +167,7 @@ func decodeHTTPPharmacyFeedBacksRequest(_ context.Context, r *http.Request) (int
 	if len(s) > 0 {
 		req.Date = s[0]
 	} else {
-		req.Date = time.Now().Format(service.QueryDatefmt)
+		req.Date = time.Now().In(util.Location).Format(service.QueryDatefmt)
 	}
 
 	var err error
@@ -173,7 +193,7 @@ func decodeHTTPUserFeedBacksRequest(_ context.Context, r *http.Request) (interfa
 	if len(s) > 0 {
 		req.Date = s[0]
 	} else {
-		req.Date = time.Now().Format(service.QueryDatefmt)
+		req.Date = time.Now().In(util.Location).Format(service.QueryDatefmt)
 	}
 
 	var err error
@@ -198,7 +218,55 @@ func decodeHTTPFeedBackRequest(_ context.Context, r *http.Request) (interface{},
 	return req, err
 }
 
-func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
+// decodeHTTPExportRequest is a transport/http.DecodeRequestFunc that decodes
+// the export range and format from the query string. Primarily useful in a server.
+func decodeHTTPExportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.ExportRequest
+	today := time.Now().In(util.Location).Format(service.QueryDatefmt)
+	req.From, req.To = today, today
+	if s := bone.GetQuery(r, "from"); len(s) > 0 {
+		req.From = s[0]
+	}
+	if s := bone.GetQuery(r, "to"); len(s) > 0 {
+		req.To = s[0]
+	}
+
+	f, err := export.ParseFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if err != nil {
+		return nil, errors.Wrap(service.ErrMalformedEntity, err)
+	}
+	req.Format = f
+	return req, nil
+}
+
+// decodeHTTPSuspectsRequest is a transport/http.DecodeRequestFunc that decodes
+// the paging from the URL. Primarily useful in a server.
+func decodeHTTPSuspectsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.SuspectsRequest
+
+	var err error
+	req.Offset, err = readUintQuery(r, "offset", defOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Limit, err = readUintQuery(r, "limit", defLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeHTTPUnbanRequest is a transport/http.DecodeRequestFunc that decodes
+// the user id from the path. Primarily useful in a server.
+func decodeHTTPUnbanRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.UnbanRequest
+	req.UserID = bone.GetValue(r, "user_id")
+	return req, nil
+}
+
+func httpEncodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	code := http.StatusInternalServerError
 	var message string
 	var errs []errors.Errors
@@ -208,8 +276,11 @@ func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 	switch errorVal := err.(type) {
 	case errors.Error:
 		switch {
-		case errors.Contains(errorVal, service.ErrMalformedEntity):
+		case errors.Contains(errorVal, service.ErrMalformedEntity),
+			errors.Contains(errorVal, service.ErrInvalidQueryParams):
 			code = http.StatusBadRequest
+		case errors.Contains(errorVal, model.ErrSuspectNotFound):
+			code = http.StatusNotFound
 		}
 
 		if errorVal.Msg() != "" {
@@ -219,7 +290,7 @@ func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 		switch err {
 		case io.ErrUnexpectedEOF, io.EOF:
 			code = http.StatusBadRequest
-		case kitjwt.ErrTokenContextMissing:
+		case kitjwt.ErrTokenContextMissing, kitjwt.ErrTokenInvalid:
 			code = http.StatusUnauthorized
 		default:
 			switch err.(type) {
@@ -233,10 +304,15 @@ func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 	}
 
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(responses.ErrorRes{responses.ErrorResItem{code, message, errs}})
+	item := responses.ErrorResItem{Code: code, Message: message, Errors: errs}
+	if responses.Version(ctx) == 2 {
+		json.NewEncoder(w).Encode(responses.Envelope{APIVersion: service.Version, Error: &item})
+		return
+	}
+	json.NewEncoder(w).Encode(responses.ErrorRes{Error: item})
 }
 
-func encodeJSONResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+func encodeJSONResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if headerer, ok := response.(httptransport.Headerer); ok {
 		for k, values := range headerer.Headers() {
@@ -254,6 +330,13 @@ func encodeJSONResponse(_ context.Context, w http.ResponseWriter, response inter
 		return nil
 	}
 
+	if responses.Version(ctx) == 2 {
+		if er, ok := response.(responses.Enveloper); ok {
+			return json.NewEncoder(w).Encode(er.Envelope())
+		}
+		return json.NewEncoder(w).Encode(responses.Envelope{APIVersion: service.Version, Data: response})
+	}
+
 	if ar, ok := response.(responses.Responser); ok {
 		return json.NewEncoder(w).Encode(ar.Response())
 	}
@@ -261,6 +344,47 @@ func encodeJSONResponse(_ context.Context, w http.ResponseWriter, response inter
 	return json.NewEncoder(w).Encode(response)
 }
 
+// encodeExportResponse streams the export cursor row by row. Once the first
+// row is written the status line is gone, so later failures are only logged.
+func encodeExportResponse(logger log.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		logger := logging.WithRequestID(ctx, logger)
+		res := response.(endpoints.ExportResponse)
+		defer res.Cursor.Close()
+
+		for k, values := range res.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+
+		ew, err := export.NewWriter(w, res.Format, model.FeedbackCSVHeader)
+		if err != nil {
+			level.Error(logger).Log("method", "export.NewWriter", "err", err)
+			return nil
+		}
+
+		for res.Cursor.Next() {
+			f, err := res.Cursor.Feedback()
+			if err != nil {
+				level.Error(logger).Log("method", "res.Cursor.Feedback", "err", err)
+				break
+			}
+			if err := ew.Write(&f); err != nil {
+				level.Error(logger).Log("method", "ew.Write", "err", err)
+				return nil
+			}
+		}
+		if err := res.Cursor.Err(); err != nil {
+			level.Error(logger).Log("method", "res.Cursor.Err", "err", err)
+		}
+		if err := ew.Flush(); err != nil {
+			level.Error(logger).Log("method", "ew.Flush", "err", err)
+		}
+		return nil
+	}
+}
+
 func readUintQuery(r *http.Request, key string, def uint64) (uint64, error) {
 	vals := bone.GetQuery(r, key)
 	if len(vals) > 1 {
@@ -0,0 +1,184 @@
+package transports
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/feedback/abuse"
+	"github.com/cage1016/mask/internal/app/feedback/endpoints"
+	"github.com/cage1016/mask/internal/app/feedback/nanoid"
+	feedbackPostgres "github.com/cage1016/mask/internal/app/feedback/postgres"
+	"github.com/cage1016/mask/internal/app/feedback/pseudonym"
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+// recorder is a database/sql driver answering every statement with no rows
+// and recording the SQL text it was sent.
+type recorder struct {
+	mu   sync.Mutex
+	stmt []string
+}
+
+func (r *recorder) Open(string) (driver.Conn, error) { return recorderConn{r}, nil }
+
+func (r *recorder) record(q string) {
+	r.mu.Lock()
+	r.stmt = append(r.stmt, q)
+	r.mu.Unlock()
+}
+
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stmt := r.stmt
+	r.stmt = nil
+	return stmt
+}
+
+type recorderConn struct{ r *recorder }
+
+func (c recorderConn) Prepare(q string) (driver.Stmt, error) { return recorderStmt{c.r, q}, nil }
+func (c recorderConn) Close() error                          { return nil }
+func (c recorderConn) Begin() (driver.Tx, error)             { return c, nil }
+func (c recorderConn) Commit() error                         { return nil }
+func (c recorderConn) Rollback() error                       { return nil }
+
+type recorderStmt struct {
+	r *recorder
+	q string
+}
+
+func (s recorderStmt) Close() error  { return nil }
+func (s recorderStmt) NumInput() int { return -1 }
+
+func (s recorderStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.r.record(s.q)
+	return driver.RowsAffected(1), nil
+}
+
+func (s recorderStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.r.record(s.q)
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return nil }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
+
+const adminToken = "admin-token"
+
+var (
+	rec      = &recorder{}
+	register sync.Once
+)
+
+func newHandler(t testing.TB) http.Handler {
+	register.Do(func() { sql.Register("recorder", rec) })
+	db, err := sql.Open("recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := log.NewNopLogger()
+	dbx := sqlx.NewDb(db, "postgres")
+	repo := feedbackPostgres.New(dbx, logger)
+	suspects := feedbackPostgres.NewSuspectRepository(dbx, logger)
+	scorer := abuse.New(repo, suspects, nil, abuse.Config{})
+	svc := service.New(repo, suspects, nanoid.New(), pseudonym.New("key"), scorer, logger)
+	return NewHTTPHandler(endpoints.New(svc, adminToken, logger), adminToken, logger)
+}
+
+// requests builds the requests carrying id and date through every decoder
+// ending in a query.
+func requests(id, date string) []*http.Request {
+	q := "?date=" + url.QueryEscape(date)
+	body, _ := json.Marshal(endpoints.FeedBackRequest{
+		UserID:      id,
+		PharmacyID:  id,
+		OptionID:    id,
+		Description: date,
+	})
+	export := httptest.NewRequest(http.MethodGet, "/api/feedback/export?from="+url.QueryEscape(date)+"&to="+url.QueryEscape(date), nil)
+	export.Header.Set("Authorization", "Bearer "+adminToken)
+	return []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/feedback/users/"+url.PathEscape(id)+q, nil),
+		httptest.NewRequest(http.MethodGet, "/api/feedback/pharmacies/"+url.PathEscape(id)+q, nil),
+		export,
+		httptest.NewRequest(http.MethodPost, "/api/feedback", strings.NewReader(string(body))),
+	}
+}
+
+// FuzzDecodersNeverAlterSQL asserts that whatever IDs and dates the clients
+// send, the statements reaching the database are the ones issued for
+// well-formed requests; the values only ever travel as bind parameters.
+func FuzzDecodersNeverAlterSQL(f *testing.F) {
+	h := newHandler(f)
+
+	allowed := map[string]bool{}
+	for _, r := range requests("0123456789", "2020_0301") {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	for _, q := range rec.take() {
+		allowed[q] = true
+	}
+	if len(allowed) == 0 {
+		f.Fatal("well-formed requests issued no statements")
+	}
+
+	f.Add("0123456789", "2020_0301")
+	f.Add("0123456789'; drop table feedback; --", "2020_0301")
+	f.Add("0123456789", "2020_0301' or '1'='1")
+	f.Add(`" or 1=1 --`, "2020_0301;select pg_sleep(10)")
+	f.Add("%27%3B", "9999_1231")
+	f.Add("", "")
+	f.Fuzz(func(t *testing.T, id, date string) {
+		for _, r := range requests(id, date) {
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		}
+		for _, q := range rec.take() {
+			if !allowed[q] {
+				t.Fatalf("id %q and date %q issued an unexpected statement:\n%s", id, date, q)
+			}
+		}
+	})
+}
+
+func TestDefaultDatesAreTaipeiDays(t *testing.T) {
+	today := time.Now().In(util.Location).Format(service.QueryDatefmt)
+
+	req, err := decodeHTTPExportRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/api/feedback/export?format=csv", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := req.(endpoints.ExportRequest); r.From != today || r.To != today {
+		t.Errorf("export: got %s - %s, want %s", r.From, r.To, today)
+	}
+
+	req, err = decodeHTTPPharmacyFeedBacksRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/api/feedback/pharmacies/x", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := req.(endpoints.PharmacyFeedBacksRequest); r.Date != today {
+		t.Errorf("pharmacy feedbacks: got %s, want %s", r.Date, today)
+	}
+
+	if got := grpcDate(""); got != today {
+		t.Errorf("gRPC: got %s, want %s", got, today)
+	}
+}
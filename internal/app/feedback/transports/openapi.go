@@ -0,0 +1,114 @@
+package transports
+
+import (
+	"net/http"
+
+	"github.com/cage1016/mask/internal/app/feedback/endpoints"
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/openapi"
+)
+
+// OpenAPI returns the OpenAPI document of the routes of NewHTTPHandler.
+func OpenAPI() *openapi.Document {
+	return openapi.Spec{
+		Info: openapi.Info{
+			Title:       "Mask feedback API",
+			Description: "User feedback on the mask stock of pharmacies.",
+			Version:     service.Version,
+		},
+		Tags: []openapi.Tag{
+			{Name: "feedback", Description: "User feedback on the mask stock of pharmacies"},
+			{Name: "suspect", Description: "Review of the users banned for abusive feedback"},
+		},
+		Routes: []openapi.Route{
+			{
+				Method:   http.MethodGet,
+				Path:     "/feedback/options",
+				ID:       "feedbackOptions",
+				Summary:  "Fetch the feedback options",
+				Tags:     []string{"feedback"},
+				Response: endpoints.OptionsResponse{},
+				Errors:   []int{http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/feedback/pharmacies/:pharmacy_id",
+				ID:       "pharmacyFeedbacks",
+				Summary:  "Fetch the feedback on a pharmacy of a day",
+				Tags:     []string{"feedback"},
+				Query:    pageParams(),
+				Response: endpoints.PharmacyFeedBacksResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/feedback/users/:user_id",
+				ID:       "userFeedbacks",
+				Summary:  "Fetch the feedback of a user of a day",
+				Tags:     []string{"feedback"},
+				Query:    pageParams(),
+				Response: endpoints.UserFeedBacksResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodPost,
+				Path:     "/feedback",
+				ID:       "submitFeedback",
+				Summary:  "Submit feedback on a pharmacy",
+				Tags:     []string{"feedback"},
+				Request:  endpoints.FeedBackRequest{},
+				Response: endpoints.FeedBackResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/feedback/export",
+				ID:      "exportFeedbacks",
+				Summary: "Export the feedback of a date range with pseudonymized user ids, with the admin token",
+				Tags:    []string{"feedback"},
+				Query: []openapi.Parameter{
+					dateParam("from", "first day, yyyy_mmdd, defaults to today"),
+					dateParam("to", "last day, yyyy_mmdd, defaults to today"),
+					{Name: "format", In: "query", Description: "csv or ndjson, else negotiated from Accept", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Response: endpoints.ExportResponse{},
+				Produces: []string{"text/csv", "application/x-ndjson"},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError},
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/feedback/suspects",
+				ID:      "suspects",
+				Summary: "Fetch the banned users for review, highest score first, with the admin token",
+				Tags:    []string{"suspect"},
+				Query: []openapi.Parameter{
+					{Name: "offset", In: "query", Description: "defaults to 0", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+					{Name: "limit", In: "query", Description: "1 - 100, defaults to 10", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+				},
+				Response: endpoints.SuspectsResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodDelete,
+				Path:     "/feedback/suspects/:user_id",
+				ID:       "unban",
+				Summary:  "Clear the abuse score of a user, which unbans them, with the admin token",
+				Tags:     []string{"suspect"},
+				Response: endpoints.UnbanResponse{},
+				Errors:   []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+			},
+		},
+	}.Document()
+}
+
+func dateParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func pageParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		dateParam("date", "yyyy_mmdd, defaults to today"),
+		{Name: "offset", In: "query", Description: "defaults to 0", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+		{Name: "limit", In: "query", Description: "1 - 100, defaults to 10", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+	}
+}
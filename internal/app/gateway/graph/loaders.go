@@ -0,0 +1,303 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cage1016/mask/internal/pkg/dataloader"
+	"github.com/cage1016/mask/internal/pkg/export"
+	"github.com/cage1016/mask/pkg/client"
+)
+
+const (
+	// allOptions is the options key of the whole option list. Option ids are
+	// nanoids, which never contain it.
+	allOptions = "*"
+
+	// feedbackFanOut bounds the concurrent calls of one feedback batch.
+	feedbackFanOut = 8
+)
+
+// loaders batch the loads of one request.
+type loaders struct {
+	// pharmacies loads pharmacies by id from the index of the export, since
+	// the pharmacy service has no lookup by id.
+	pharmacies *dataloader.Loader
+	// options loads options by id, or the whole list as allOptions, from the
+	// option list, fetched once per request.
+	options *dataloader.Loader
+	// feedback loads feedback pages by feedbackKey. The feedback service has
+	// no lookup of several pharmacies, so a batch fans out one call per page.
+	feedback *dataloader.Loader
+
+	r *Resolver
+
+	mu          sync.Mutex
+	optionsList []client.Option
+}
+
+type loadersKey struct{}
+
+func newLoaders(r *Resolver) *loaders {
+	l := &loaders{r: r}
+	l.pharmacies = dataloader.New(l.loadPharmacies)
+	l.options = dataloader.New(l.loadOptions)
+	l.feedback = dataloader.New(l.loadFeedback)
+	return l
+}
+
+// WithLoaders attaches fresh loaders to every request before next serves it,
+// so loads are batched and cached within a request only, but for the
+// pharmacy index the requests share.
+func (r *Resolver) WithLoaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), loadersKey{}, newLoaders(r))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// loaders returns the loaders of the request, or unshared ones outside of
+// WithLoaders.
+func (r *Resolver) loaders(ctx context.Context) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders(r)
+}
+
+func (l *loaders) loadPharmacies(ctx context.Context, ids []string) []dataloader.Result {
+	results := make([]dataloader.Result, len(ids))
+
+	byID, err := l.r.index.get(ctx, l.r.pharmacies)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	for i, id := range ids {
+		if p, ok := byID[id]; ok {
+			results[i].Value = p
+		}
+	}
+	return results
+}
+
+// pharmacyIndex caches the pharmacies of the export by id, shared by the
+// requests for ttl. A snapshot is published every few minutes, so a lookup
+// may lag it by ttl.
+type pharmacyIndex struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	byID    map[string]client.Pharmacy
+	fetched time.Time
+}
+
+// get returns the index, reading the export again once it is older than ttl.
+// The lock is not held during the download: requests finding the index stale
+// at once each read the export.
+func (x *pharmacyIndex) get(ctx context.Context, pharmacies PharmacyService) (map[string]client.Pharmacy, error) {
+	x.mu.Lock()
+	byID, fetched := x.byID, x.fetched
+	x.mu.Unlock()
+	if byID != nil && time.Since(fetched) < x.ttl {
+		return byID, nil
+	}
+
+	body, err := pharmacies.Export(ctx, export.FormatNDJSON)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	byID = map[string]client.Pharmacy{}
+	dec := json.NewDecoder(body)
+	for {
+		var p client.Pharmacy
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		byID[p.Id] = p
+	}
+
+	x.mu.Lock()
+	x.byID, x.fetched = byID, time.Now()
+	x.mu.Unlock()
+	return byID, nil
+}
+
+// listOptions returns the option list, fetching it on the first call only.
+func (l *loaders) listOptions(ctx context.Context) ([]client.Option, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.optionsList == nil {
+		items, err := l.r.feedback.Options(ctx)
+		if err != nil {
+			return nil, err
+		}
+		l.optionsList = items
+	}
+	return l.optionsList, nil
+}
+
+func (l *loaders) loadOptions(ctx context.Context, ids []string) []dataloader.Result {
+	results := make([]dataloader.Result, len(ids))
+
+	items, err := l.listOptions(ctx)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	for i, id := range ids {
+		if id == allOptions {
+			results[i].Value = items
+			continue
+		}
+		for _, o := range items {
+			if o.ID == id {
+				results[i].Value = o
+				break
+			}
+		}
+	}
+	return results
+}
+
+// feedbackKey is the feedback loader key of req.
+func feedbackKey(req client.PharmacyFeedBacksRequest) string {
+	b, _ := json.Marshal(req)
+	return string(b)
+}
+
+func (l *loaders) loadFeedback(ctx context.Context, keys []string) []dataloader.Result {
+	results := make([]dataloader.Result, len(keys))
+	sem := make(chan struct{}, feedbackFanOut)
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var req client.PharmacyFeedBacksRequest
+			if err := json.Unmarshal([]byte(key), &req); err != nil {
+				results[i].Err = err
+				return
+			}
+			page, err := l.r.feedback.PharmacyFeedBacks(ctx, req)
+			results[i] = dataloader.Result{Value: page, Err: err}
+		}(i, key)
+	}
+	wg.Wait()
+	return results
+}
+
+// loadFeedbackPages loads the pages of reqs, which join the same batches.
+func (l *loaders) loadFeedbackPages(ctx context.Context, reqs []client.PharmacyFeedBacksRequest) ([]client.FeedbackItemPage, error) {
+	pages := make([]client.FeedbackItemPage, len(reqs))
+	errs := make([]error, len(reqs))
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req client.PharmacyFeedBacksRequest) {
+			defer wg.Done()
+			v, err := l.feedback.Load(ctx, feedbackKey(req))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			pages[i] = v.(client.FeedbackItemPage)
+		}(i, req)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// feedbackRange pages through the feedback of days as if they were one day.
+// Every day is loaded with its first offset+limit feedbacks, which covers the
+// page unless it is deeper than maxLimit; the days it reaches further into
+// are loaded again at the offset of the page.
+func (l *loaders) feedbackRange(ctx context.Context, pharmacyID string, days []string, offset, limit uint64) (client.FeedbackItemPage, error) {
+	head := offset + limit
+	if head > maxLimit {
+		head = maxLimit
+	}
+
+	reqs := make([]client.PharmacyFeedBacksRequest, len(days))
+	for i, d := range days {
+		reqs[i] = client.PharmacyFeedBacksRequest{PharmacyID: pharmacyID, Date: d, Limit: head}
+	}
+	pages, err := l.loadFeedbackPages(ctx, reqs)
+	if err != nil {
+		return client.FeedbackItemPage{}, err
+	}
+
+	// windows are the parts of the days the page covers, cut from the pages
+	// loaded above, or loaded again when deeper than head
+	res := client.FeedbackItemPage{Items: []client.Feedback{}}
+	res.Offset, res.Limit = offset, limit
+	var windows, deep []client.PharmacyFeedBacksRequest
+	var cut []client.FeedbackItemPage
+	skip, left := offset, limit
+	for i, p := range pages {
+		res.Total += p.Total
+		switch {
+		case left == 0:
+		case skip >= p.Total:
+			skip -= p.Total
+		default:
+			n := p.Total - skip
+			if n > left {
+				n = left
+			}
+			w := client.PharmacyFeedBacksRequest{PharmacyID: pharmacyID, Date: days[i], Offset: skip, Limit: n}
+			windows, cut = append(windows, w), append(cut, p)
+			if w.Offset+w.Limit > head {
+				deep = append(deep, w)
+			}
+			skip, left = 0, left-n
+		}
+	}
+
+	deepPages, err := l.loadFeedbackPages(ctx, deep)
+	if err != nil {
+		return client.FeedbackItemPage{}, err
+	}
+	for i, w := range windows {
+		if w.Offset+w.Limit > head {
+			res.Items = append(res.Items, deepPages[0].Items...)
+			deepPages = deepPages[1:]
+			continue
+		}
+		items := cut[i].Items
+		end := w.Offset + w.Limit
+		if end > uint64(len(items)) {
+			end = uint64(len(items))
+		}
+		if w.Offset < end {
+			res.Items = append(res.Items, items[w.Offset:end]...)
+		}
+	}
+	return res, nil
+}
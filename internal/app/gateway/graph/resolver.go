@@ -0,0 +1,202 @@
+// Package graph serves the pharmacy and feedback services as one GraphQL
+// schema. Fields are resolved over the HTTP routes of the services; the loads
+// of one request are batched by the loaders WithLoaders attaches to it.
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"io"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/pkg/client"
+)
+
+const (
+	// maxDepth bounds the nesting of a query.
+	maxDepth = 8
+
+	// maxLimit mirrors the page size limit of the feedback service.
+	maxLimit = 100
+
+	// maxRangeDays bounds the days of a feedback range, each loaded with
+	// one call.
+	maxRangeDays = 31
+
+	// dateFormat is the day format of the feedback routes.
+	dateFormat = "2006_0102"
+)
+
+var (
+	// ErrMalformedArgument indicates an argument out of its range.
+	ErrMalformedArgument = errors.New("malformed argument")
+
+	//go:embed schema.graphql
+	schema string
+)
+
+// PharmacyService is the part of the pharmacy service the gateway calls.
+type PharmacyService interface {
+	Query(ctx context.Context, req client.QueryRequest) ([]client.Pharmacy, []client.Cluster, error)
+	Export(ctx context.Context, format string) (io.ReadCloser, error)
+}
+
+// FeedbackService is the part of the feedback service the gateway calls.
+type FeedbackService interface {
+	Options(ctx context.Context) ([]client.Option, error)
+	PharmacyFeedBacks(ctx context.Context, req client.PharmacyFeedBacksRequest) (client.FeedbackItemPage, error)
+	FeedBack(ctx context.Context, req client.FeedBackRequest) (string, error)
+}
+
+// Resolver resolves the Query and Mutation types.
+type Resolver struct {
+	pharmacies PharmacyService
+	feedback   FeedbackService
+	index      *pharmacyIndex
+}
+
+// NewResolver returns the root resolver over the given services. Pharmacies
+// looked up by id are read from the export, downloaded at most once per
+// indexTTL.
+func NewResolver(pharmacies PharmacyService, feedback FeedbackService, indexTTL time.Duration) *Resolver {
+	return &Resolver{pharmacies: pharmacies, feedback: feedback, index: &pharmacyIndex{ttl: indexTTL}}
+}
+
+// NewSchema parses the schema against r.
+func NewSchema(r *Resolver) *graphql.Schema {
+	return graphql.MustParseSchema(schema, r, graphql.UseStringDescriptions(), graphql.MaxDepth(maxDepth))
+}
+
+type latLngInput struct {
+	Lat float64
+	Lng float64
+}
+
+type boundsInput struct {
+	Ne latLngInput
+	Sw latLngInput
+}
+
+type pharmacyFilter struct {
+	MinMaskAdult *int32
+	MinMaskChild *int32
+	County       *string
+	Town         *string
+}
+
+func (f *pharmacyFilter) match(p client.Pharmacy) bool {
+	switch {
+	case f == nil:
+		return true
+	case f.MinMaskAdult != nil && int64(p.MaskAdult) < int64(*f.MinMaskAdult),
+		f.MinMaskChild != nil && int64(p.MaskChild) < int64(*f.MinMaskChild),
+		f.County != nil && p.County != *f.County,
+		f.Town != nil && p.Town != *f.Town:
+		return false
+	}
+	return true
+}
+
+// Pharmacies queries the pharmacy service and filters what it returns, so
+// filters narrow the max nearest stores rather than widen the search.
+func (r *Resolver) Pharmacies(ctx context.Context, args struct {
+	Bounds  boundsInput
+	Center  *latLngInput
+	Max     int32
+	Filters *pharmacyFilter
+}) ([]*pharmacyResolver, error) {
+	if args.Max < 1 {
+		return nil, errors.Wrap(ErrMalformedArgument, errors.New("max must be positive"))
+	}
+
+	ne, sw := args.Bounds.Ne, args.Bounds.Sw
+	center := latLngInput{Lat: (ne.Lat + sw.Lat) / 2, Lng: (ne.Lng + sw.Lng) / 2}
+	if args.Center != nil {
+		center = *args.Center
+	}
+
+	items, _, err := r.pharmacies.Query(ctx, client.QueryRequest{
+		Center: client.LatLng{Lat: center.Lat, Lng: center.Lng},
+		Bounds: client.Bounds{
+			Ne: client.LatLng{Lat: ne.Lat, Lng: ne.Lng},
+			Se: client.LatLng{Lat: sw.Lat, Lng: ne.Lng},
+			Sw: client.LatLng{Lat: sw.Lat, Lng: sw.Lng},
+			Nw: client.LatLng{Lat: ne.Lat, Lng: sw.Lng},
+		},
+		Max: uint64(args.Max),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := r.loaders(ctx)
+	res := make([]*pharmacyResolver, 0, len(items))
+	for _, p := range items {
+		if args.Filters.match(p) {
+			res = append(res, &pharmacyResolver{p: p, nearby: true, l: l})
+		}
+	}
+	return res, nil
+}
+
+// Pharmacy looks up a pharmacy of the latest snapshot.
+func (r *Resolver) Pharmacy(ctx context.Context, args struct{ ID graphql.ID }) (*pharmacyResolver, error) {
+	l := r.loaders(ctx)
+	v, err := l.pharmacies.Load(ctx, string(args.ID))
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return &pharmacyResolver{p: v.(client.Pharmacy), l: l}, nil
+}
+
+// Options lists the feedback options.
+func (r *Resolver) Options(ctx context.Context) ([]*optionResolver, error) {
+	v, err := r.loaders(ctx).options.Load(ctx, allOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	items := v.([]client.Option)
+	res := make([]*optionResolver, 0, len(items))
+	for _, o := range items {
+		res = append(res, &optionResolver{o: o})
+	}
+	return res, nil
+}
+
+type feedbackInput struct {
+	UserID      string
+	PharmacyID  graphql.ID
+	OptionID    graphql.ID
+	Description *string
+	Longitude   *float64
+	Latitude    *float64
+}
+
+// SubmitFeedback saves a feedback through the feedback service.
+func (r *Resolver) SubmitFeedback(ctx context.Context, args struct{ Input feedbackInput }) (graphql.ID, error) {
+	in := args.Input
+	req := client.FeedBackRequest{
+		UserID:     in.UserID,
+		PharmacyID: string(in.PharmacyID),
+		OptionID:   string(in.OptionID),
+	}
+	if in.Description != nil {
+		req.Description = *in.Description
+	}
+	if in.Longitude != nil {
+		req.Longitude = *in.Longitude
+	}
+	if in.Latitude != nil {
+		req.Latitude = *in.Latitude
+	}
+
+	id, err := r.feedback.FeedBack(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return graphql.ID(id), nil
+}
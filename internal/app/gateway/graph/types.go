@@ -0,0 +1,168 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/util"
+	"github.com/cage1016/mask/pkg/client"
+)
+
+type pharmacyResolver struct {
+	p client.Pharmacy
+	// nearby is set on the results of a pharmacies query, whose distances
+	// are relative to its center.
+	nearby bool
+	l      *loaders
+}
+
+func (r *pharmacyResolver) ID() graphql.ID         { return graphql.ID(r.p.Id) }
+func (r *pharmacyResolver) Name() string           { return r.p.Name }
+func (r *pharmacyResolver) Phone() string          { return r.p.Phone }
+func (r *pharmacyResolver) Address() string        { return r.p.Address }
+func (r *pharmacyResolver) MaskAdult() int32       { return int32(r.p.MaskAdult) }
+func (r *pharmacyResolver) MaskChild() int32       { return int32(r.p.MaskChild) }
+func (r *pharmacyResolver) Available() string      { return r.p.Available }
+func (r *pharmacyResolver) Note() string           { return r.p.Note }
+func (r *pharmacyResolver) CustomNote() string     { return r.p.CustomNote }
+func (r *pharmacyResolver) Website() string        { return r.p.Website }
+func (r *pharmacyResolver) ServicePeriods() string { return r.p.ServicePeriods }
+func (r *pharmacyResolver) ServiceNote() string    { return r.p.ServiceNote }
+func (r *pharmacyResolver) County() string         { return r.p.County }
+func (r *pharmacyResolver) Town() string           { return r.p.Town }
+func (r *pharmacyResolver) Cunli() string          { return r.p.Cunli }
+func (r *pharmacyResolver) Longitude() float64     { return r.p.Longitude }
+func (r *pharmacyResolver) Latitude() float64      { return r.p.Latitude }
+
+func (r *pharmacyResolver) Updated() *string {
+	if r.p.Updated == nil || !r.p.Updated.Valid {
+		return nil
+	}
+	s := r.p.Updated.Time.In(util.Location).Format(time.RFC3339)
+	return &s
+}
+
+func (r *pharmacyResolver) Distance() *float64 {
+	if !r.nearby {
+		return nil
+	}
+	return &r.p.Distance
+}
+
+// Feedback loads a page of the feedback about the pharmacy, of one day or of
+// a range of days. The pages of every pharmacy of a query are loaded in one
+// batch.
+func (r *pharmacyResolver) Feedback(ctx context.Context, args struct {
+	Date   *string
+	From   *string
+	To     *string
+	Offset int32
+	Limit  int32
+}) (*feedbackPageResolver, error) {
+	if args.Offset < 0 || args.Limit < 1 || args.Limit > maxLimit {
+		return nil, errors.Wrap(ErrMalformedArgument, errors.New("offset must not be negative and limit must be between 1 and 100"))
+	}
+
+	if args.From != nil || args.To != nil {
+		if args.Date != nil || args.From == nil || args.To == nil {
+			return nil, errors.Wrap(ErrMalformedArgument, errors.New("from and to go together and exclude date"))
+		}
+		days, err := feedbackDays(*args.From, *args.To)
+		if err != nil {
+			return nil, err
+		}
+		page, err := r.l.feedbackRange(ctx, r.p.Id, days, uint64(args.Offset), uint64(args.Limit))
+		if err != nil {
+			return nil, err
+		}
+		return &feedbackPageResolver{page: page, l: r.l}, nil
+	}
+
+	req := client.PharmacyFeedBacksRequest{
+		PharmacyID: r.p.Id,
+		Offset:     uint64(args.Offset),
+		Limit:      uint64(args.Limit),
+	}
+	if args.Date != nil {
+		req.Date = *args.Date
+	}
+
+	v, err := r.l.feedback.Load(ctx, feedbackKey(req))
+	if err != nil {
+		return nil, err
+	}
+	return &feedbackPageResolver{page: v.(client.FeedbackItemPage), l: r.l}, nil
+}
+
+// feedbackDays returns the days from from to to, both included, newest
+// first like the feedback of a day.
+func feedbackDays(from, to string) ([]string, error) {
+	f, err := time.ParseInLocation(dateFormat, from, util.Location)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedArgument, err)
+	}
+	t, err := time.ParseInLocation(dateFormat, to, util.Location)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedArgument, err)
+	}
+	if t.Before(f) || f.AddDate(0, 0, maxRangeDays).Before(t.AddDate(0, 0, 1)) {
+		return nil, errors.Wrap(ErrMalformedArgument, errors.New("to must not be before from and a range spans at most 31 days"))
+	}
+
+	var days []string
+	for d := t; !d.Before(f); d = d.AddDate(0, 0, -1) {
+		days = append(days, d.Format(dateFormat))
+	}
+	return days, nil
+}
+
+type feedbackPageResolver struct {
+	page client.FeedbackItemPage
+	l    *loaders
+}
+
+func (r *feedbackPageResolver) Total() int32  { return int32(r.page.Total) }
+func (r *feedbackPageResolver) Offset() int32 { return int32(r.page.Offset) }
+func (r *feedbackPageResolver) Limit() int32  { return int32(r.page.Limit) }
+
+func (r *feedbackPageResolver) Items() []*feedbackResolver {
+	res := make([]*feedbackResolver, 0, len(r.page.Items))
+	for _, f := range r.page.Items {
+		res = append(res, &feedbackResolver{f: f, l: r.l})
+	}
+	return res
+}
+
+type feedbackResolver struct {
+	f client.Feedback
+	l *loaders
+}
+
+func (r *feedbackResolver) ID() graphql.ID      { return graphql.ID(r.f.ID) }
+func (r *feedbackResolver) Description() string { return r.f.Description }
+func (r *feedbackResolver) Longitude() float64  { return r.f.Longitude }
+func (r *feedbackResolver) Latitude() float64   { return r.f.Latitude }
+
+func (r *feedbackResolver) CreatedAt() string {
+	return r.f.CreatedAt.In(util.Location).Format(time.RFC3339)
+}
+
+// Option loads the option of the feedback, or null for a retired one. The
+// options of a whole query are loaded once.
+func (r *feedbackResolver) Option(ctx context.Context) (*optionResolver, error) {
+	v, err := r.l.options.Load(ctx, r.f.OptionID)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return &optionResolver{o: v.(client.Option)}, nil
+}
+
+type optionResolver struct {
+	o client.Option
+}
+
+func (r *optionResolver) ID() graphql.ID { return graphql.ID(r.o.ID) }
+func (r *optionResolver) Name() string   { return r.o.Name }
@@ -0,0 +1,30 @@
+package transports
+
+import (
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-zoo/bone"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/cors"
+
+	"github.com/cage1016/mask/internal/app/gateway/graph"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+)
+
+// NewHTTPHandler returns a handler that serves the GraphQL schema of resolver
+// at /graphql.
+func NewHTTPHandler(resolver *graph.Resolver, logger log.Logger) http.Handler {
+	m := bone.New()
+	m.Post("/graphql", resolver.WithLoaders(&relay.Handler{Schema: graph.NewSchema(resolver)}))
+	m.Get("/metrics", promhttp.Handler())
+	m.GetFunc("/_ah/warmup", func(w http.ResponseWriter, r *http.Request) {
+		logger.Log("/_ah/warmup", "done")
+	})
+	m.GetFunc("/graphql/health_check", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	return cors.AllowAll().Handler(logging.RequestIDHandler(tracing.HTTPHandler(m, "/metrics")))
+}
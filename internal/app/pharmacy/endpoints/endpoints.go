@@ -2,39 +2,141 @@ package endpoints
 
 import (
 	"context"
+
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 
 	"github.com/cage1016/mask/internal/app/pharmacy/model"
 	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/auth"
+	"github.com/cage1016/mask/internal/pkg/export"
+	"github.com/cage1016/mask/internal/pkg/tracing"
 )
 
 // Endpoints collects all of the endpoints that compose the pharmacy service. It's
 // meant to be used as a helper struct, to collect all of the endpoints into a
 // single parameter.
 type Endpoints struct {
-	QueryEndpoint        endpoint.Endpoint `json:""`
-	TickerUpdateEndpoint endpoint.Endpoint `json:""`
+	QueryEndpoint             endpoint.Endpoint `json:""`
+	ExportEndpoint            endpoint.Endpoint `json:""`
+	TickerUpdateEndpoint      endpoint.Endpoint `json:""`
+	CreateWebhookEndpoint     endpoint.Endpoint `json:""`
+	ListWebhooksEndpoint      endpoint.Endpoint `json:""`
+	RemoveWebhookEndpoint     endpoint.Endpoint `json:""`
+	WebhookDeliveriesEndpoint endpoint.Endpoint `json:""`
+	FollowEndpoint            endpoint.Endpoint `json:""`
+	UnfollowEndpoint          endpoint.Endpoint `json:""`
+	WatchesEndpoint           endpoint.Endpoint `json:""`
+	StreamEndpoint            endpoint.Endpoint `json:""`
 }
 
 // New return a new instance of the endpoint that wraps the provided service.
-func New(svc service.PharmacyService, logger log.Logger) (ep Endpoints) {
+// The webhook endpoints, which register the URLs the service posts to and
+// hand out their secrets, require adminToken.
+func New(svc service.PharmacyService, adminToken string, logger log.Logger) (ep Endpoints) {
 	var queryEndpoint endpoint.Endpoint
 	{
 		method := "query"
 		queryEndpoint = MakeQueryEndpoint(svc)
 		queryEndpoint = LoggingMiddleware(log.With(logger, "method", method))(queryEndpoint)
+		queryEndpoint = tracing.EndpointMiddleware(method)(queryEndpoint)
 		ep.QueryEndpoint = queryEndpoint
 	}
 
+	var exportEndpoint endpoint.Endpoint
+	{
+		method := "export"
+		exportEndpoint = MakeExportEndpoint(svc)
+		exportEndpoint = LoggingMiddleware(log.With(logger, "method", method))(exportEndpoint)
+		exportEndpoint = tracing.EndpointMiddleware(method)(exportEndpoint)
+		ep.ExportEndpoint = exportEndpoint
+	}
+
 	var tickerUpdateEndpoint endpoint.Endpoint
 	{
 		method := "tickerUpdate"
 		tickerUpdateEndpoint = MakeTickerUpdateEndpoint(svc)
 		tickerUpdateEndpoint = LoggingMiddleware(log.With(logger, "method", method))(tickerUpdateEndpoint)
+		tickerUpdateEndpoint = tracing.EndpointMiddleware(method)(tickerUpdateEndpoint)
 		ep.TickerUpdateEndpoint = tickerUpdateEndpoint
 	}
 
+	var createWebhookEndpoint endpoint.Endpoint
+	{
+		method := "createWebhook"
+		createWebhookEndpoint = MakeCreateWebhookEndpoint(svc)
+		createWebhookEndpoint = auth.Middleware(adminToken)(createWebhookEndpoint)
+		createWebhookEndpoint = LoggingMiddleware(log.With(logger, "method", method))(createWebhookEndpoint)
+		createWebhookEndpoint = tracing.EndpointMiddleware(method)(createWebhookEndpoint)
+		ep.CreateWebhookEndpoint = createWebhookEndpoint
+	}
+
+	var listWebhooksEndpoint endpoint.Endpoint
+	{
+		method := "listWebhooks"
+		listWebhooksEndpoint = MakeListWebhooksEndpoint(svc)
+		listWebhooksEndpoint = auth.Middleware(adminToken)(listWebhooksEndpoint)
+		listWebhooksEndpoint = LoggingMiddleware(log.With(logger, "method", method))(listWebhooksEndpoint)
+		listWebhooksEndpoint = tracing.EndpointMiddleware(method)(listWebhooksEndpoint)
+		ep.ListWebhooksEndpoint = listWebhooksEndpoint
+	}
+
+	var removeWebhookEndpoint endpoint.Endpoint
+	{
+		method := "removeWebhook"
+		removeWebhookEndpoint = MakeRemoveWebhookEndpoint(svc)
+		removeWebhookEndpoint = auth.Middleware(adminToken)(removeWebhookEndpoint)
+		removeWebhookEndpoint = LoggingMiddleware(log.With(logger, "method", method))(removeWebhookEndpoint)
+		removeWebhookEndpoint = tracing.EndpointMiddleware(method)(removeWebhookEndpoint)
+		ep.RemoveWebhookEndpoint = removeWebhookEndpoint
+	}
+
+	var webhookDeliveriesEndpoint endpoint.Endpoint
+	{
+		method := "webhookDeliveries"
+		webhookDeliveriesEndpoint = MakeWebhookDeliveriesEndpoint(svc)
+		webhookDeliveriesEndpoint = auth.Middleware(adminToken)(webhookDeliveriesEndpoint)
+		webhookDeliveriesEndpoint = LoggingMiddleware(log.With(logger, "method", method))(webhookDeliveriesEndpoint)
+		webhookDeliveriesEndpoint = tracing.EndpointMiddleware(method)(webhookDeliveriesEndpoint)
+		ep.WebhookDeliveriesEndpoint = webhookDeliveriesEndpoint
+	}
+
+	var followEndpoint endpoint.Endpoint
+	{
+		method := "follow"
+		followEndpoint = MakeFollowEndpoint(svc)
+		followEndpoint = LoggingMiddleware(log.With(logger, "method", method))(followEndpoint)
+		followEndpoint = tracing.EndpointMiddleware(method)(followEndpoint)
+		ep.FollowEndpoint = followEndpoint
+	}
+
+	var unfollowEndpoint endpoint.Endpoint
+	{
+		method := "unfollow"
+		unfollowEndpoint = MakeUnfollowEndpoint(svc)
+		unfollowEndpoint = LoggingMiddleware(log.With(logger, "method", method))(unfollowEndpoint)
+		unfollowEndpoint = tracing.EndpointMiddleware(method)(unfollowEndpoint)
+		ep.UnfollowEndpoint = unfollowEndpoint
+	}
+
+	var watchesEndpoint endpoint.Endpoint
+	{
+		method := "watches"
+		watchesEndpoint = MakeWatchesEndpoint(svc)
+		watchesEndpoint = LoggingMiddleware(log.With(logger, "method", method))(watchesEndpoint)
+		watchesEndpoint = tracing.EndpointMiddleware(method)(watchesEndpoint)
+		ep.WatchesEndpoint = watchesEndpoint
+	}
+
+	var streamEndpoint endpoint.Endpoint
+	{
+		method := "stream"
+		streamEndpoint = MakeStreamEndpoint(svc)
+		streamEndpoint = LoggingMiddleware(log.With(logger, "method", method))(streamEndpoint)
+		streamEndpoint = tracing.EndpointMiddleware(method)(streamEndpoint)
+		ep.StreamEndpoint = streamEndpoint
+	}
+
 	return ep
 }
 
@@ -46,6 +148,10 @@ func MakeQueryEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
 		if err := req.validate(); err != nil {
 			return QueryResponse{}, err
 		}
+		if req.clustered() {
+			clusters, err := svc.QueryClusters(ctx, req.Bounds.Ne.Lng, req.Bounds.Ne.Lat, req.Bounds.Sw.Lng, req.Bounds.Sw.Lat, req.Zoom)
+			return QueryResponse{Items: []model.Pharmacy{}, Clusters: clusters}, err
+		}
 		pharmacies, err := svc.Query(ctx, req.Center.Lng, req.Center.Lat, req.Bounds.Ne.Lng, req.Bounds.Ne.Lat, req.Bounds.Se.Lng, req.Bounds.Se.Lat, req.Bounds.Sw.Lng, req.Bounds.Sw.Lat, req.Bounds.Nw.Lng, req.Bounds.Nw.Lat, req.Max)
 		return QueryResponse{Items: pharmacies}, err
 	}
@@ -70,6 +176,46 @@ func (e Endpoints) Query(ctx context.Context, centerLng float64, centerLat float
 	return response.Items, nil
 }
 
+// QueryClusters implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) QueryClusters(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64, zoom uint64) (items []model.Cluster, err error) {
+	resp, err := e.QueryEndpoint(ctx, QueryRequest{Bounds: Bounds{
+		Ne: LatLng{neLat, neLng},
+		Se: LatLng{swLat, neLng},
+		Sw: LatLng{swLat, swLng},
+		Nw: LatLng{neLat, swLng},
+	}, Zoom: zoom})
+	if err != nil {
+		return
+	}
+	response := resp.(QueryResponse)
+	return response.Clusters, nil
+}
+
+// MakeExportEndpoint returns an endpoint that invokes Export on the service.
+// Primarily useful in a server.
+func MakeExportEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ExportRequest)
+		if err := req.validate(); err != nil {
+			return ExportResponse{}, err
+		}
+		cursor, err := svc.Export(ctx)
+		return ExportResponse{Format: req.Format, Cursor: cursor}, err
+	}
+}
+
+// Export implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Export(ctx context.Context) (cursor model.PharmacyCursor, err error) {
+	resp, err := e.ExportEndpoint(ctx, ExportRequest{Format: export.FormatNDJSON})
+	if err != nil {
+		return
+	}
+	response := resp.(ExportResponse)
+	return response.Cursor, nil
+}
+
 // MakeTickerUpdateEndpoint returns an endpoint that invokes TickerUpdate on the service.
 // Primarily useful in a server.
 func MakeTickerUpdateEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
@@ -89,3 +235,179 @@ func (e Endpoints) TickerUpdate(ctx context.Context) (err error) {
 	_ = resp.(TickerUpdateResponse)
 	return nil
 }
+
+// MakeCreateWebhookEndpoint returns an endpoint that invokes CreateWebhook on the service.
+// Primarily useful in a server.
+func MakeCreateWebhookEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateWebhookRequest)
+		if err := req.validate(); err != nil {
+			return CreateWebhookResponse{}, err
+		}
+		webhook, err := svc.CreateWebhook(ctx, req.URL, req.Secret, req.Events)
+		return CreateWebhookResponse{Webhook: webhook}, err
+	}
+}
+
+// CreateWebhook implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) CreateWebhook(ctx context.Context, url string, secret string, events []string) (webhook model.Webhook, err error) {
+	resp, err := e.CreateWebhookEndpoint(ctx, CreateWebhookRequest{URL: url, Secret: secret, Events: events})
+	if err != nil {
+		return
+	}
+	response := resp.(CreateWebhookResponse)
+	return response.Webhook, nil
+}
+
+// MakeListWebhooksEndpoint returns an endpoint that invokes ListWebhooks on the service.
+// Primarily useful in a server.
+func MakeListWebhooksEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		items, err := svc.ListWebhooks(ctx)
+		return ListWebhooksResponse{Items: items}, err
+	}
+}
+
+// ListWebhooks implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) ListWebhooks(ctx context.Context) (items []model.Webhook, err error) {
+	resp, err := e.ListWebhooksEndpoint(ctx, ListWebhooksRequest{})
+	if err != nil {
+		return
+	}
+	response := resp.(ListWebhooksResponse)
+	return response.Items, nil
+}
+
+// MakeRemoveWebhookEndpoint returns an endpoint that invokes RemoveWebhook on the service.
+// Primarily useful in a server.
+func MakeRemoveWebhookEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RemoveWebhookRequest)
+		if err := req.validate(); err != nil {
+			return RemoveWebhookResponse{}, err
+		}
+		err := svc.RemoveWebhook(ctx, req.ID)
+		return RemoveWebhookResponse{}, err
+	}
+}
+
+// RemoveWebhook implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) RemoveWebhook(ctx context.Context, id string) (err error) {
+	_, err = e.RemoveWebhookEndpoint(ctx, RemoveWebhookRequest{ID: id})
+	return err
+}
+
+// MakeWebhookDeliveriesEndpoint returns an endpoint that invokes WebhookDeliveries on the service.
+// Primarily useful in a server.
+func MakeWebhookDeliveriesEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(WebhookDeliveriesRequest)
+		if err := req.validate(); err != nil {
+			return WebhookDeliveriesResponse{}, err
+		}
+		res, err := svc.WebhookDeliveries(ctx, req.ID, req.Offset, req.Limit)
+		return WebhookDeliveriesResponse{Res: res}, err
+	}
+}
+
+// WebhookDeliveries implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) WebhookDeliveries(ctx context.Context, id string, offset uint64, limit uint64) (res model.DeliveryPage, err error) {
+	resp, err := e.WebhookDeliveriesEndpoint(ctx, WebhookDeliveriesRequest{ID: id, Offset: offset, Limit: limit})
+	if err != nil {
+		return
+	}
+	response := resp.(WebhookDeliveriesResponse)
+	return response.Res, nil
+}
+
+// MakeFollowEndpoint returns an endpoint that invokes Follow on the service.
+// Primarily useful in a server.
+func MakeFollowEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(FollowRequest)
+		if err := req.validate(); err != nil {
+			return FollowResponse{}, err
+		}
+		err := svc.Follow(ctx, req.UserID, req.PharmacyID, req.MaskAdult, req.MaskChild)
+		return FollowResponse{}, err
+	}
+}
+
+// Follow implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Follow(ctx context.Context, userID string, pharmacyID string, maskAdult uint64, maskChild uint64) (err error) {
+	_, err = e.FollowEndpoint(ctx, FollowRequest{UserID: userID, PharmacyID: pharmacyID, MaskAdult: maskAdult, MaskChild: maskChild})
+	return err
+}
+
+// MakeUnfollowEndpoint returns an endpoint that invokes Unfollow on the service.
+// Primarily useful in a server.
+func MakeUnfollowEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UnfollowRequest)
+		if err := req.validate(); err != nil {
+			return UnfollowResponse{}, err
+		}
+		err := svc.Unfollow(ctx, req.UserID, req.PharmacyID)
+		return UnfollowResponse{}, err
+	}
+}
+
+// Unfollow implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Unfollow(ctx context.Context, userID string, pharmacyID string) (err error) {
+	_, err = e.UnfollowEndpoint(ctx, UnfollowRequest{UserID: userID, PharmacyID: pharmacyID})
+	return err
+}
+
+// MakeWatchesEndpoint returns an endpoint that invokes Watches on the service.
+// Primarily useful in a server.
+func MakeWatchesEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(WatchesRequest)
+		if err := req.validate(); err != nil {
+			return WatchesResponse{}, err
+		}
+		items, err := svc.Watches(ctx, req.UserID)
+		return WatchesResponse{Items: items}, err
+	}
+}
+
+// Watches implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Watches(ctx context.Context, userID string) (items []model.Watch, err error) {
+	resp, err := e.WatchesEndpoint(ctx, WatchesRequest{UserID: userID})
+	if err != nil {
+		return
+	}
+	response := resp.(WatchesResponse)
+	return response.Items, nil
+}
+
+// MakeStreamEndpoint returns an endpoint that invokes Stream on the service.
+// Primarily useful in a server.
+func MakeStreamEndpoint(svc service.PharmacyService) (ep endpoint.Endpoint) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(StreamRequest)
+		if err := req.validate(); err != nil {
+			return StreamResponse{}, err
+		}
+		events, err := svc.Stream(ctx, req.Ne.Lng, req.Ne.Lat, req.Sw.Lng, req.Sw.Lat)
+		return StreamResponse{Events: events}, err
+	}
+}
+
+// Stream implements the service interface, so Endpoints may be used as a service.
+// This is primarily useful in the context of a client library.
+func (e Endpoints) Stream(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64) (events <-chan model.StreamEvent, err error) {
+	resp, err := e.StreamEndpoint(ctx, StreamRequest{Ne: LatLng{neLat, neLng}, Sw: LatLng{swLat, swLng}})
+	if err != nil {
+		return
+	}
+	response := resp.(StreamResponse)
+	return response.Events, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/go-kit/kit/log"
 
 	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
 )
 
 // LoggingMiddleware returns an endpoint middleware that logs the
@@ -16,6 +17,7 @@ func LoggingMiddleware(logger log.Logger) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 			defer func(begin time.Time) {
+				logger := logging.WithRequestID(ctx, logger)
 				if err == nil {
 					level.Info(logger).Log("transport_error", err, "took", time.Since(begin))
 				} else {
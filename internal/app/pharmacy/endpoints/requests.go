@@ -1,5 +1,20 @@
 package endpoints
 
+import (
+	"net/url"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/export"
+)
+
+const (
+	maxZoom = 22
+
+	maxLimitSize = 100
+)
+
 type Request interface {
 	validate() error
 }
@@ -21,10 +36,22 @@ type QueryRequest struct {
 	Center LatLng `json:"center"`
 	Bounds Bounds `json:"bounds"`
 	Max    uint64 `json:"max"`
+	// Zoom is the map zoom level of the client, 0 - 22. Levels 1 up to
+	// service.ClusterMaxZoom are answered with clusters instead of stores.
+	// Zero, the default, turns clustering off and always returns stores.
+	Zoom uint64 `json:"zoom"`
 }
 
 func (r QueryRequest) validate() error {
-	return nil // TBA
+	if r.Zoom > maxZoom {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("zoom must be between 0 - 22"))
+	}
+
+	return nil
+}
+
+func (r QueryRequest) clustered() bool {
+	return r.Zoom > 0 && r.Zoom <= service.ClusterMaxZoom
 }
 
 // TickerUpdateRequest collects the request parameters for the TickerUpdate method.
@@ -34,3 +61,144 @@ type TickerUpdateRequest struct {
 func (r TickerUpdateRequest) validate() error {
 	return nil // TBA
 }
+
+// ExportRequest collects the request parameters for the Export method.
+type ExportRequest struct {
+	Format string `json:"format"`
+}
+
+func (r ExportRequest) validate() error {
+	if r.Format != export.FormatCSV && r.Format != export.FormatNDJSON {
+		return errors.Wrap(service.ErrMalformedEntity, export.ErrUnsupportedFormat)
+	}
+
+	return nil
+}
+
+// CreateWebhookRequest collects the request parameters for the CreateWebhook method.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+func (r CreateWebhookRequest) validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return errors.Wrap(service.ErrMalformedEntity, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("url must be an absolute http(s) url"))
+	}
+
+	for _, e := range r.Events {
+		switch e {
+		case model.EventRestocked, model.EventSoldOut, model.EventListed, model.EventRemoved:
+		default:
+			return errors.Wrap(service.ErrMalformedEntity, errors.New("unknown event "+e))
+		}
+	}
+
+	return nil
+}
+
+// ListWebhooksRequest collects the request parameters for the ListWebhooks method.
+type ListWebhooksRequest struct {
+}
+
+func (r ListWebhooksRequest) validate() error {
+	return nil
+}
+
+// RemoveWebhookRequest collects the request parameters for the RemoveWebhook method.
+type RemoveWebhookRequest struct {
+	ID string `json:"id"`
+}
+
+func (r RemoveWebhookRequest) validate() error {
+	if r.ID == "" {
+		return service.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// WebhookDeliveriesRequest collects the request parameters for the WebhookDeliveries method.
+type WebhookDeliveriesRequest struct {
+	ID     string `json:"id"`
+	Offset uint64 `json:"offset"`
+	Limit  uint64 `json:"limit"`
+}
+
+func (r WebhookDeliveriesRequest) validate() error {
+	if r.ID == "" {
+		return service.ErrMalformedEntity
+	}
+
+	if r.Limit <= 0 || r.Limit > maxLimitSize {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("limit must between 1 - 100"))
+	}
+
+	return nil
+}
+
+// FollowRequest collects the request parameters for the Follow method.
+type FollowRequest struct {
+	UserID     string `json:"userId"`
+	PharmacyID string `json:"pharmacyId"`
+	MaskAdult  uint64 `json:"maskAdult"`
+	MaskChild  uint64 `json:"maskChild"`
+}
+
+func (r FollowRequest) validate() error {
+	if r.UserID == "" || r.PharmacyID == "" {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("userId or pharmacyId is empty"))
+	}
+
+	if r.MaskAdult == 0 && r.MaskChild == 0 {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("maskAdult or maskChild threshold must be greater than 0"))
+	}
+
+	return nil
+}
+
+// UnfollowRequest collects the request parameters for the Unfollow method.
+type UnfollowRequest struct {
+	UserID     string `json:"userId"`
+	PharmacyID string `json:"pharmacyId"`
+}
+
+func (r UnfollowRequest) validate() error {
+	if r.UserID == "" || r.PharmacyID == "" {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("userId or pharmacyId is empty"))
+	}
+
+	return nil
+}
+
+// WatchesRequest collects the request parameters for the Watches method.
+type WatchesRequest struct {
+	UserID string `json:"userId"`
+}
+
+func (r WatchesRequest) validate() error {
+	if r.UserID == "" {
+		return service.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// StreamRequest collects the request parameters for the Stream method.
+type StreamRequest struct {
+	Ne LatLng `json:"ne"`
+	Sw LatLng `json:"sw"`
+}
+
+func (r StreamRequest) validate() error {
+	if r.Sw.Lng > r.Ne.Lng || r.Sw.Lat > r.Ne.Lat {
+		return errors.Wrap(service.ErrMalformedEntity, errors.New("sw must be south west of ne"))
+	}
+
+	return nil
+}
@@ -1,12 +1,14 @@
 package endpoints
 
 import (
+	"fmt"
 	"net/http"
 
 	httptransport "github.com/go-kit/kit/transport/http"
 
 	"github.com/cage1016/mask/internal/app/pharmacy/model"
 	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/export"
 	"github.com/cage1016/mask/internal/pkg/responses"
 )
 
@@ -15,6 +17,50 @@ var (
 
 	_ httptransport.StatusCoder = (*QueryResponse)(nil)
 
+	_ responses.GeoJSONResponser = (*QueryResponse)(nil)
+
+	_ responses.Enveloper = (*QueryResponse)(nil)
+
+	_ httptransport.Headerer = (*ExportResponse)(nil)
+
+	_ httptransport.Headerer = (*CreateWebhookResponse)(nil)
+
+	_ httptransport.StatusCoder = (*CreateWebhookResponse)(nil)
+
+	_ responses.Enveloper = (*CreateWebhookResponse)(nil)
+
+	_ httptransport.Headerer = (*ListWebhooksResponse)(nil)
+
+	_ httptransport.StatusCoder = (*ListWebhooksResponse)(nil)
+
+	_ responses.Enveloper = (*ListWebhooksResponse)(nil)
+
+	_ httptransport.Headerer = (*RemoveWebhookResponse)(nil)
+
+	_ httptransport.StatusCoder = (*RemoveWebhookResponse)(nil)
+
+	_ httptransport.Headerer = (*WebhookDeliveriesResponse)(nil)
+
+	_ httptransport.StatusCoder = (*WebhookDeliveriesResponse)(nil)
+
+	_ responses.Enveloper = (*WebhookDeliveriesResponse)(nil)
+
+	_ httptransport.Headerer = (*FollowResponse)(nil)
+
+	_ httptransport.StatusCoder = (*FollowResponse)(nil)
+
+	_ httptransport.Headerer = (*UnfollowResponse)(nil)
+
+	_ httptransport.StatusCoder = (*UnfollowResponse)(nil)
+
+	_ httptransport.Headerer = (*WatchesResponse)(nil)
+
+	_ httptransport.StatusCoder = (*WatchesResponse)(nil)
+
+	_ responses.Enveloper = (*WatchesResponse)(nil)
+
+	_ httptransport.Headerer = (*StreamResponse)(nil)
+
 	_ httptransport.Headerer = (*TickerUpdateResponse)(nil)
 
 	_ httptransport.StatusCoder = (*TickerUpdateResponse)(nil)
@@ -22,8 +68,9 @@ var (
 
 // QueryResponse collects the response values for the Query method.
 type QueryResponse struct {
-	Items []model.Pharmacy `json:"items"`
-	Err   error            `json:"-"`
+	Items    []model.Pharmacy `json:"items"`
+	Clusters []model.Cluster  `json:"clusters,omitempty"`
+	Err      error            `json:"-"`
 }
 
 func (r QueryResponse) StatusCode() int {
@@ -38,8 +85,29 @@ func (r QueryResponse) Response() interface{} {
 	return responses.DataRes{APIVersion: service.Version, Data: r}
 }
 
-func (r QueryResponse) ResponseOld() interface{} {
-	return r.Items
+func (r QueryResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: service.Version, Data: r}
+}
+
+func (r QueryResponse) GeoJSON() interface{} {
+	if r.Clusters != nil {
+		return model.FeatureCollectionFromClusters(r.Clusters)
+	}
+	return model.Pharmacies(r.Items).FeatureCollection()
+}
+
+// ExportResponse collects the response values for the Export method.
+type ExportResponse struct {
+	Format string               `json:"-"`
+	Cursor model.PharmacyCursor `json:"-"`
+	Err    error                `json:"-"`
+}
+
+func (r ExportResponse) Headers() http.Header {
+	return http.Header{
+		"Content-Type":        []string{export.ContentType(r.Format)},
+		"Content-Disposition": []string{fmt.Sprintf(`attachment; filename="pharmacies.%s"`, r.Format)},
+	}
 }
 
 // TickerUpdateResponse collects the response values for the TickerUpdate method.
@@ -58,3 +126,148 @@ func (r TickerUpdateResponse) Headers() http.Header {
 func (r TickerUpdateResponse) Response() interface{} {
 	return responses.DataRes{APIVersion: service.Version}
 }
+
+// CreateWebhookResponse collects the response values for the CreateWebhook method.
+type CreateWebhookResponse struct {
+	Webhook model.Webhook `json:"webhook"`
+	Err     error         `json:"-"`
+}
+
+func (r CreateWebhookResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r CreateWebhookResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+func (r CreateWebhookResponse) Response() interface{} {
+	return responses.DataRes{APIVersion: service.Version, Data: r.Webhook}
+}
+
+func (r CreateWebhookResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: service.Version, Data: r.Webhook}
+}
+
+// ListWebhooksResponse collects the response values for the ListWebhooks method.
+type ListWebhooksResponse struct {
+	Items []model.Webhook `json:"items"`
+	Err   error           `json:"-"`
+}
+
+func (r ListWebhooksResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r ListWebhooksResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+func (r ListWebhooksResponse) Response() interface{} {
+	return responses.DataRes{APIVersion: service.Version, Data: r}
+}
+
+func (r ListWebhooksResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: service.Version, Data: r.Items}
+}
+
+// RemoveWebhookResponse collects the response values for the RemoveWebhook method.
+type RemoveWebhookResponse struct {
+	Err error `json:"-"`
+}
+
+func (r RemoveWebhookResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+func (r RemoveWebhookResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// WebhookDeliveriesResponse collects the response values for the WebhookDeliveries method.
+type WebhookDeliveriesResponse struct {
+	Res model.DeliveryPage `json:"res"`
+	Err error              `json:"-"`
+}
+
+func (r WebhookDeliveriesResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r WebhookDeliveriesResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+func (r WebhookDeliveriesResponse) Response() interface{} {
+	return responses.DataRes{APIVersion: service.Version, Data: r.Res}
+}
+
+func (r WebhookDeliveriesResponse) Envelope() responses.Envelope {
+	return responses.Envelope{
+		APIVersion: service.Version,
+		Data:       r.Res.Items,
+		Paging:     responses.NewPaging(len(r.Res.Items), r.Res.Offset, r.Res.Limit, r.Res.Total),
+	}
+}
+
+// FollowResponse collects the response values for the Follow method.
+type FollowResponse struct {
+	Err error `json:"-"`
+}
+
+func (r FollowResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+func (r FollowResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// UnfollowResponse collects the response values for the Unfollow method.
+type UnfollowResponse struct {
+	Err error `json:"-"`
+}
+
+func (r UnfollowResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+func (r UnfollowResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+// WatchesResponse collects the response values for the Watches method.
+type WatchesResponse struct {
+	Items []model.Watch `json:"items"`
+	Err   error         `json:"-"`
+}
+
+func (r WatchesResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r WatchesResponse) Headers() http.Header {
+	return http.Header{}
+}
+
+func (r WatchesResponse) Response() interface{} {
+	return responses.DataRes{APIVersion: service.Version, Data: r}
+}
+
+func (r WatchesResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: service.Version, Data: r.Items}
+}
+
+// StreamResponse collects the response values for the Stream method.
+type StreamResponse struct {
+	Events <-chan model.StreamEvent `json:"-"`
+	Err    error                    `json:"-"`
+}
+
+func (r StreamResponse) Headers() http.Header {
+	return http.Header{
+		"Content-Type":      []string{"text/event-stream"},
+		"Cache-Control":     []string{"no-cache"},
+		"X-Accel-Buffering": []string{"no"},
+	}
+}
@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// earthRadiusMiles is the radius the earthdistance <@> operator computes
+// statute miles with.
+const earthRadiusMiles = 3958.747716
+
+// snapshotTable matches the table names latest_pharmacy_table picks from.
+var snapshotTable = regexp.MustCompile(`^pharmacy_[0-9]{4}_[0-9]{4}$`)
+
+var (
+	// ErrSnapshotNotFound indicates a snapshot table that was never added.
+	ErrSnapshotNotFound = errors.New("pharmacy snapshot not found")
+	// ErrNoSnapshot indicates no snapshot table was added yet.
+	ErrNoSnapshot = errors.New("no pharmacy snapshot")
+)
+
+var _ model.PharmacyRepository = (*PharmacyRepository)(nil)
+
+// PharmacyRepository is an in-memory implementation of the pharmacy
+// repository. Snapshots stand in for the snapshot tables loaded into
+// PostgreSQL outside of the services.
+type PharmacyRepository struct {
+	mu        sync.RWMutex
+	snapshots map[string][]model.Pharmacy
+}
+
+// New instantiates an in-memory pharmacy repository without snapshots.
+func New() *PharmacyRepository {
+	return &PharmacyRepository{snapshots: make(map[string][]model.Pharmacy)}
+}
+
+// AddSnapshot stores the pharmacies as the snapshot table named table,
+// replacing any snapshot of the same name.
+func (r *PharmacyRepository) AddSnapshot(table string, pharmacies []model.Pharmacy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snapshots[table] = append([]model.Pharmacy(nil), pharmacies...)
+}
+
+func (r *PharmacyRepository) snapshot(table string) ([]model.Pharmacy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pharmacies, ok := r.snapshots[table]
+	if !ok {
+		return nil, ErrSnapshotNotFound
+	}
+	return pharmacies, nil
+}
+
+func (r *PharmacyRepository) Query(_ context.Context, latestPharmacyTable string, centerLng, centerLat, swLng, neLng, swLat, neLat float64, max uint64) ([]model.Pharmacy, error) {
+	pharmacies, err := r.snapshot(latestPharmacyTable)
+	if err != nil {
+		return []model.Pharmacy{}, err
+	}
+
+	items := []model.Pharmacy{}
+	for _, p := range pharmacies {
+		if p.Longitude >= swLng && p.Longitude <= neLng && p.Latitude >= swLat && p.Latitude <= neLat {
+			p.Distance = distance(centerLng, centerLat, p.Longitude, p.Latitude)
+			items = append(items, p)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Distance < items[j].Distance })
+
+	if uint64(len(items)) > max {
+		items = items[:max]
+	}
+	return items, nil
+}
+
+func (r *PharmacyRepository) Nearby(_ context.Context, latestPharmacyTable string, centerLng, centerLat, radius float64, max uint64) ([]model.Pharmacy, error) {
+	pharmacies, err := r.snapshot(latestPharmacyTable)
+	if err != nil {
+		return []model.Pharmacy{}, err
+	}
+
+	items := []model.Pharmacy{}
+	for _, p := range pharmacies {
+		if p.Distance = distance(centerLng, centerLat, p.Longitude, p.Latitude); p.Distance <= radius {
+			items = append(items, p)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Distance < items[j].Distance })
+
+	if uint64(len(items)) > max {
+		items = items[:max]
+	}
+	return items, nil
+}
+
+func (r *PharmacyRepository) Cluster(_ context.Context, latestPharmacyTable string, swLng, neLng, swLat, neLat, gridSize float64) ([]model.Cluster, error) {
+	pharmacies, err := r.snapshot(latestPharmacyTable)
+	if err != nil {
+		return []model.Cluster{}, err
+	}
+
+	type cell struct{ lng, lat float64 }
+	cells := map[cell]*model.Cluster{}
+	var order []cell
+	for _, p := range pharmacies {
+		if p.Longitude < swLng || p.Longitude > neLng || p.Latitude < swLat || p.Latitude > neLat {
+			continue
+		}
+
+		k := cell{math.Floor(p.Longitude / gridSize), math.Floor(p.Latitude / gridSize)}
+		c, ok := cells[k]
+		if !ok {
+			c = &model.Cluster{}
+			cells[k] = c
+			order = append(order, k)
+		}
+		// sum the coordinates, averaged below
+		c.Longitude += p.Longitude
+		c.Latitude += p.Latitude
+		c.Count++
+		c.MaskAdult += p.MaskAdult
+		c.MaskChild += p.MaskChild
+	}
+
+	clusters := make([]model.Cluster, 0, len(order))
+	for _, k := range order {
+		c := *cells[k]
+		c.Longitude /= float64(c.Count)
+		c.Latitude /= float64(c.Count)
+		clusters = append(clusters, c)
+	}
+	return clusters, nil
+}
+
+func (r *PharmacyRepository) Export(_ context.Context, latestPharmacyTable string) (model.PharmacyCursor, error) {
+	pharmacies, err := r.snapshot(latestPharmacyTable)
+	if err != nil {
+		return nil, err
+	}
+
+	items := append([]model.Pharmacy(nil), pharmacies...)
+	sort.Slice(items, func(i, j int) bool { return items[i].Id < items[j].Id })
+	return &pharmacyCursor{items: items, i: -1}, nil
+}
+
+func (r *PharmacyRepository) Snapshot(_ context.Context, table string) ([]model.Pharmacy, error) {
+	pharmacies, err := r.snapshot(table)
+	if err != nil {
+		return []model.Pharmacy{}, err
+	}
+	return append([]model.Pharmacy{}, pharmacies...), nil
+}
+
+// GetLatestPharmacyTableName returns the greatest snapshot table name, like
+// the latest_pharmacy_table view.
+func (r *PharmacyRepository) GetLatestPharmacyTableName(context.Context) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest string
+	for table := range r.snapshots {
+		if snapshotTable.MatchString(table) && table > latest {
+			latest = table
+		}
+	}
+	if latest == "" {
+		return "", ErrNoSnapshot
+	}
+	return latest, nil
+}
+
+// distance is the great circle distance in statute miles between two points,
+// as computed by earthdistance.
+func distance(lng1, lat1, lng2, lat2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+type pharmacyCursor struct {
+	items []model.Pharmacy
+	i     int
+}
+
+func (c *pharmacyCursor) Next() bool {
+	if c.i+1 >= len(c.items) {
+		return false
+	}
+	c.i++
+	return true
+}
+
+func (c *pharmacyCursor) Pharmacy() (model.Pharmacy, error) {
+	return c.items[c.i], nil
+}
+
+func (c *pharmacyCursor) Err() error {
+	return nil
+}
+
+func (c *pharmacyCursor) Close() error {
+	return nil
+}
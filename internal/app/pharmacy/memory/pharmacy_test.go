@@ -0,0 +1,16 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/memory"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/repotest"
+)
+
+func TestPharmacyRepository(t *testing.T) {
+	repo := memory.New()
+	repotest.Run(t, repo, func(_ *testing.T, table string, pharmacies []model.Pharmacy) {
+		repo.AddSnapshot(table, pharmacies)
+	})
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+var _ model.WatchRepository = (*watchRepository)(nil)
+
+type watchKey struct{ userID, pharmacyID string }
+
+// pendingNotification is a claimed notification not delivered yet, due
+// again once until has passed.
+type pendingNotification struct {
+	notification model.Notification
+	until        time.Time
+}
+
+type watchRepository struct {
+	mu       sync.RWMutex
+	watches  map[watchKey]model.Watch
+	notified map[watchKey]string
+	pending  map[watchKey]pendingNotification
+}
+
+// NewWatchRepository instantiates an in-memory implementation of watch
+// repository.
+func NewWatchRepository() model.WatchRepository {
+	return &watchRepository{
+		watches:  make(map[watchKey]model.Watch),
+		notified: make(map[watchKey]string),
+		pending:  make(map[watchKey]pendingNotification),
+	}
+}
+
+func (r *watchRepository) Save(_ context.Context, watch model.Watch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := watchKey{watch.UserID, watch.PharmacyID}
+	if w, ok := r.watches[k]; ok {
+		watch.CreatedAt = w.CreatedAt
+	} else {
+		watch.CreatedAt = time.Now()
+	}
+	r.watches[k] = watch
+	return nil
+}
+
+func (r *watchRepository) Remove(_ context.Context, userID, pharmacyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := watchKey{userID, pharmacyID}
+	if _, ok := r.watches[k]; !ok {
+		return model.ErrWatchNotFound
+	}
+	delete(r.watches, k)
+	delete(r.notified, k)
+	delete(r.pending, k)
+	return nil
+}
+
+func (r *watchRepository) RetrieveByUserID(_ context.Context, userID string) ([]model.Watch, error) {
+	return r.filter(func(w model.Watch) bool { return w.UserID == userID }), nil
+}
+
+func (r *watchRepository) RetrieveByPharmacyIDs(_ context.Context, pharmacyIDs []string) ([]model.Watch, error) {
+	ids := make(map[string]bool, len(pharmacyIDs))
+	for _, id := range pharmacyIDs {
+		ids[id] = true
+	}
+	return r.filter(func(w model.Watch) bool { return ids[w.PharmacyID] }), nil
+}
+
+// filter returns the watches matching match, oldest first.
+func (r *watchRepository) filter(match func(model.Watch) bool) []model.Watch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	watches := []model.Watch{}
+	for _, w := range r.watches {
+		if match(w) {
+			watches = append(watches, w)
+		}
+	}
+	sort.Slice(watches, func(i, j int) bool { return watches[i].CreatedAt.Before(watches[j].CreatedAt) })
+	return watches
+}
+
+func (r *watchRepository) Claim(_ context.Context, n model.Notification) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := watchKey{n.UserID, n.PharmacyID}
+	if _, ok := r.watches[k]; !ok || r.notified[k] == n.Snapshot {
+		return false, nil
+	}
+	r.notified[k] = n.Snapshot
+	r.pending[k] = pendingNotification{notification: n, until: time.Now()}
+	return true, nil
+}
+
+func (r *watchRepository) Due(_ context.Context, lease time.Duration, limit uint64) ([]model.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var due []watchKey
+	for k, p := range r.pending {
+		if !p.until.After(now) {
+			due = append(due, k)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return r.pending[due[i]].until.Before(r.pending[due[j]].until) })
+
+	leased := []model.Notification{}
+	for _, k := range due {
+		if uint64(len(leased)) == limit {
+			break
+		}
+		p := r.pending[k]
+		p.until = now.Add(lease)
+		r.pending[k] = p
+		leased = append(leased, p.notification)
+	}
+	return leased, nil
+}
+
+func (r *watchRepository) Delivered(_ context.Context, notifications []model.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, n := range notifications {
+		k := watchKey{n.UserID, n.PharmacyID}
+		if p, ok := r.pending[k]; ok && p.notification.Snapshot == n.Snapshot {
+			delete(r.pending, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+var _ model.WebhookRepository = (*webhookRepository)(nil)
+
+type webhookRepository struct {
+	mu         sync.RWMutex
+	webhooks   map[string]model.Webhook
+	deliveries map[string][]model.Delivery
+}
+
+// NewWebhookRepository instantiates an in-memory implementation of webhook
+// repository.
+func NewWebhookRepository() model.WebhookRepository {
+	return &webhookRepository{
+		webhooks:   make(map[string]model.Webhook),
+		deliveries: make(map[string][]model.Delivery),
+	}
+}
+
+func (r *webhookRepository) Save(_ context.Context, webhook model.Webhook) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.CreatedAt = time.Now()
+	r.webhooks[webhook.ID] = webhook
+	return webhook.ID, nil
+}
+
+func (r *webhookRepository) Remove(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return model.ErrWebhookNotFound
+	}
+	delete(r.webhooks, id)
+	delete(r.deliveries, id)
+	return nil
+}
+
+func (r *webhookRepository) RetrieveAll(context.Context) ([]model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]model.Webhook, 0, len(r.webhooks))
+	for _, w := range r.webhooks {
+		webhooks = append(webhooks, w)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+	return webhooks, nil
+}
+
+func (r *webhookRepository) Claim(_ context.Context, delivery model.Delivery) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries[delivery.WebhookID] {
+		if d.Snapshot == delivery.Snapshot {
+			return false, nil
+		}
+	}
+	delivery.CreatedAt = time.Now()
+	delivery.UpdatedAt = delivery.CreatedAt
+	delivery.NextAttemptAt = delivery.CreatedAt
+	r.deliveries[delivery.WebhookID] = append(r.deliveries[delivery.WebhookID], delivery)
+	return true, nil
+}
+
+func (r *webhookRepository) Due(_ context.Context, lease time.Duration, limit uint64) ([]model.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var due []*model.Delivery
+	for _, deliveries := range r.deliveries {
+		for i := range deliveries {
+			if deliveries[i].Status == model.DeliveryPending && !deliveries[i].NextAttemptAt.After(now) {
+				due = append(due, &deliveries[i])
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	leased := []model.Delivery{}
+	for _, d := range due {
+		if uint64(len(leased)) == limit {
+			break
+		}
+		d.NextAttemptAt = now.Add(lease)
+		leased = append(leased, *d)
+	}
+	return leased, nil
+}
+
+func (r *webhookRepository) UpdateDelivery(_ context.Context, delivery model.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := r.deliveries[delivery.WebhookID]
+	for i, d := range deliveries {
+		if d.Snapshot == delivery.Snapshot {
+			d.Status = delivery.Status
+			d.Attempts = delivery.Attempts
+			d.LastStatusCode = delivery.LastStatusCode
+			d.LastError = delivery.LastError
+			d.NextAttemptAt = delivery.NextAttemptAt
+			d.UpdatedAt = time.Now()
+			deliveries[i] = d
+		}
+	}
+	return nil
+}
+
+func (r *webhookRepository) RetrieveDeliveries(_ context.Context, webhookID string, offset, limit uint64) (model.DeliveryPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := append([]model.Delivery(nil), r.deliveries[webhookID]...)
+	sort.SliceStable(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+
+	items := []model.Delivery{}
+	if offset < uint64(len(deliveries)) {
+		end := offset + limit
+		if end > uint64(len(deliveries)) {
+			end = uint64(len(deliveries))
+		}
+		items = append(items, deliveries[offset:end]...)
+	}
+	for i := range items {
+		items[i].Payload = nil
+	}
+
+	return model.DeliveryPage{
+		Total:  uint64(len(deliveries)),
+		Offset: offset,
+		Limit:  limit,
+		Items:  items,
+	}, nil
+}
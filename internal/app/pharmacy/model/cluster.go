@@ -0,0 +1,11 @@
+package model
+
+// Cluster aggregates every pharmacy that falls into one grid cell of a
+// zoomed-out map view.
+type Cluster struct {
+	Longitude float64 `json:"longitude" db:"longitude"`
+	Latitude  float64 `json:"latitude" db:"latitude"`
+	Count     uint64  `json:"count" db:"count"`
+	MaskAdult uint64  `json:"maskAdult" db:"mask_adult"`
+	MaskChild uint64  `json:"maskChild" db:"mask_child"`
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// ErrWebhookNotFound indicates a webhook subscription does not exist.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+const (
+	EventRestocked = "restocked"
+	EventSoldOut   = "sold_out"
+	EventListed    = "listed"
+	EventRemoved   = "removed"
+)
+
+// StockEvent describes how a single pharmacy changed between two snapshots.
+type StockEvent struct {
+	Type          string  `json:"type"`
+	PharmacyID    string  `json:"pharmacyId"`
+	Name          string  `json:"name"`
+	MaskAdult     uint64  `json:"maskAdult"`
+	MaskChild     uint64  `json:"maskChild"`
+	PrevMaskAdult uint64  `json:"prevMaskAdult"`
+	PrevMaskChild uint64  `json:"prevMaskChild"`
+	Longitude     float64 `json:"longitude"`
+	Latitude      float64 `json:"latitude"`
+}
+
+// Diff compares two snapshots and returns the stock events that lead from
+// prev to next.
+func Diff(prev, next []Pharmacy) []StockEvent {
+	old := make(map[string]Pharmacy, len(prev))
+	for _, p := range prev {
+		old[p.Id] = p
+	}
+
+	events := []StockEvent{}
+	for _, p := range next {
+		o, ok := old[p.Id]
+		if !ok {
+			events = append(events, newStockEvent(EventListed, Pharmacy{}, p))
+			continue
+		}
+		delete(old, p.Id)
+
+		if (o.MaskAdult == 0 && p.MaskAdult > 0) || (o.MaskChild == 0 && p.MaskChild > 0) {
+			events = append(events, newStockEvent(EventRestocked, o, p))
+		}
+		if (o.MaskAdult > 0 && p.MaskAdult == 0) || (o.MaskChild > 0 && p.MaskChild == 0) {
+			events = append(events, newStockEvent(EventSoldOut, o, p))
+		}
+	}
+
+	for _, p := range prev {
+		if _, ok := old[p.Id]; ok {
+			events = append(events, newStockEvent(EventRemoved, p, Pharmacy{Id: p.Id, Name: p.Name, Longitude: p.Longitude, Latitude: p.Latitude}))
+		}
+	}
+	return events
+}
+
+func newStockEvent(typ string, prev, next Pharmacy) StockEvent {
+	return StockEvent{
+		Type:          typ,
+		PharmacyID:    next.Id,
+		Name:          next.Name,
+		MaskAdult:     next.MaskAdult,
+		MaskChild:     next.MaskChild,
+		PrevMaskAdult: prev.MaskAdult,
+		PrevMaskChild: prev.MaskChild,
+		Longitude:     next.Longitude,
+		Latitude:      next.Latitude,
+	}
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a partner endpoint subscribed to stock events.
+type Webhook struct {
+	ID        string         `json:"id" db:"id"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"secret,omitempty" db:"secret"`
+	Events    pq.StringArray `json:"events" db:"events"`
+	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
+}
+
+// Accepts reports whether the webhook subscribed to events of typ. A webhook
+// without explicit events receives every event.
+func (w Webhook) Accepts(typ string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery records the delivery of one snapshot's events to a webhook.
+type Delivery struct {
+	WebhookID      string    `json:"webhookId" db:"webhook_id"`
+	Snapshot       string    `json:"snapshot" db:"snapshot"`
+	Status         string    `json:"status" db:"status"`
+	Attempts       uint64    `json:"attempts" db:"attempts"`
+	LastStatusCode int       `json:"lastStatusCode" db:"last_status_code"`
+	LastError      string    `json:"lastError" db:"last_error"`
+	Payload        []byte    `json:"-" db:"payload"`
+	NextAttemptAt  time.Time `json:"nextAttemptAt" db:"next_attempt_at"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type DeliveryPage struct {
+	Total  uint64     `json:"total"`
+	Offset uint64     `json:"offset"`
+	Limit  uint64     `json:"limit"`
+	Items  []Delivery `json:"items"`
+}
+
+// WebhookRepository specifies a webhook subscription and delivery log
+// persistence API.
+type WebhookRepository interface {
+	// Save persists the webhook subscription.
+	Save(context.Context, Webhook) (string, error)
+
+	// Remove deletes the webhook subscription and its delivery log.
+	Remove(context.Context, string) error
+
+	// RetrieveAll returns every webhook subscription, secrets included.
+	RetrieveAll(context.Context) ([]Webhook, error)
+
+	// Claim records a pending delivery and its payload, due at once. It
+	// returns false if the delivery was already claimed, e.g. by another
+	// instance observing the same snapshot.
+	Claim(context.Context, Delivery) (bool, error)
+
+	// Due leases at most limit pending deliveries whose next attempt is due,
+	// postponing it by the lease so that no other worker picks them up
+	// meanwhile. A delivery left pending by a stopped worker is due again
+	// once its lease expires.
+	Due(context.Context, time.Duration, uint64) ([]Delivery, error)
+
+	// UpdateDelivery persists the outcome of a delivery attempt, and when
+	// the delivery is still pending, its next attempt.
+	UpdateDelivery(context.Context, Delivery) error
+
+	// RetrieveDeliveries returns the delivery log of a webhook, without the
+	// payloads.
+	RetrieveDeliveries(context.Context, string, uint64, uint64) (DeliveryPage, error)
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := Pharmacy{Id: "a", Name: "A", MaskAdult: 10, MaskChild: 5, Longitude: 121.5, Latitude: 25.0}
+
+	cases := []struct {
+		desc       string
+		prev, next []Pharmacy
+		want       []StockEvent
+	}{
+		{
+			desc: "unchanged",
+			prev: []Pharmacy{a},
+			next: []Pharmacy{a},
+			want: []StockEvent{},
+		},
+		{
+			desc: "stock changed but not sold out",
+			prev: []Pharmacy{a},
+			next: []Pharmacy{{Id: "a", Name: "A", MaskAdult: 1, MaskChild: 1}},
+			want: []StockEvent{},
+		},
+		{
+			desc: "listed",
+			next: []Pharmacy{a},
+			want: []StockEvent{{Type: EventListed, PharmacyID: "a", Name: "A", MaskAdult: 10, MaskChild: 5, Longitude: 121.5, Latitude: 25.0}},
+		},
+		{
+			desc: "removed",
+			prev: []Pharmacy{a},
+			want: []StockEvent{{Type: EventRemoved, PharmacyID: "a", Name: "A", PrevMaskAdult: 10, PrevMaskChild: 5, Longitude: 121.5, Latitude: 25.0}},
+		},
+		{
+			desc: "sold out",
+			prev: []Pharmacy{a},
+			next: []Pharmacy{{Id: "a", Name: "A", MaskAdult: 0, MaskChild: 5}},
+			want: []StockEvent{{Type: EventSoldOut, PharmacyID: "a", Name: "A", MaskChild: 5, PrevMaskAdult: 10, PrevMaskChild: 5}},
+		},
+		{
+			desc: "restocked",
+			prev: []Pharmacy{{Id: "a", Name: "A"}},
+			next: []Pharmacy{{Id: "a", Name: "A", MaskChild: 20}},
+			want: []StockEvent{{Type: EventRestocked, PharmacyID: "a", Name: "A", MaskChild: 20}},
+		},
+		{
+			desc: "adult restocked while child sold out",
+			prev: []Pharmacy{{Id: "a", MaskChild: 3}},
+			next: []Pharmacy{{Id: "a", MaskAdult: 7}},
+			want: []StockEvent{
+				{Type: EventRestocked, PharmacyID: "a", MaskAdult: 7, PrevMaskChild: 3},
+				{Type: EventSoldOut, PharmacyID: "a", MaskAdult: 7, PrevMaskChild: 3},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		if got := Diff(c.prev, c.next); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.desc, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+const (
+	featureCollectionType = "FeatureCollection"
+	featureType           = "Feature"
+	pointType             = "Point"
+)
+
+// FeatureCollection is a GeoJSON (RFC 7946) FeatureCollection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature with a Point geometry.
+type Feature struct {
+	Type       string      `json:"type"`
+	ID         string      `json:"id,omitempty"`
+	Geometry   Point       `json:"geometry"`
+	Properties interface{} `json:"properties"`
+}
+
+// Point is a GeoJSON Point geometry, coordinates are [longitude, latitude].
+type Point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+func newPoint(lng, lat float64) Point {
+	return Point{Type: pointType, Coordinates: [2]float64{lng, lat}}
+}
+
+// FeatureCollection returns pharmacies as Point features carrying every
+// pharmacy field as properties.
+func (p Pharmacies) FeatureCollection() FeatureCollection {
+	fc := FeatureCollection{Type: featureCollectionType, Features: make([]Feature, 0, len(p))}
+	for i := range p {
+		fc.Features = append(fc.Features, Feature{
+			Type:       featureType,
+			ID:         p[i].Id,
+			Geometry:   newPoint(p[i].Longitude, p[i].Latitude),
+			Properties: &p[i],
+		})
+	}
+	return fc
+}
+
+// FeatureCollectionFromClusters returns clusters as Point features located at their
+// centroid.
+func FeatureCollectionFromClusters(clusters []Cluster) FeatureCollection {
+	fc := FeatureCollection{Type: featureCollectionType, Features: make([]Feature, 0, len(clusters))}
+	for i := range clusters {
+		fc.Features = append(fc.Features, Feature{
+			Type:       featureType,
+			Geometry:   newPoint(clusters[i].Longitude, clusters[i].Latitude),
+			Properties: clusters[i],
+		})
+	}
+	return fc
+}
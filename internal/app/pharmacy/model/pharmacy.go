@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -64,7 +65,78 @@ func (p *Pharmacy) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UnmarshalJSON reads the RFC 3339 updated time written by MarshalJSON.
+func (p *Pharmacy) UnmarshalJSON(b []byte) error {
+	type Alias Pharmacy
+
+	aux := &struct {
+		*Alias
+		Updated string `json:"updated"`
+	}{
+		Alias: (*Alias)(p),
+	}
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+
+	p.Updated = nil
+	if aux.Updated != "" {
+		t, err := time.Parse(time.RFC3339, aux.Updated)
+		if err != nil {
+			return err
+		}
+		p.Updated = &pq.NullTime{Time: t, Valid: true}
+	}
+	return nil
+}
+
+// PharmacyCSVHeader lists the CSV columns written by Pharmacy.CSVRecord.
+var PharmacyCSVHeader = []string{"id", "name", "phone", "address", "mask_adult", "mask_child", "updated", "available", "custom_note", "website", "note", "longitude", "latitude", "service_periods", "service_note", "county", "town", "cunli"}
+
+func (p Pharmacy) CSVRecord() []string {
+	var updated string
+	if p.Updated != nil && p.Updated.Valid {
+		updated = p.Updated.Time.In(util.Location).Format(time.RFC3339)
+	}
+
+	return []string{
+		p.Id,
+		p.Name,
+		p.Phone,
+		p.Address,
+		strconv.FormatUint(p.MaskAdult, 10),
+		strconv.FormatUint(p.MaskChild, 10),
+		updated,
+		p.Available,
+		p.CustomNote,
+		p.Website,
+		p.Note,
+		strconv.FormatFloat(p.Longitude, 'f', -1, 64),
+		strconv.FormatFloat(p.Latitude, 'f', -1, 64),
+		p.ServicePeriods,
+		p.ServiceNote,
+		p.County,
+		p.Town,
+		p.Cunli,
+	}
+}
+
+// PharmacyCursor iterates over pharmacies streamed from the repository. It
+// must be closed once consumed.
+type PharmacyCursor interface {
+	Next() bool
+	Pharmacy() (Pharmacy, error)
+	Err() error
+	Close() error
+}
+
 type PharmacyRepository interface {
 	Query(context.Context, string, float64, float64, float64, float64, float64, float64, uint64) ([]Pharmacy, error)
+	Cluster(context.Context, string, float64, float64, float64, float64, float64) ([]Cluster, error)
+	// Nearby returns at most max pharmacies within a radius in miles of a
+	// point, nearest first.
+	Nearby(context.Context, string, float64, float64, float64, uint64) ([]Pharmacy, error)
+	Export(context.Context, string) (PharmacyCursor, error)
+	Snapshot(context.Context, string) ([]Pharmacy, error)
 	GetLatestPharmacyTableName(context.Context) (string, error)
 }
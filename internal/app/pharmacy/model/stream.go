@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+const (
+	StreamStock    = "stock"
+	StreamFeedback = "feedback"
+)
+
+// StreamEvent is pushed to live stream subscribers.
+type StreamEvent struct {
+	Type       string         `json:"type"`
+	Snapshot   string         `json:"snapshot,omitempty"`
+	Pharmacies []Pharmacy     `json:"pharmacies,omitempty"`
+	Feedback   *FeedbackEvent `json:"feedback,omitempty"`
+}
+
+// FeedbackEvent announces a feedback reported by the feedback service.
+type FeedbackEvent struct {
+	ID          string    `json:"id"`
+	PharmacyID  string    `json:"pharmacyId"`
+	OptionID    string    `json:"optionId"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// BoundingBox is a longitude/latitude aligned rectangle.
+type BoundingBox struct {
+	SwLng float64
+	SwLat float64
+	NeLng float64
+	NeLat float64
+}
+
+// Contains reports whether the point lies inside the box.
+func (b BoundingBox) Contains(lng, lat float64) bool {
+	return lng >= b.SwLng && lng <= b.NeLng && lat >= b.SwLat && lat <= b.NeLat
+}
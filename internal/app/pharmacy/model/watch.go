@@ -0,0 +1,75 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// ErrWatchNotFound indicates a user does not watch a pharmacy.
+var ErrWatchNotFound = errors.New("watch not found")
+
+// Watch is a user following a pharmacy. The user is notified once stock of
+// a mask type climbs to its threshold; a zero threshold ignores that type.
+type Watch struct {
+	UserID     string    `json:"userId" db:"user_id"`
+	PharmacyID string    `json:"pharmacyId" db:"pharmacy_id"`
+	MaskAdult  uint64    `json:"maskAdult" db:"mask_adult"`
+	MaskChild  uint64    `json:"maskChild" db:"mask_child"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Triggered reports whether the stock change from prev to next crosses one
+// of the watch thresholds.
+func (w Watch) Triggered(prev, next Pharmacy) bool {
+	return crossed(w.MaskAdult, prev.MaskAdult, next.MaskAdult) ||
+		crossed(w.MaskChild, prev.MaskChild, next.MaskChild)
+}
+
+func crossed(threshold, prev, next uint64) bool {
+	return threshold > 0 && prev < threshold && next >= threshold
+}
+
+// Notification tells a user that a watched pharmacy has been restocked.
+type Notification struct {
+	UserID     string  `json:"userId"`
+	PharmacyID string  `json:"pharmacyId"`
+	Name       string  `json:"name"`
+	Address    string  `json:"address"`
+	MaskAdult  uint64  `json:"maskAdult"`
+	MaskChild  uint64  `json:"maskChild"`
+	Longitude  float64 `json:"longitude"`
+	Latitude   float64 `json:"latitude"`
+	Snapshot   string  `json:"snapshot"`
+}
+
+// WatchRepository specifies a watchlist persistence API.
+type WatchRepository interface {
+	// Save creates the watch or updates its thresholds.
+	Save(context.Context, Watch) error
+
+	// Remove deletes the watch of a user on a pharmacy.
+	Remove(context.Context, string, string) error
+
+	// RetrieveByUserID returns every watch of a user.
+	RetrieveByUserID(context.Context, string) ([]Watch, error)
+
+	// RetrieveByPharmacyIDs returns every watch on the given pharmacies.
+	RetrieveByPharmacyIDs(context.Context, []string) ([]Watch, error)
+
+	// Claim marks the watch of the notification as notified for its snapshot
+	// and keeps the notification pending until it is delivered. It returns
+	// false if the notification was already claimed, e.g. by another
+	// instance.
+	Claim(context.Context, Notification) (bool, error)
+
+	// Due leases at most limit pending notifications, postponing them by the
+	// lease so that no other worker picks them up meanwhile. A notification
+	// that is not marked delivered is due again once its lease expires.
+	Due(context.Context, time.Duration, uint64) ([]Notification, error)
+
+	// Delivered clears the pending notifications. A notification superseded
+	// by the claim of a later snapshot is left pending.
+	Delivered(context.Context, []Notification) error
+}
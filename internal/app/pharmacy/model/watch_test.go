@@ -0,0 +1,28 @@
+package model
+
+import "testing"
+
+func TestWatchTriggered(t *testing.T) {
+	cases := []struct {
+		desc       string
+		watch      Watch
+		prev, next Pharmacy
+		want       bool
+	}{
+		{"adult climbs to threshold", Watch{MaskAdult: 10}, Pharmacy{MaskAdult: 0}, Pharmacy{MaskAdult: 10}, true},
+		{"adult climbs past threshold", Watch{MaskAdult: 10}, Pharmacy{MaskAdult: 9}, Pharmacy{MaskAdult: 200}, true},
+		{"adult stays below threshold", Watch{MaskAdult: 10}, Pharmacy{MaskAdult: 0}, Pharmacy{MaskAdult: 9}, false},
+		{"adult already above threshold", Watch{MaskAdult: 10}, Pharmacy{MaskAdult: 10}, Pharmacy{MaskAdult: 50}, false},
+		{"adult drops below threshold", Watch{MaskAdult: 10}, Pharmacy{MaskAdult: 50}, Pharmacy{MaskAdult: 5}, false},
+		{"child climbs to threshold", Watch{MaskChild: 20}, Pharmacy{MaskChild: 19}, Pharmacy{MaskChild: 20}, true},
+		{"zero threshold ignores child", Watch{MaskAdult: 10}, Pharmacy{MaskChild: 0}, Pharmacy{MaskChild: 100}, false},
+		{"no thresholds", Watch{}, Pharmacy{}, Pharmacy{MaskAdult: 100, MaskChild: 100}, false},
+		{"either type triggers", Watch{MaskAdult: 10, MaskChild: 20}, Pharmacy{MaskAdult: 50, MaskChild: 0}, Pharmacy{MaskAdult: 50, MaskChild: 20}, true},
+	}
+
+	for _, c := range cases {
+		if got := c.watch.Triggered(c.prev, c.next); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.desc, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+)
+
+var _ service.NotificationSender = (*fileSender)(nil)
+
+type fileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSender instantiates a sender that appends notifications to path as
+// newline delimited JSON. It is meant for local development and testing.
+func NewFileSender(path string) service.NotificationSender {
+	return &fileSender{path: path}
+}
+
+func (s *fileSender) Send(_ context.Context, notifications []model.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, n := range notifications {
+		if err := enc.Encode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
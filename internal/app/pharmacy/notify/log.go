@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/level"
+)
+
+var _ service.NotificationSender = (*logSender)(nil)
+
+type logSender struct {
+	logger log.Logger
+}
+
+// NewLogSender instantiates a sender that only logs notifications. It is
+// meant for local development and testing.
+func NewLogSender(logger log.Logger) service.NotificationSender {
+	return &logSender{level.Info(logger)}
+}
+
+func (s *logSender) Send(_ context.Context, notifications []model.Notification) error {
+	for _, n := range notifications {
+		s.logger.Log("notification", "restocked", "userID", n.UserID, "pharmacyID", n.PharmacyID, "maskAdult", n.MaskAdult, "maskChild", n.MaskChild, "snapshot", n.Snapshot)
+	}
+	return nil
+}
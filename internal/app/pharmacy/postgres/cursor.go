@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+var _ model.PharmacyCursor = (*pharmacyCursor)(nil)
+
+type pharmacyCursor struct {
+	rows *sqlx.Rows
+}
+
+func (c pharmacyCursor) Next() bool {
+	return c.rows.Next()
+}
+
+func (c pharmacyCursor) Pharmacy() (model.Pharmacy, error) {
+	var p model.Pharmacy
+	err := c.rows.StructScan(&p)
+	return p, err
+}
+
+func (c pharmacyCursor) Err() error {
+	return c.rows.Err()
+}
+
+func (c pharmacyCursor) Close() error {
+	return c.rows.Close()
+}
@@ -3,20 +3,29 @@ package postgres
 import (
 	"context"
 	"fmt"
+
 	"github.com/go-kit/kit/log"
 	"github.com/gomurphyx/sqlx"
 
 	"github.com/cage1016/mask/internal/app/pharmacy/model"
 	"github.com/cage1016/mask/internal/pkg/errors"
 	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	psql "github.com/cage1016/mask/internal/pkg/postgres"
 )
 
 var (
 	ErrQueryStoreFromPharmaciesDB = errors.New("query pharmacies from DB failed")
+	ErrClusterPharmaciesFromDB    = errors.New("cluster pharmacies from DB failed")
+	ErrExportPharmaciesFromDB     = errors.New("export pharmacies from DB failed")
 )
 
 var _ model.PharmacyRepository = (*pharmacyRepository)(nil)
 
+// snapshotTables are the pharmacy snapshot tables listed by the
+// latest_pharmacy_table view, e.g. pharmacy_0322_1030.
+var snapshotTables = psql.NewTables(`pharmacy_[0-9]{4}_[0-9]{4}`)
+
 type pharmacyRepository struct {
 	db  *sqlx.DB
 	log log.Logger
@@ -29,13 +38,88 @@ func New(db *sqlx.DB, log log.Logger) model.PharmacyRepository {
 }
 
 func (s pharmacyRepository) Query(ctx context.Context, latestPharmacyTable string, centerLng, centerLat, swLng, neLng, swLat, neLat float64, max uint64) ([]model.Pharmacy, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return []model.Pharmacy{}, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+
 	q := fmt.Sprintf(`SELECT *, point ($1, $2) <@> point(longitude, latitude)::point as distance
 			FROM (select * from %s where longitude >= $3 and longitude <= $4 and latitude >= $5 and latitude <= $6) as a
-			ORDER BY distance limit $7;`, latestPharmacyTable)
+			ORDER BY distance limit $7;`, table)
 
 	pharmacies := []model.Pharmacy{}
 	if err := s.db.SelectContext(ctx, &pharmacies, q, centerLng, centerLat, swLng, neLng, swLat, neLat, max); err != nil {
-		level.Error(s.log).Log("method", "s.db.SelectContext", "err", err)
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return pharmacies, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+	return pharmacies, nil
+}
+
+func (s pharmacyRepository) Nearby(ctx context.Context, latestPharmacyTable string, centerLng, centerLat, radius float64, max uint64) ([]model.Pharmacy, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return []model.Pharmacy{}, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+
+	q := fmt.Sprintf(`SELECT *, point ($1, $2) <@> point(longitude, latitude)::point as distance
+			FROM %s
+			WHERE point ($1, $2) <@> point(longitude, latitude)::point <= $3
+			ORDER BY distance limit $4;`, table)
+
+	pharmacies := []model.Pharmacy{}
+	if err := s.db.SelectContext(ctx, &pharmacies, q, centerLng, centerLat, radius, max); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return pharmacies, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+	return pharmacies, nil
+}
+
+func (s pharmacyRepository) Cluster(ctx context.Context, latestPharmacyTable string, swLng, neLng, swLat, neLat, gridSize float64) ([]model.Cluster, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return []model.Cluster{}, errors.Wrap(ErrClusterPharmaciesFromDB, err)
+	}
+
+	q := fmt.Sprintf(`SELECT avg(longitude) as longitude, avg(latitude) as latitude, count(*) as count,
+			sum(mask_adult) as mask_adult, sum(mask_child) as mask_child
+			FROM %s where longitude >= $1 and longitude <= $2 and latitude >= $3 and latitude <= $4
+			GROUP BY floor(longitude / $5), floor(latitude / $5);`, table)
+
+	clusters := []model.Cluster{}
+	if err := s.db.SelectContext(ctx, &clusters, q, swLng, neLng, swLat, neLat, gridSize); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return clusters, errors.Wrap(ErrClusterPharmaciesFromDB, err)
+	}
+	return clusters, nil
+}
+
+func (s pharmacyRepository) Export(ctx context.Context, latestPharmacyTable string) (model.PharmacyCursor, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return nil, errors.Wrap(ErrExportPharmaciesFromDB, err)
+	}
+
+	q := fmt.Sprintf(`SELECT * FROM %s ORDER BY id;`, table)
+
+	rows, err := s.db.QueryxContext(ctx, q)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.QueryxContext", "err", err)
+		return nil, errors.Wrap(ErrExportPharmaciesFromDB, err)
+	}
+	return pharmacyCursor{rows}, nil
+}
+
+func (s pharmacyRepository) Snapshot(ctx context.Context, name string) ([]model.Pharmacy, error) {
+	table, err := snapshotTables.Quote(name)
+	if err != nil {
+		return []model.Pharmacy{}, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+
+	q := fmt.Sprintf(`SELECT * FROM %s;`, table)
+
+	pharmacies := []model.Pharmacy{}
+	if err := s.db.SelectContext(ctx, &pharmacies, q); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
 		return pharmacies, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
 	}
 	return pharmacies, nil
@@ -48,7 +132,7 @@ func (s pharmacyRepository) GetLatestPharmacyTableName(ctx context.Context) (str
 
 	q := `select table_name from latest_pharmacy_table;`
 	if err := s.db.GetContext(ctx, &lt, q); err != nil {
-		level.Error(s.log).Log("method", "GetLatestPharmacyTableName", "err", err)
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "GetLatestPharmacyTableName", "err", err)
 		return "", err
 	}
 	return lt.TableName, nil
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/repotest"
+	"github.com/cage1016/mask/internal/pkg/postgres/pgtest"
+)
+
+// The benchmarks run against the pgtest database, like the conformance
+// suites. The PostGIS cases are skipped where the postgis
+// extension is missing.
+const (
+	benchTable      = "pharmacy_0000_0000"
+	benchPharmacies = 6000
+)
+
+// viewport is a map viewport over Taipei.
+var viewport = struct{ centerLng, centerLat, swLng, neLng, swLat, neLat float64 }{
+	121.5418, 25.0478, 121.5005, 121.5830, 25.0210, 25.0745,
+}
+
+var seeded bool
+
+// seededDB is the pgtest database with benchTable seeded once per run.
+func seededDB(b *testing.B) *sqlx.DB {
+	db := pgtest.Require(b)
+	if !seeded {
+		seed(b, db)
+		seeded = true
+	}
+	return db
+}
+
+// seed fills benchTable with pharmacies spread over Taiwan, denser around
+// Taipei. Its name sorts before the real snapshots, so latest_pharmacy_table
+// never prefers it to them.
+func seed(b *testing.B, db *sqlx.DB) {
+	stmts := []string{
+		`drop table if exists ` + benchTable,
+		`create table ` + benchTable + ` (like pharmacies including all)`,
+		`select setseed(0.42)`,
+		`insert into ` + benchTable + ` (id, name, longitude, latitude)
+			select lpad(i::text, 10, '0'), 'pharmacy ' || i,
+				case when i % 4 = 0 then 121.45 + random() * 0.2 else 120.1 + random() * 1.9 end,
+				case when i % 4 = 0 then 24.98 + random() * 0.15 else 22.0 + random() * 3.3 end
+			from generate_series(1, ` + strconv.Itoa(benchPharmacies) + `) i`,
+		`analyze ` + benchTable,
+	}
+	for _, q := range stmts {
+		if _, err := db.Exec(q); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// addSnapshot creates table like the snapshot loader does and drops it once
+// the test is done.
+func addSnapshot(db *sqlx.DB) repotest.AddSnapshot {
+	return func(t *testing.T, table string, pharmacies []model.Pharmacy) {
+		stmts := []string{
+			`drop table if exists ` + table,
+			`create table ` + table + ` (like pharmacies including all)`,
+		}
+		for _, q := range stmts {
+			if _, err := db.Exec(q); err != nil {
+				t.Fatal(err)
+			}
+		}
+		t.Cleanup(func() { db.Exec(`drop table if exists ` + table) })
+
+		q := `insert into ` + table + ` (id, name, mask_adult, mask_child, longitude, latitude)
+			values (:id, :name, :mask_adult, :mask_child, :longitude, :latitude)`
+		for _, p := range pharmacies {
+			if _, err := db.NamedExec(q, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestPharmacyRepository(t *testing.T) {
+	db := pgtest.Require(t)
+	repotest.Run(t, New(db, log.NewNopLogger()), addSnapshot(db))
+}
+
+func TestPostGISRepository(t *testing.T) {
+	db := pgtest.Require(t)
+	repo, err := NewPostGIS(context.Background(), db, log.NewNopLogger())
+	if err != nil {
+		t.Skip(err)
+	}
+	repotest.Run(t, repo, addSnapshot(db))
+}
+
+func repositories(b *testing.B) map[string]model.PharmacyRepository {
+	db := seededDB(b)
+	repos := map[string]model.PharmacyRepository{"earthdistance": New(db, log.NewNopLogger())}
+	if repo, err := NewPostGIS(context.Background(), db, log.NewNopLogger()); err == nil {
+		awaitSync(b, repo.(*postgisRepository))
+		repos["postgis"] = repo
+	}
+	return repos
+}
+
+// awaitSync waits for the locations of benchTable to be synced, so the
+// PostGIS cases do not time the earthdistance fallback.
+func awaitSync(b *testing.B, repo *postgisRepository) {
+	table, err := snapshotTables.Quote(benchTable)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for deadline := time.Now().Add(syncTimeout); !repo.ready(table); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			b.Fatalf("locations of %s not synced", benchTable)
+		}
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+	for name, repo := range repositories(b) {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			v := viewport
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.Query(ctx, benchTable, v.centerLng, v.centerLat, v.swLng, v.neLng, v.swLat, v.neLat, 50); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNearby(b *testing.B) {
+	for name, repo := range repositories(b) {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			v := viewport
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.Nearby(ctx, benchTable, v.centerLng, v.centerLat, 2, 50); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCluster(b *testing.B) {
+	for name, repo := range repositories(b) {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.Cluster(ctx, benchTable, 120.0, 122.0, 21.9, 25.4, 0.1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+)
+
+const (
+	// metersPerMile converts PostGIS distances to the statute miles returned
+	// by the earthdistance <@> operator.
+	metersPerMile = 1609.344
+
+	// syncTimeout bounds the upsert of the locations of a snapshot.
+	syncTimeout = time.Minute
+)
+
+var (
+	// ErrPostGISUnavailable indicates the pharmacy_locations table was not
+	// migrated because the postgis extension is missing.
+	ErrPostGISUnavailable = errors.New("postgis pharmacy locations are unavailable")
+)
+
+var _ model.PharmacyRepository = (*postgisRepository)(nil)
+
+// postgisRepository answers the geographic queries from the GiST indexed
+// pharmacy_locations table, synced in the background from each new snapshot
+// table, and leaves the rest to the earthdistance repository. Until the
+// locations of a snapshot are synced, its queries are answered by the
+// earthdistance repository too, so readers never wait on the sync.
+type postgisRepository struct {
+	pharmacyRepository
+
+	mu      sync.Mutex
+	synced  string
+	syncing string
+}
+
+// NewPostGIS instantiates a PostGIS implementation of the pharmacy
+// repository.
+func NewPostGIS(ctx context.Context, db *sqlx.DB, log log.Logger) (model.PharmacyRepository, error) {
+	var exists bool
+	if err := db.GetContext(ctx, &exists, `select to_regclass('pharmacy_locations') is not null;`); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPostGISUnavailable
+	}
+	return &postgisRepository{pharmacyRepository: pharmacyRepository{db, log}}, nil
+}
+
+func (s *postgisRepository) Query(ctx context.Context, latestPharmacyTable string, centerLng, centerLat, swLng, neLng, swLat, neLat float64, max uint64) ([]model.Pharmacy, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return []model.Pharmacy{}, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+	if !s.ready(table) {
+		return s.pharmacyRepository.Query(ctx, latestPharmacyTable, centerLng, centerLat, swLng, neLng, swLat, neLat, max)
+	}
+
+	q := fmt.Sprintf(`SELECT p.*, ST_Distance(l.geog, c.geog) / %f as distance
+			FROM pharmacy_locations l
+			JOIN %s p USING (id),
+			(select ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography as geog) c
+			WHERE l.geog && ST_MakeEnvelope($3, $5, $4, $6, 4326)::geography
+			ORDER BY l.geog <-> c.geog limit $7;`, metersPerMile, table)
+
+	pharmacies := []model.Pharmacy{}
+	if err := s.db.SelectContext(ctx, &pharmacies, q, centerLng, centerLat, swLng, neLng, swLat, neLat, max); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return pharmacies, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+	return pharmacies, nil
+}
+
+// Nearby filters with ST_DWithin, which the GiST index of pharmacy_locations
+// answers, unlike a comparison of ST_Distance.
+func (s *postgisRepository) Nearby(ctx context.Context, latestPharmacyTable string, centerLng, centerLat, radius float64, max uint64) ([]model.Pharmacy, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return []model.Pharmacy{}, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+	if !s.ready(table) {
+		return s.pharmacyRepository.Nearby(ctx, latestPharmacyTable, centerLng, centerLat, radius, max)
+	}
+
+	q := fmt.Sprintf(`SELECT p.*, ST_Distance(l.geog, c.geog) / %f as distance
+			FROM pharmacy_locations l
+			JOIN %s p USING (id),
+			(select ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography as geog) c
+			WHERE ST_DWithin(l.geog, c.geog, $3)
+			ORDER BY l.geog <-> c.geog limit $4;`, metersPerMile, table)
+
+	pharmacies := []model.Pharmacy{}
+	if err := s.db.SelectContext(ctx, &pharmacies, q, centerLng, centerLat, radius*metersPerMile, max); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return pharmacies, errors.Wrap(ErrQueryStoreFromPharmaciesDB, err)
+	}
+	return pharmacies, nil
+}
+
+func (s *postgisRepository) Cluster(ctx context.Context, latestPharmacyTable string, swLng, neLng, swLat, neLat, gridSize float64) ([]model.Cluster, error) {
+	table, err := snapshotTables.Quote(latestPharmacyTable)
+	if err != nil {
+		return []model.Cluster{}, errors.Wrap(ErrClusterPharmaciesFromDB, err)
+	}
+	if !s.ready(table) {
+		return s.pharmacyRepository.Cluster(ctx, latestPharmacyTable, swLng, neLng, swLat, neLat, gridSize)
+	}
+
+	q := fmt.Sprintf(`SELECT avg(p.longitude) as longitude, avg(p.latitude) as latitude, count(*) as count,
+			sum(p.mask_adult) as mask_adult, sum(p.mask_child) as mask_child
+			FROM pharmacy_locations l
+			JOIN %s p USING (id)
+			WHERE l.geog && ST_MakeEnvelope($1, $3, $2, $4, 4326)::geography
+			GROUP BY floor(p.longitude / $5), floor(p.latitude / $5);`, table)
+
+	clusters := []model.Cluster{}
+	if err := s.db.SelectContext(ctx, &clusters, q, swLng, neLng, swLat, neLat, gridSize); err != nil {
+		level.Error(logging.WithRequestID(ctx, s.log)).Log("method", "s.db.SelectContext", "err", err)
+		return clusters, errors.Wrap(ErrClusterPharmaciesFromDB, err)
+	}
+	return clusters, nil
+}
+
+// ready reports whether the locations of the quoted snapshot table are
+// synced, and starts syncing them in the background if they are not.
+func (s *postgisRepository) ready(table string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.synced == table {
+		return true
+	}
+	if s.syncing != table {
+		s.syncing = table
+		go s.sync(table)
+	}
+	return false
+}
+
+// sync upserts the locations of the quoted snapshot table. A failed sync is
+// retried by the next query; a sync overtaken by a newer snapshot is not
+// recorded.
+func (s *postgisRepository) sync(table string) {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	q := fmt.Sprintf(`INSERT INTO pharmacy_locations (id, geog)
+			SELECT id, ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography FROM %s
+			ON CONFLICT (id) DO UPDATE SET geog = excluded.geog
+			WHERE NOT ST_Equals(pharmacy_locations.geog::geometry, excluded.geog::geometry);`, table)
+	_, err := s.db.ExecContext(ctx, q)
+	if err != nil {
+		level.Error(s.log).Log("method", "s.db.ExecContext", "table", table, "err", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.syncing != table {
+		return
+	}
+	s.syncing = ""
+	if err == nil {
+		s.synced = table
+	}
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+)
+
+var (
+	ErrSaveWatchToDB       = errors.New("save watch to DB failed")
+	ErrRetrieveWatchFromDB = errors.New("retrieve watch from DB failed")
+)
+
+var _ model.WatchRepository = (*watchRepository)(nil)
+
+type watchRepository struct {
+	db  *sqlx.DB
+	log log.Logger
+}
+
+// NewWatchRepository instantiates a PostgreSQL implementation of watch
+// repository.
+func NewWatchRepository(db *sqlx.DB, log log.Logger) model.WatchRepository {
+	return &watchRepository{db, log}
+}
+
+func (w watchRepository) Save(ctx context.Context, watch model.Watch) error {
+	q := `INSERT INTO watches (user_id, pharmacy_id, mask_adult, mask_child) VALUES (:user_id, :pharmacy_id, :mask_adult, :mask_child)
+			ON CONFLICT (user_id, pharmacy_id) DO UPDATE SET mask_adult = excluded.mask_adult, mask_child = excluded.mask_child;`
+	if _, err := w.db.NamedExecContext(ctx, q, watch); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.NamedExecContext", "err", err)
+		return errors.Wrap(ErrSaveWatchToDB, err)
+	}
+	return nil
+}
+
+func (w watchRepository) Remove(ctx context.Context, userID, pharmacyID string) error {
+	res, err := w.db.ExecContext(ctx, `DELETE FROM watches WHERE user_id = $1 AND pharmacy_id = $2;`, userID, pharmacyID)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.ExecContext", "err", err)
+		return errors.Wrap(ErrSaveWatchToDB, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrWatchNotFound
+	}
+	return nil
+}
+
+func (w watchRepository) RetrieveByUserID(ctx context.Context, userID string) ([]model.Watch, error) {
+	watches := []model.Watch{}
+	q := `SELECT user_id, pharmacy_id, mask_adult, mask_child, created_at FROM watches WHERE user_id = $1 ORDER BY created_at;`
+	if err := w.db.SelectContext(ctx, &watches, q, userID); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.SelectContext", "err", err)
+		return watches, errors.Wrap(ErrRetrieveWatchFromDB, err)
+	}
+	return watches, nil
+}
+
+func (w watchRepository) RetrieveByPharmacyIDs(ctx context.Context, pharmacyIDs []string) ([]model.Watch, error) {
+	watches := []model.Watch{}
+	q := `SELECT user_id, pharmacy_id, mask_adult, mask_child, created_at FROM watches WHERE pharmacy_id = any($1);`
+	if err := w.db.SelectContext(ctx, &watches, q, pq.Array(pharmacyIDs)); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.SelectContext", "err", err)
+		return watches, errors.Wrap(ErrRetrieveWatchFromDB, err)
+	}
+	return watches, nil
+}
+
+func (w watchRepository) Claim(ctx context.Context, n model.Notification) (bool, error) {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return false, errors.Wrap(ErrSaveWatchToDB, err)
+	}
+
+	q := `UPDATE watches SET last_notified_snapshot = $3, pending_notification = $4, pending_until = now()
+			WHERE user_id = $1 AND pharmacy_id = $2 AND last_notified_snapshot <> $3;`
+	res, err := w.db.ExecContext(ctx, q, n.UserID, n.PharmacyID, n.Snapshot, payload)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.ExecContext", "err", err)
+		return false, errors.Wrap(ErrSaveWatchToDB, err)
+	}
+
+	c, err := res.RowsAffected()
+	return c == 1, err
+}
+
+func (w watchRepository) Due(ctx context.Context, lease time.Duration, limit uint64) ([]model.Notification, error) {
+	// SKIP LOCKED leaves the rows another worker is leasing to it
+	q := `UPDATE watches SET pending_until = now() + make_interval(secs => $1)
+			WHERE (user_id, pharmacy_id) IN (
+				SELECT user_id, pharmacy_id FROM watches
+				WHERE pending_notification IS NOT NULL AND pending_until <= now()
+				ORDER BY pending_until LIMIT $2 FOR UPDATE SKIP LOCKED)
+			RETURNING pending_notification;`
+	payloads := [][]byte{}
+	if err := w.db.SelectContext(ctx, &payloads, q, lease.Seconds(), limit); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.SelectContext", "err", err)
+		return []model.Notification{}, errors.Wrap(ErrSaveWatchToDB, err)
+	}
+
+	notifications := make([]model.Notification, len(payloads))
+	for i, payload := range payloads {
+		if err := json.Unmarshal(payload, &notifications[i]); err != nil {
+			return []model.Notification{}, errors.Wrap(ErrRetrieveWatchFromDB, err)
+		}
+	}
+	return notifications, nil
+}
+
+func (w watchRepository) Delivered(ctx context.Context, notifications []model.Notification) error {
+	userIDs := make([]string, len(notifications))
+	pharmacyIDs := make([]string, len(notifications))
+	snapshots := make([]string, len(notifications))
+	for i, n := range notifications {
+		userIDs[i], pharmacyIDs[i], snapshots[i] = n.UserID, n.PharmacyID, n.Snapshot
+	}
+
+	// a watch claimed again for a later snapshot keeps that notification
+	q := `UPDATE watches w SET pending_notification = NULL, pending_until = NULL
+			FROM unnest($1::text[], $2::text[], $3::text[]) AS d(user_id, pharmacy_id, snapshot)
+			WHERE w.user_id = d.user_id AND w.pharmacy_id = d.pharmacy_id AND w.last_notified_snapshot = d.snapshot;`
+	if _, err := w.db.ExecContext(ctx, q, pq.Array(userIDs), pq.Array(pharmacyIDs), pq.Array(snapshots)); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.ExecContext", "err", err)
+		return errors.Wrap(ErrSaveWatchToDB, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+)
+
+var (
+	ErrSaveWebhookToDB       = errors.New("save webhook to DB failed")
+	ErrSaveDeliveryToDB      = errors.New("save webhook delivery to DB failed")
+	ErrRetrieveWebhookFromDB = errors.New("retrieve webhook from DB failed")
+)
+
+var _ model.WebhookRepository = (*webhookRepository)(nil)
+
+type webhookRepository struct {
+	db  *sqlx.DB
+	log log.Logger
+}
+
+// NewWebhookRepository instantiates a PostgreSQL implementation of webhook
+// repository.
+func NewWebhookRepository(db *sqlx.DB, log log.Logger) model.WebhookRepository {
+	return &webhookRepository{db, log}
+}
+
+func (w webhookRepository) Save(ctx context.Context, webhook model.Webhook) (string, error) {
+	q := `INSERT INTO webhooks (id, url, secret, events) VALUES (:id, :url, :secret, :events);`
+	if _, err := w.db.NamedExecContext(ctx, q, webhook); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.NamedExecContext", "err", err)
+		return "", errors.Wrap(ErrSaveWebhookToDB, err)
+	}
+	return webhook.ID, nil
+}
+
+func (w webhookRepository) Remove(ctx context.Context, id string) error {
+	res, err := w.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1;`, id)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.ExecContext", "err", err)
+		return errors.Wrap(ErrSaveWebhookToDB, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (w webhookRepository) RetrieveAll(ctx context.Context) ([]model.Webhook, error) {
+	webhooks := []model.Webhook{}
+	if err := w.db.SelectContext(ctx, &webhooks, `SELECT * FROM webhooks ORDER BY created_at;`); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.SelectContext", "err", err)
+		return webhooks, errors.Wrap(ErrRetrieveWebhookFromDB, err)
+	}
+	return webhooks, nil
+}
+
+func (w webhookRepository) Claim(ctx context.Context, delivery model.Delivery) (bool, error) {
+	q := `INSERT INTO webhook_deliveries (webhook_id, snapshot, status, payload) VALUES (:webhook_id, :snapshot, :status, :payload)
+			ON CONFLICT (webhook_id, snapshot) DO NOTHING;`
+	res, err := w.db.NamedExecContext(ctx, q, delivery)
+	if err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.NamedExecContext", "err", err)
+		return false, errors.Wrap(ErrSaveDeliveryToDB, err)
+	}
+
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (w webhookRepository) Due(ctx context.Context, lease time.Duration, limit uint64) ([]model.Delivery, error) {
+	// SKIP LOCKED leaves the rows another worker is leasing to it
+	q := `UPDATE webhook_deliveries SET next_attempt_at = now() + make_interval(secs => $1)
+			WHERE (webhook_id, snapshot) IN (
+				SELECT webhook_id, snapshot FROM webhook_deliveries
+				WHERE status = $2 AND next_attempt_at <= now()
+				ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+			RETURNING *;`
+	deliveries := []model.Delivery{}
+	if err := w.db.SelectContext(ctx, &deliveries, q, lease.Seconds(), model.DeliveryPending, limit); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.SelectContext", "err", err)
+		return deliveries, errors.Wrap(ErrSaveDeliveryToDB, err)
+	}
+	return deliveries, nil
+}
+
+func (w webhookRepository) UpdateDelivery(ctx context.Context, delivery model.Delivery) error {
+	q := `UPDATE webhook_deliveries SET status = :status, attempts = :attempts, last_status_code = :last_status_code,
+			last_error = :last_error, next_attempt_at = :next_attempt_at, updated_at = now()
+			WHERE webhook_id = :webhook_id AND snapshot = :snapshot;`
+	if _, err := w.db.NamedExecContext(ctx, q, delivery); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.NamedExecContext", "err", err)
+		return errors.Wrap(ErrSaveDeliveryToDB, err)
+	}
+	return nil
+}
+
+func (w webhookRepository) RetrieveDeliveries(ctx context.Context, webhookID string, offset, limit uint64) (model.DeliveryPage, error) {
+	items := []model.Delivery{}
+	// the payload is left to Due, the log never returns it
+	q := `SELECT webhook_id, snapshot, status, attempts, last_status_code, last_error, next_attempt_at, created_at, updated_at
+			FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;`
+	if err := w.db.SelectContext(ctx, &items, q, webhookID, limit, offset); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.SelectContext", "err", err)
+		return model.DeliveryPage{Items: []model.Delivery{}}, errors.Wrap(ErrRetrieveWebhookFromDB, err)
+	}
+
+	var total uint64
+	if err := w.db.GetContext(ctx, &total, `SELECT count(*) FROM webhook_deliveries WHERE webhook_id = $1;`, webhookID); err != nil {
+		level.Error(logging.WithRequestID(ctx, w.log)).Log("method", "w.db.GetContext", "err", err)
+		return model.DeliveryPage{Items: []model.Delivery{}}, errors.Wrap(ErrRetrieveWebhookFromDB, err)
+	}
+
+	return model.DeliveryPage{
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+		Items:  items,
+	}, nil
+}
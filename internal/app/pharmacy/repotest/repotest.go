@@ -0,0 +1,232 @@
+// Package repotest is the conformance suite every model.PharmacyRepository
+// implementation must pass.
+package repotest
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+// AddSnapshot stores pharmacies as the snapshot table named table, the way
+// the snapshot loader does for the repository under test.
+type AddSnapshot func(t *testing.T, table string, pharmacies []model.Pharmacy)
+
+const (
+	olderTable  = "pharmacy_9999_0101"
+	latestTable = "pharmacy_9999_0102"
+	// missingTable is a well-formed snapshot name never added.
+	missingTable = "pharmacy_9999_0103"
+	// otherTable shares the snapshot prefix and sorts after the snapshots,
+	// like pharmacy_locations, but is not a snapshot.
+	otherTable = "pharmacy_zz_other"
+)
+
+// pharmacies lie in Taipei except for the last one, in Kaohsiung. The first
+// two share a 0.05 degree cluster cell.
+var pharmacies = []model.Pharmacy{
+	{Id: "9999000003", Name: "Taipei Main", MaskAdult: 10, MaskChild: 5, Longitude: 121.5170, Latitude: 25.0478},
+	{Id: "9999000001", Name: "Zhongshan", MaskAdult: 20, MaskChild: 1, Longitude: 121.5202, Latitude: 25.0490},
+	{Id: "9999000002", Name: "Xinyi", MaskAdult: 30, MaskChild: 2, Longitude: 121.5645, Latitude: 25.0330},
+	{Id: "9999000004", Name: "Kaohsiung", MaskAdult: 40, MaskChild: 3, Longitude: 120.3014, Latitude: 22.6273},
+}
+
+// Run checks the repository against the behaviour shared by the
+// implementations. add must make the snapshots visible to repo.
+func Run(t *testing.T, repo model.PharmacyRepository, add AddSnapshot) {
+	add(t, olderTable, pharmacies[:1])
+	add(t, latestTable, pharmacies)
+	add(t, otherTable, pharmacies[:1])
+
+	t.Run("GetLatestPharmacyTableName", func(t *testing.T) { testLatest(t, repo) })
+	t.Run("Snapshot", func(t *testing.T) { testSnapshot(t, repo) })
+	t.Run("Query", func(t *testing.T) { testQuery(t, repo) })
+	t.Run("Nearby", func(t *testing.T) { testNearby(t, repo) })
+	t.Run("Cluster", func(t *testing.T) { testCluster(t, repo) })
+	t.Run("Export", func(t *testing.T) { testExport(t, repo) })
+}
+
+func testLatest(t *testing.T, repo model.PharmacyRepository) {
+	table, err := repo.GetLatestPharmacyTableName(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table != latestTable {
+		t.Errorf("got %q, want %q", table, latestTable)
+	}
+}
+
+func testSnapshot(t *testing.T, repo model.PharmacyRepository) {
+	ctx := context.Background()
+
+	items, err := repo.Snapshot(ctx, olderTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Id != pharmacies[0].Id {
+		t.Errorf("got %v, want only %s", ids(items), pharmacies[0].Id)
+	}
+
+	items, err = repo.Snapshot(ctx, latestTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ids(items)
+	sort.Strings(got)
+	if want := []string{"9999000001", "9999000002", "9999000003", "9999000004"}; !equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := repo.Snapshot(ctx, missingTable); err == nil {
+		t.Error("missing snapshot: expected an error")
+	}
+}
+
+func testQuery(t *testing.T, repo model.PharmacyRepository) {
+	ctx := context.Background()
+	center := pharmacies[0]
+
+	items, err := repo.Query(ctx, latestTable, center.Longitude, center.Latitude, 121.4, 121.7, 24.9, 25.2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"9999000003", "9999000001", "9999000002"}; !equal(ids(items), want) {
+		t.Fatalf("got %v, want %v nearest first", ids(items), want)
+	}
+
+	for _, p := range items {
+		want := miles(center, p)
+		if math.Abs(p.Distance-want) > 0.01*want+0.001 {
+			t.Errorf("%s: got distance %f, want %f miles", p.Id, p.Distance, want)
+		}
+	}
+	if items[1].Name != pharmacies[1].Name || items[1].MaskAdult != pharmacies[1].MaskAdult || items[1].MaskChild != pharmacies[1].MaskChild {
+		t.Errorf("got %+v, want the fields of %+v", items[1], pharmacies[1])
+	}
+
+	items, err = repo.Query(ctx, latestTable, center.Longitude, center.Latitude, 121.4, 121.7, 24.9, 25.2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"9999000003", "9999000001"}; !equal(ids(items), want) {
+		t.Errorf("max 2: got %v, want %v", ids(items), want)
+	}
+
+	items, err = repo.Query(ctx, latestTable, 0, 0, 0, 1, 0, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("empty box: got %v", ids(items))
+	}
+}
+
+func testNearby(t *testing.T, repo model.PharmacyRepository) {
+	ctx := context.Background()
+	center := pharmacies[0]
+
+	for _, tc := range []struct {
+		radius float64
+		max    uint64
+		want   []string
+	}{
+		{radius: 1, max: 10, want: []string{"9999000003", "9999000001"}},
+		{radius: 5, max: 10, want: []string{"9999000003", "9999000001", "9999000002"}},
+		{radius: 5, max: 1, want: []string{"9999000003"}},
+	} {
+		items, err := repo.Nearby(ctx, latestTable, center.Longitude, center.Latitude, tc.radius, tc.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal(ids(items), tc.want) {
+			t.Errorf("radius %v, max %d: got %v, want %v nearest first", tc.radius, tc.max, ids(items), tc.want)
+		}
+		for _, p := range items {
+			want := miles(center, p)
+			if math.Abs(p.Distance-want) > 0.01*want+0.001 {
+				t.Errorf("%s: got distance %f, want %f miles", p.Id, p.Distance, want)
+			}
+		}
+	}
+}
+
+func testCluster(t *testing.T, repo model.PharmacyRepository) {
+	clusters, err := repo.Cluster(context.Background(), latestTable, 121.4, 121.7, 24.9, 25.2, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Count > clusters[j].Count })
+
+	want := []model.Cluster{
+		{Longitude: (121.5170 + 121.5202) / 2, Latitude: (25.0478 + 25.0490) / 2, Count: 2, MaskAdult: 30, MaskChild: 6},
+		{Longitude: 121.5645, Latitude: 25.0330, Count: 1, MaskAdult: 30, MaskChild: 2},
+	}
+	if len(clusters) != len(want) {
+		t.Fatalf("got %+v, want %+v", clusters, want)
+	}
+	for i, c := range clusters {
+		w := want[i]
+		if c.Count != w.Count || c.MaskAdult != w.MaskAdult || c.MaskChild != w.MaskChild ||
+			math.Abs(c.Longitude-w.Longitude) > 1e-9 || math.Abs(c.Latitude-w.Latitude) > 1e-9 {
+			t.Errorf("got %+v, want %+v", c, w)
+		}
+	}
+}
+
+func testExport(t *testing.T, repo model.PharmacyRepository) {
+	cursor, err := repo.Export(context.Background(), latestTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cursor.Close()
+
+	var items []model.Pharmacy
+	for cursor.Next() {
+		p, err := cursor.Pharmacy()
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, p)
+	}
+	if err := cursor.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"9999000001", "9999000002", "9999000003", "9999000004"}; !equal(ids(items), want) {
+		t.Errorf("got %v, want %v ordered by id", ids(items), want)
+	}
+}
+
+// miles is the great circle distance between two pharmacies in the statute
+// miles of earthdistance.
+func miles(a, b model.Pharmacy) float64 {
+	const r = 3958.747716
+	rad := math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * rad
+	dLng := (b.Longitude - a.Longitude) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(a.Latitude*rad)*math.Cos(b.Latitude*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * r * math.Asin(math.Sqrt(h))
+}
+
+func ids(items []model.Pharmacy) []string {
+	ids := make([]string, len(items))
+	for i, p := range items {
+		ids[i] = p.Id
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+// Hub specifies an API for fanning out live updates to stream subscribers.
+type Hub interface {
+	// Snapshot announces that next replaced prev as the latest snapshot. prev
+	// is empty for the first snapshot loaded.
+	Snapshot(table string, prev, next []model.Pharmacy)
+
+	// Feedback announces a feedback recorded by the feedback service.
+	Feedback(model.FeedbackEvent)
+
+	// Subscribe returns the events located inside box. The channel is closed
+	// once ctx is done.
+	Subscribe(ctx context.Context, box model.BoundingBox) <-chan model.StreamEvent
+}
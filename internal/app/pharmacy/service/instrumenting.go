@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+type instrumentingMiddleware struct {
+	requestCount   metrics.Counter
+	errorCount     metrics.Counter
+	requestLatency metrics.Histogram
+	snapshotAge    metrics.Gauge
+	next           PharmacyService
+}
+
+// InstrumentingMiddleware returns a service middleware that counts requests
+// and errors and observes the latency of every method. The age of the latest
+// snapshot is refreshed on every TickerUpdate.
+func InstrumentingMiddleware(requestCount, errorCount metrics.Counter, requestLatency metrics.Histogram, snapshotAge metrics.Gauge) Middleware {
+	return func(next PharmacyService) PharmacyService {
+		return instrumentingMiddleware{requestCount, errorCount, requestLatency, snapshotAge, next}
+	}
+}
+
+func (im instrumentingMiddleware) observe(method string, begin time.Time, err error) {
+	im.requestCount.With("method", method).Add(1)
+	im.requestLatency.With("method", method).Observe(time.Since(begin).Seconds())
+	if err != nil {
+		im.errorCount.With("method", method).Add(1)
+	}
+}
+
+func (im instrumentingMiddleware) Query(ctx context.Context, centerLng float64, centerLat float64, neLng float64, neLat float64, seLng float64, seLat float64, swLng float64, swLat float64, nwLng float64, nwLat float64, max uint64) (items []model.Pharmacy, err error) {
+	defer func(begin time.Time) { im.observe("Query", begin, err) }(time.Now())
+	return im.next.Query(ctx, centerLng, centerLat, neLng, neLat, seLng, seLat, swLng, swLat, nwLng, nwLat, max)
+}
+
+func (im instrumentingMiddleware) QueryClusters(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64, zoom uint64) (items []model.Cluster, err error) {
+	defer func(begin time.Time) { im.observe("QueryClusters", begin, err) }(time.Now())
+	return im.next.QueryClusters(ctx, neLng, neLat, swLng, swLat, zoom)
+}
+
+func (im instrumentingMiddleware) Export(ctx context.Context) (cursor model.PharmacyCursor, err error) {
+	defer func(begin time.Time) { im.observe("Export", begin, err) }(time.Now())
+	return im.next.Export(ctx)
+}
+
+func (im instrumentingMiddleware) TickerUpdate(ctx context.Context) (err error) {
+	defer func(begin time.Time) {
+		im.observe("TickerUpdate", begin, err)
+		if age, err := im.next.SnapshotAge(ctx); err == nil {
+			im.snapshotAge.Set(age.Seconds())
+		}
+	}(time.Now())
+	return im.next.TickerUpdate(ctx)
+}
+
+func (im instrumentingMiddleware) SnapshotAge(ctx context.Context) (age time.Duration, err error) {
+	return im.next.SnapshotAge(ctx)
+}
+
+func (im instrumentingMiddleware) CreateWebhook(ctx context.Context, url string, secret string, events []string) (webhook model.Webhook, err error) {
+	defer func(begin time.Time) { im.observe("CreateWebhook", begin, err) }(time.Now())
+	return im.next.CreateWebhook(ctx, url, secret, events)
+}
+
+func (im instrumentingMiddleware) ListWebhooks(ctx context.Context) (items []model.Webhook, err error) {
+	defer func(begin time.Time) { im.observe("ListWebhooks", begin, err) }(time.Now())
+	return im.next.ListWebhooks(ctx)
+}
+
+func (im instrumentingMiddleware) RemoveWebhook(ctx context.Context, id string) (err error) {
+	defer func(begin time.Time) { im.observe("RemoveWebhook", begin, err) }(time.Now())
+	return im.next.RemoveWebhook(ctx, id)
+}
+
+func (im instrumentingMiddleware) WebhookDeliveries(ctx context.Context, id string, offset uint64, limit uint64) (res model.DeliveryPage, err error) {
+	defer func(begin time.Time) { im.observe("WebhookDeliveries", begin, err) }(time.Now())
+	return im.next.WebhookDeliveries(ctx, id, offset, limit)
+}
+
+func (im instrumentingMiddleware) Follow(ctx context.Context, userID string, pharmacyID string, maskAdult uint64, maskChild uint64) (err error) {
+	defer func(begin time.Time) { im.observe("Follow", begin, err) }(time.Now())
+	return im.next.Follow(ctx, userID, pharmacyID, maskAdult, maskChild)
+}
+
+func (im instrumentingMiddleware) Unfollow(ctx context.Context, userID string, pharmacyID string) (err error) {
+	defer func(begin time.Time) { im.observe("Unfollow", begin, err) }(time.Now())
+	return im.next.Unfollow(ctx, userID, pharmacyID)
+}
+
+func (im instrumentingMiddleware) Watches(ctx context.Context, userID string) (items []model.Watch, err error) {
+	defer func(begin time.Time) { im.observe("Watches", begin, err) }(time.Now())
+	return im.next.Watches(ctx, userID)
+}
+
+func (im instrumentingMiddleware) Stream(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64) (events <-chan model.StreamEvent, err error) {
+	defer func(begin time.Time) { im.observe("Stream", begin, err) }(time.Now())
+	return im.next.Stream(ctx, neLng, neLat, swLng, swLat)
+}
@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-kit/kit/log"
 
 	"github.com/cage1016/mask/internal/app/pharmacy/model"
 	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
 )
 
 type loggingMiddleware struct {
@@ -24,16 +27,100 @@ func LoggingMiddleware(logger log.Logger) Middleware {
 
 func (lm loggingMiddleware) TickerUpdate(ctx context.Context) (err error) {
 	defer func() {
-		lm.logger.Log("method", "TickerUpdate", "err", err)
+		logging.WithRequestID(ctx, lm.logger).Log("method", "TickerUpdate", "err", err)
 	}()
 
 	return lm.next.TickerUpdate(ctx)
 }
 
+func (lm loggingMiddleware) SnapshotAge(ctx context.Context) (age time.Duration, err error) {
+	return lm.next.SnapshotAge(ctx)
+}
+
 func (lm loggingMiddleware) Query(ctx context.Context, centerLng float64, centerLat float64, neLng float64, neLat float64, seLng float64, seLat float64, swLng float64, swLat float64, nwLng float64, nwLat float64, max uint64) (items []model.Pharmacy, err error) {
 	defer func() {
-		lm.logger.Log("method", "Query", "centerLng", centerLng, "centerLat", centerLat, "neLng", neLng, "neLat", neLat, "seLng", seLng, "seLat", seLat, "swLng", swLng, "swLat", swLat, "nwLng", nwLng, "nwLat", nwLat, "max", max, "err", err)
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Query", "centerLng", centerLng, "centerLat", centerLat, "neLng", neLng, "neLat", neLat, "seLng", seLng, "seLat", seLat, "swLng", swLng, "swLat", swLat, "nwLng", nwLng, "nwLat", nwLat, "max", max, "err", err)
 	}()
 
 	return lm.next.Query(ctx, centerLng, centerLat, neLng, neLat, seLng, seLat, swLng, swLat, nwLng, nwLat, max)
 }
+
+func (lm loggingMiddleware) QueryClusters(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64, zoom uint64) (items []model.Cluster, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "QueryClusters", "neLng", neLng, "neLat", neLat, "swLng", swLng, "swLat", swLat, "zoom", zoom, "err", err)
+	}()
+
+	return lm.next.QueryClusters(ctx, neLng, neLat, swLng, swLat, zoom)
+}
+
+func (lm loggingMiddleware) Export(ctx context.Context) (cursor model.PharmacyCursor, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Export", "err", err)
+	}()
+
+	return lm.next.Export(ctx)
+}
+
+func (lm loggingMiddleware) CreateWebhook(ctx context.Context, url string, secret string, events []string) (webhook model.Webhook, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "CreateWebhook", "url", url, "events", fmt.Sprint(events), "err", err)
+	}()
+
+	return lm.next.CreateWebhook(ctx, url, secret, events)
+}
+
+func (lm loggingMiddleware) ListWebhooks(ctx context.Context) (items []model.Webhook, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "ListWebhooks", "err", err)
+	}()
+
+	return lm.next.ListWebhooks(ctx)
+}
+
+func (lm loggingMiddleware) RemoveWebhook(ctx context.Context, id string) (err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "RemoveWebhook", "id", id, "err", err)
+	}()
+
+	return lm.next.RemoveWebhook(ctx, id)
+}
+
+func (lm loggingMiddleware) WebhookDeliveries(ctx context.Context, id string, offset uint64, limit uint64) (res model.DeliveryPage, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "WebhookDeliveries", "id", id, "offset", offset, "limit", limit, "err", err)
+	}()
+
+	return lm.next.WebhookDeliveries(ctx, id, offset, limit)
+}
+
+func (lm loggingMiddleware) Follow(ctx context.Context, userID string, pharmacyID string, maskAdult uint64, maskChild uint64) (err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Follow", "userID", userID, "pharmacyID", pharmacyID, "maskAdult", maskAdult, "maskChild", maskChild, "err", err)
+	}()
+
+	return lm.next.Follow(ctx, userID, pharmacyID, maskAdult, maskChild)
+}
+
+func (lm loggingMiddleware) Unfollow(ctx context.Context, userID string, pharmacyID string) (err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Unfollow", "userID", userID, "pharmacyID", pharmacyID, "err", err)
+	}()
+
+	return lm.next.Unfollow(ctx, userID, pharmacyID)
+}
+
+func (lm loggingMiddleware) Watches(ctx context.Context, userID string) (items []model.Watch, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Watches", "userID", userID, "err", err)
+	}()
+
+	return lm.next.Watches(ctx, userID)
+}
+
+func (lm loggingMiddleware) Stream(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64) (events <-chan model.StreamEvent, err error) {
+	defer func() {
+		logging.WithRequestID(ctx, lm.logger).Log("method", "Stream", "neLng", neLng, "neLat", neLat, "swLng", swLng, "swLat", swLat, "err", err)
+	}()
+
+	return lm.next.Stream(ctx, neLng, neLat, swLng, swLat)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/memory"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+type nopHub struct{ Hub }
+
+func (nopHub) Snapshot(string, []model.Pharmacy, []model.Pharmacy) {}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, string, []model.StockEvent) error { return nil }
+
+type flakySender struct {
+	err  error
+	sent []model.Notification
+}
+
+func (s *flakySender) Send(_ context.Context, notifications []model.Notification) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, notifications...)
+	return nil
+}
+
+func TestNotificationsSurviveFailedSend(t *testing.T) {
+	defer func(lease time.Duration) { notificationLease = lease }(notificationLease)
+	notificationLease = 0
+
+	ctx := context.Background()
+	repo := memory.New()
+	repo.AddSnapshot("pharmacy_0322_1030", []model.Pharmacy{{Id: "5901012345", MaskAdult: 0}})
+	watches := memory.NewWatchRepository()
+	if err := watches.Save(ctx, model.Watch{UserID: "u1", PharmacyID: "5901012345", MaskAdult: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &flakySender{err: errors.New("push gateway down")}
+	svc := &stubPharmacyService{repo: repo, watches: watches, publisher: nopPublisher{}, sender: sender, hub: nopHub{}, logger: log.NewNopLogger()}
+	if err := svc.TickerUpdate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.AddSnapshot("pharmacy_0322_1031", []model.Pharmacy{{Id: "5901012345", MaskAdult: 20}})
+	if err := svc.TickerUpdate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d notifications through a failing sender", len(sender.sent))
+	}
+
+	sender.err = nil
+	if err := svc.TickerUpdate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].UserID != "u1" || sender.sent[0].Snapshot != "pharmacy_0322_1031" {
+		t.Fatalf("sent = %+v, want the notification of u1 for pharmacy_0322_1031", sender.sent)
+	}
+
+	if err := svc.TickerUpdate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("sent %d notifications, want the delivered one sent once", len(sender.sent))
+	}
+}
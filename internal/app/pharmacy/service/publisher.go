@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+// EventPublisher specifies an API for delivering the stock events of a new
+// snapshot to subscribers.
+type EventPublisher interface {
+	// Publish hands events over for delivery. It must not block on slow
+	// subscribers.
+	Publish(ctx context.Context, snapshot string, events []model.StockEvent) error
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+// NotificationSender specifies an API for delivering restock notifications
+// to users, e.g. through push messages, e-mail or a local log.
+type NotificationSender interface {
+	// Send delivers the notifications.
+	Send(ctx context.Context, notifications []model.Notification) error
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"time"
+
 	"github.com/cage1016/mask/internal/app/pharmacy/model"
 	"github.com/cage1016/mask/internal/pkg/errors"
 	"github.com/cage1016/mask/internal/pkg/level"
@@ -12,10 +14,30 @@ var (
 	ErrInvalidTask     = errors.New("Bad Request - Invalid Task")
 	ErrTaskCreatFailed = errors.New("task create failed")
 	ErrMalformedEntity = errors.New("malformed entity specification")
+
+	// ErrSnapshotNotLoaded indicates no snapshot has been loaded yet.
+	ErrSnapshotNotLoaded = errors.New("snapshot not loaded")
 )
 
 const newLayout = "15:04"
 
+const (
+	// ClusterMaxZoom is the deepest zoom level at which pharmacies are still
+	// returned as clusters instead of individual stores.
+	ClusterMaxZoom = 13
+
+	// clusterCellsPerTile is how many grid cells a map tile is divided into
+	// along each axis when clustering.
+	clusterCellsPerTile = 4
+
+	// notificationBatch is how many notifications are sent at once.
+	notificationBatch = 100
+)
+
+// notificationLease is how long a notification handed to the sender is held
+// back from other instances before it is sent again.
+var notificationLease = time.Minute
+
 // Middleware describes a service (as opposed to endpoint) middleware.
 type Middleware func(PharmacyService) PharmacyService
 
@@ -26,22 +48,52 @@ type PharmacyService interface {
 	// [method=post,expose=true,router=api/pharmacies]
 	Query(ctx context.Context, centerLng float64, centerLat float64, neLng float64, neLat float64, seLng float64, seLat float64, swLng float64, swLat float64, nwLng float64, nwLat float64, max uint64) (items []model.Pharmacy, err error)
 	// [expose=false]
+	QueryClusters(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64, zoom uint64) (items []model.Cluster, err error)
+	// [method=get,expose=true,router=api/pharmacies/export]
+	Export(ctx context.Context) (cursor model.PharmacyCursor, err error)
+	// [expose=false]
 	TickerUpdate(ctx context.Context) (err error)
+	// [expose=false]
+	SnapshotAge(ctx context.Context) (age time.Duration, err error)
+	// [method=post,expose=true,router=api/pharmacies/webhooks]
+	CreateWebhook(ctx context.Context, url string, secret string, events []string) (webhook model.Webhook, err error)
+	// [method=get,expose=true,router=api/pharmacies/webhooks]
+	ListWebhooks(ctx context.Context) (items []model.Webhook, err error)
+	// [method=delete,expose=true,router=api/pharmacies/webhooks/:id]
+	RemoveWebhook(ctx context.Context, id string) (err error)
+	// [method=get,expose=true,router=api/pharmacies/webhooks/:id/deliveries]
+	WebhookDeliveries(ctx context.Context, id string, offset uint64, limit uint64) (res model.DeliveryPage, err error)
+	// [method=post,expose=true,router=api/pharmacies/watches]
+	Follow(ctx context.Context, userID string, pharmacyID string, maskAdult uint64, maskChild uint64) (err error)
+	// [method=delete,expose=true,router=api/pharmacies/watches/:user_id/:pharmacy_id]
+	Unfollow(ctx context.Context, userID string, pharmacyID string) (err error)
+	// [method=get,expose=true,router=api/pharmacies/watches/:user_id]
+	Watches(ctx context.Context, userID string) (items []model.Watch, err error)
+	// [method=get,expose=true,router=api/pharmacies/stream]
+	Stream(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64) (events <-chan model.StreamEvent, err error)
 }
 
 // the concrete implementation of service interface
 type stubPharmacyService struct {
-	logger              log.Logger
-	repo                model.PharmacyRepository
-	latestPharmacyTable string
+	logger               log.Logger
+	repo                 model.PharmacyRepository
+	webhooks             model.WebhookRepository
+	watches              model.WatchRepository
+	idpNano              NanoIdentityProvider
+	publisher            EventPublisher
+	sender               NotificationSender
+	hub                  Hub
+	latestPharmacyTable  string
+	handledPharmacyTable string
+	snapshotUpdatedAt    time.Time
 }
 
 // New return a new instance of the service.
 // If you want to add service middleware this is the place to put them.
-func New(repo model.PharmacyRepository, logger log.Logger) (s PharmacyService) {
+func New(repo model.PharmacyRepository, webhooks model.WebhookRepository, watches model.WatchRepository, idpNano NanoIdentityProvider, publisher EventPublisher, sender NotificationSender, hub Hub, logger log.Logger) (s PharmacyService) {
 	var svc PharmacyService
 	{
-		svc = &stubPharmacyService{repo: repo, logger: logger}
+		svc = &stubPharmacyService{repo: repo, webhooks: webhooks, watches: watches, idpNano: idpNano, publisher: publisher, sender: sender, hub: hub, logger: logger}
 		svc = LoggingMiddleware(logger)(svc)
 	}
 	return svc
@@ -49,7 +101,160 @@ func New(repo model.PharmacyRepository, logger log.Logger) (s PharmacyService) {
 
 // Implement the business logic of TickerUpdate
 func (as *stubPharmacyService) TickerUpdate(ctx context.Context) (err error) {
-	return as._GetLatestPharmacyTableName(ctx)
+	if err := as._GetLatestPharmacyTableName(ctx); err != nil {
+		return err
+	}
+
+	next := as.latestPharmacyTable
+	if next != "" && next != as.handledPharmacyTable {
+		if err := as.snapshotChanged(ctx, as.handledPharmacyTable, next); err != nil {
+			return err
+		}
+		as.handledPharmacyTable = next
+	}
+	return as.deliverNotifications(ctx)
+}
+
+// snapshotChanged loads the previous and the new snapshot and reacts to the
+// stock changes between them. prevTable is empty for the first snapshot seen
+// by this instance, which is only handed to the hub.
+func (as *stubPharmacyService) snapshotChanged(ctx context.Context, prevTable, nextTable string) error {
+	next, err := as.repo.Snapshot(ctx, nextTable)
+	if err != nil {
+		return err
+	}
+	as.snapshotUpdatedAt = latestUpdate(next)
+
+	if prevTable == "" {
+		as.hub.Snapshot(nextTable, nil, next)
+		return nil
+	}
+
+	prev, err := as.repo.Snapshot(ctx, prevTable)
+	if err != nil {
+		return err
+	}
+
+	as.hub.Snapshot(nextTable, prev, next)
+	if err := as.publishStockEvents(ctx, nextTable, prev, next); err != nil {
+		level.Error(as.logger).Log("method", "as.publishStockEvents", "err", err)
+	}
+	return as.notifyWatchers(ctx, nextTable, prev, next)
+}
+
+// latestUpdate returns the most recent update time reported for any pharmacy
+// of a snapshot.
+func latestUpdate(pharmacies []model.Pharmacy) (t time.Time) {
+	for _, p := range pharmacies {
+		if p.Updated != nil && p.Updated.Valid && p.Updated.Time.After(t) {
+			t = p.Updated.Time
+		}
+	}
+	return t
+}
+
+// Implement the business logic of SnapshotAge
+func (as *stubPharmacyService) SnapshotAge(ctx context.Context) (age time.Duration, err error) {
+	if as.snapshotUpdatedAt.IsZero() {
+		return 0, ErrSnapshotNotLoaded
+	}
+	return time.Since(as.snapshotUpdatedAt), nil
+}
+
+// publishStockEvents diffs two snapshots and hands the resulting events to
+// the publisher.
+func (as *stubPharmacyService) publishStockEvents(ctx context.Context, nextTable string, prev, next []model.Pharmacy) error {
+	events := model.Diff(prev, next)
+	as.logger.Log("snapshot", nextTable, "events", len(events))
+	if len(events) == 0 {
+		return nil
+	}
+	return as.publisher.Publish(ctx, nextTable, events)
+}
+
+// notifyWatchers claims a notification for every user whose watch threshold
+// was crossed between the two snapshots. The claimed notifications stay
+// pending until deliverNotifications sends them.
+func (as *stubPharmacyService) notifyWatchers(ctx context.Context, nextTable string, prev, next []model.Pharmacy) error {
+	old := make(map[string]model.Pharmacy, len(prev))
+	for _, p := range prev {
+		old[p.Id] = p
+	}
+
+	changed := map[string]model.Pharmacy{}
+	ids := []string{}
+	for _, p := range next {
+		o := old[p.Id]
+		if p.MaskAdult > o.MaskAdult || p.MaskChild > o.MaskChild {
+			changed[p.Id] = p
+			ids = append(ids, p.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	watches, err := as.watches.RetrieveByPharmacyIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	notifications := 0
+	for _, w := range watches {
+		p := changed[w.PharmacyID]
+		if !w.Triggered(old[w.PharmacyID], p) {
+			continue
+		}
+
+		n := model.Notification{
+			UserID:     w.UserID,
+			PharmacyID: p.Id,
+			Name:       p.Name,
+			Address:    p.Address,
+			MaskAdult:  p.MaskAdult,
+			MaskChild:  p.MaskChild,
+			Longitude:  p.Longitude,
+			Latitude:   p.Latitude,
+			Snapshot:   nextTable,
+		}
+		claimed, err := as.watches.Claim(ctx, n)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			notifications++
+		}
+	}
+
+	as.logger.Log("snapshot", nextTable, "notifications", notifications)
+	return nil
+}
+
+// deliverNotifications sends the pending notifications. A batch the sender
+// fails on stays pending and is sent again once its lease expires, so a
+// notification may be sent more than once but is never lost.
+func (as *stubPharmacyService) deliverNotifications(ctx context.Context) error {
+	for {
+		notifications, err := as.watches.Due(ctx, notificationLease, notificationBatch)
+		if err != nil {
+			return err
+		}
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		if err := as.sender.Send(ctx, notifications); err != nil {
+			level.Warn(as.logger).Log("method", "as.sender.Send", "notifications", len(notifications), "err", err)
+			return nil
+		}
+		if err := as.watches.Delivered(ctx, notifications); err != nil {
+			return err
+		}
+
+		if len(notifications) < notificationBatch {
+			return nil
+		}
+	}
 }
 
 func (as *stubPharmacyService) _GetLatestPharmacyTableName(ctx context.Context) (err error) {
@@ -74,3 +279,101 @@ func (st *stubPharmacyService) Query(ctx context.Context, centerLng float64, cen
 
 	return st.repo.Query(ctx, st.latestPharmacyTable, centerLng, centerLat, swLng, neLng, swLat, neLat, max)
 }
+
+// Implement the business logic of QueryClusters
+func (st *stubPharmacyService) QueryClusters(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64, zoom uint64) (items []model.Cluster, err error) {
+	if zoom > ClusterMaxZoom {
+		return []model.Cluster{}, ErrMalformedEntity
+	}
+
+	if st.latestPharmacyTable == "" {
+		err := st._GetLatestPharmacyTableName(ctx)
+		if err != nil {
+			return []model.Cluster{}, err
+		}
+	}
+
+	return st.repo.Cluster(ctx, st.latestPharmacyTable, swLng, neLng, swLat, neLat, gridSize(zoom))
+}
+
+// gridSize returns the cluster cell size in degrees for a web mercator zoom
+// level, where a single tile spans 360 / 2^zoom degrees of longitude.
+func gridSize(zoom uint64) float64 {
+	return 360 / float64(uint64(1)<<zoom) / clusterCellsPerTile
+}
+
+// Implement the business logic of Export
+func (st *stubPharmacyService) Export(ctx context.Context) (cursor model.PharmacyCursor, err error) {
+	if st.latestPharmacyTable == "" {
+		err := st._GetLatestPharmacyTableName(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return st.repo.Export(ctx, st.latestPharmacyTable)
+}
+
+// Implement the business logic of CreateWebhook
+func (st *stubPharmacyService) CreateWebhook(ctx context.Context, url string, secret string, events []string) (webhook model.Webhook, err error) {
+	id, err := st.idpNano.ID()
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	if secret == "" {
+		if secret, err = st.idpNano.ID(); err != nil {
+			return model.Webhook{}, err
+		}
+	}
+
+	webhook = model.Webhook{ID: id, URL: url, Secret: secret, Events: events}
+	if _, err := st.webhooks.Save(ctx, webhook); err != nil {
+		return model.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// Implement the business logic of ListWebhooks
+func (st *stubPharmacyService) ListWebhooks(ctx context.Context) (items []model.Webhook, err error) {
+	items, err = st.webhooks.RetrieveAll(ctx)
+	for i := range items {
+		items[i].Secret = ""
+	}
+	return items, err
+}
+
+// Implement the business logic of RemoveWebhook
+func (st *stubPharmacyService) RemoveWebhook(ctx context.Context, id string) (err error) {
+	return st.webhooks.Remove(ctx, id)
+}
+
+// Implement the business logic of WebhookDeliveries
+func (st *stubPharmacyService) WebhookDeliveries(ctx context.Context, id string, offset uint64, limit uint64) (res model.DeliveryPage, err error) {
+	return st.webhooks.RetrieveDeliveries(ctx, id, offset, limit)
+}
+
+// Implement the business logic of Follow
+func (st *stubPharmacyService) Follow(ctx context.Context, userID string, pharmacyID string, maskAdult uint64, maskChild uint64) (err error) {
+	return st.watches.Save(ctx, model.Watch{
+		UserID:     userID,
+		PharmacyID: pharmacyID,
+		MaskAdult:  maskAdult,
+		MaskChild:  maskChild,
+	})
+}
+
+// Implement the business logic of Unfollow
+func (st *stubPharmacyService) Unfollow(ctx context.Context, userID string, pharmacyID string) (err error) {
+	return st.watches.Remove(ctx, userID, pharmacyID)
+}
+
+// Implement the business logic of Watches
+func (st *stubPharmacyService) Watches(ctx context.Context, userID string) (items []model.Watch, err error) {
+	return st.watches.RetrieveByUserID(ctx, userID)
+}
+
+// Implement the business logic of Stream
+func (st *stubPharmacyService) Stream(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64) (events <-chan model.StreamEvent, err error) {
+	return st.hub.Subscribe(ctx, model.BoundingBox{SwLng: swLng, SwLat: swLat, NeLng: neLng, NeLat: neLat}), nil
+}
@@ -0,0 +1,19 @@
+package service
+
+import "testing"
+
+func TestGridSize(t *testing.T) {
+	for _, tc := range []struct {
+		zoom uint64
+		want float64
+	}{
+		{zoom: 0, want: 90},
+		{zoom: 1, want: 45},
+		{zoom: 4, want: 5.625},
+		{zoom: ClusterMaxZoom, want: 360.0 / 8192 / 4},
+	} {
+		if got := gridSize(tc.zoom); got != tc.want {
+			t.Errorf("gridSize(%d) = %v, want %v", tc.zoom, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/tracing"
+)
+
+type tracingMiddleware struct {
+	tracer trace.Tracer
+	next   PharmacyService
+}
+
+// TracingMiddleware returns a service middleware that wraps every method in a
+// span, child of the endpoint span carried by ctx.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next PharmacyService) PharmacyService {
+		return tracingMiddleware{tracer, next}
+	}
+}
+
+func (tm tracingMiddleware) Query(ctx context.Context, centerLng float64, centerLat float64, neLng float64, neLat float64, seLng float64, seLat float64, swLng float64, swLat float64, nwLng float64, nwLat float64, max uint64) (items []model.Pharmacy, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Query")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Query(ctx, centerLng, centerLat, neLng, neLat, seLng, seLat, swLng, swLat, nwLng, nwLat, max)
+}
+
+func (tm tracingMiddleware) QueryClusters(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64, zoom uint64) (items []model.Cluster, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.QueryClusters")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.QueryClusters(ctx, neLng, neLat, swLng, swLat, zoom)
+}
+
+func (tm tracingMiddleware) Export(ctx context.Context) (cursor model.PharmacyCursor, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Export")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Export(ctx)
+}
+
+func (tm tracingMiddleware) TickerUpdate(ctx context.Context) (err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.TickerUpdate")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.TickerUpdate(ctx)
+}
+
+func (tm tracingMiddleware) SnapshotAge(ctx context.Context) (age time.Duration, err error) {
+	return tm.next.SnapshotAge(ctx)
+}
+
+func (tm tracingMiddleware) CreateWebhook(ctx context.Context, url string, secret string, events []string) (webhook model.Webhook, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.CreateWebhook(ctx, url, secret, events)
+}
+
+func (tm tracingMiddleware) ListWebhooks(ctx context.Context) (items []model.Webhook, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.ListWebhooks")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.ListWebhooks(ctx)
+}
+
+func (tm tracingMiddleware) RemoveWebhook(ctx context.Context, id string) (err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.RemoveWebhook")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.RemoveWebhook(ctx, id)
+}
+
+func (tm tracingMiddleware) WebhookDeliveries(ctx context.Context, id string, offset uint64, limit uint64) (res model.DeliveryPage, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.WebhookDeliveries")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.WebhookDeliveries(ctx, id, offset, limit)
+}
+
+func (tm tracingMiddleware) Follow(ctx context.Context, userID string, pharmacyID string, maskAdult uint64, maskChild uint64) (err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Follow")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Follow(ctx, userID, pharmacyID, maskAdult, maskChild)
+}
+
+func (tm tracingMiddleware) Unfollow(ctx context.Context, userID string, pharmacyID string) (err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Unfollow")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Unfollow(ctx, userID, pharmacyID)
+}
+
+func (tm tracingMiddleware) Watches(ctx context.Context, userID string) (items []model.Watch, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Watches")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Watches(ctx, userID)
+}
+
+func (tm tracingMiddleware) Stream(ctx context.Context, neLng float64, neLat float64, swLng float64, swLat float64) (events <-chan model.StreamEvent, err error) {
+	ctx, span := tm.tracer.Start(ctx, "service.Stream")
+	defer func() { tracing.End(span, err) }()
+	return tm.next.Stream(ctx, neLng, neLat, swLng, swLat)
+}
@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+)
+
+// bufferSize is the number of events a slow subscriber may lag behind before
+// further events are dropped for it.
+const bufferSize = 16
+
+var _ service.Hub = (*hub)(nil)
+
+type subscriber struct {
+	box model.BoundingBox
+	ch  chan model.StreamEvent
+}
+
+type hub struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	locations   map[string]model.Pharmacy
+}
+
+// New instantiates an in-process hub.
+func New() service.Hub {
+	return &hub{
+		subscribers: map[*subscriber]struct{}{},
+		locations:   map[string]model.Pharmacy{},
+	}
+}
+
+func (h *hub) Snapshot(table string, prev, next []model.Pharmacy) {
+	old := make(map[string]model.Pharmacy, len(prev))
+	for _, p := range prev {
+		old[p.Id] = p
+	}
+
+	locations := make(map[string]model.Pharmacy, len(next))
+	changed := []model.Pharmacy{}
+	for _, p := range next {
+		locations[p.Id] = model.Pharmacy{Id: p.Id, Longitude: p.Longitude, Latitude: p.Latitude}
+		if o, ok := old[p.Id]; !ok || o.MaskAdult != p.MaskAdult || o.MaskChild != p.MaskChild {
+			changed = append(changed, p)
+		}
+	}
+
+	h.mu.Lock()
+	h.locations = locations
+	h.mu.Unlock()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subscribers {
+		var items []model.Pharmacy
+		for _, p := range changed {
+			if s.box.Contains(p.Longitude, p.Latitude) {
+				items = append(items, p)
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		send(s, model.StreamEvent{Type: model.StreamStock, Snapshot: table, Pharmacies: items})
+	}
+}
+
+func (h *hub) Feedback(f model.FeedbackEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	p, ok := h.locations[f.PharmacyID]
+	if !ok {
+		return
+	}
+
+	for s := range h.subscribers {
+		if s.box.Contains(p.Longitude, p.Latitude) {
+			send(s, model.StreamEvent{Type: model.StreamFeedback, Feedback: &f})
+		}
+	}
+}
+
+func (h *hub) Subscribe(ctx context.Context, box model.BoundingBox) <-chan model.StreamEvent {
+	s := &subscriber{box: box, ch: make(chan model.StreamEvent, bufferSize)}
+
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subscribers, s)
+		close(s.ch)
+		h.mu.Unlock()
+	}()
+
+	return s.ch
+}
+
+// send never blocks, events are dropped for subscribers that fall behind.
+func send(s *subscriber, e model.StreamEvent) {
+	select {
+	case s.ch <- e:
+	default:
+	}
+}
@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+var (
+	taipei    = model.BoundingBox{SwLng: 121.4, SwLat: 25.0, NeLng: 121.6, NeLat: 25.2}
+	inTaipei  = model.Pharmacy{Id: "5901012345", Longitude: 121.5, Latitude: 25.1, MaskAdult: 10}
+	elsewhere = model.Pharmacy{Id: "5902054321", Longitude: 120.3, Latitude: 22.6, MaskAdult: 10}
+)
+
+// receive returns the events on ch until none arrives for a while.
+func receive(ch <-chan model.StreamEvent) []model.StreamEvent {
+	var events []model.StreamEvent
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		case <-time.After(20 * time.Millisecond):
+			return events
+		}
+	}
+}
+
+func TestHubSnapshot(t *testing.T) {
+	restocked := inTaipei
+	restocked.MaskChild = 5
+
+	cases := []struct {
+		desc       string
+		prev, next []model.Pharmacy
+		want       []string
+	}{
+		{"listed in the box", nil, []model.Pharmacy{inTaipei, elsewhere}, []string{inTaipei.Id}},
+		{"stock changed in the box", []model.Pharmacy{inTaipei}, []model.Pharmacy{restocked}, []string{inTaipei.Id}},
+		{"unchanged", []model.Pharmacy{inTaipei, elsewhere}, []model.Pharmacy{inTaipei, elsewhere}, nil},
+		{"changed outside the box", nil, []model.Pharmacy{elsewhere}, nil},
+	}
+
+	for _, c := range cases {
+		ctx, cancel := context.WithCancel(context.Background())
+		h := New()
+		ch := h.Subscribe(ctx, taipei)
+
+		h.Snapshot("pharmacy_0322_1030", c.prev, c.next)
+		var got []string
+		for _, e := range receive(ch) {
+			if e.Type != model.StreamStock || e.Snapshot != "pharmacy_0322_1030" {
+				t.Errorf("%s: got event %+v", c.desc, e)
+			}
+			for _, p := range e.Pharmacies {
+				got = append(got, p.Id)
+			}
+		}
+		if len(got) != len(c.want) || (len(got) > 0 && got[0] != c.want[0]) {
+			t.Errorf("%s: got pharmacies %v, want %v", c.desc, got, c.want)
+		}
+		cancel()
+	}
+}
+
+func TestHubFeedback(t *testing.T) {
+	cases := []struct {
+		desc       string
+		pharmacyID string
+		want       int
+	}{
+		{"in the box", inTaipei.Id, 1},
+		{"outside the box", elsewhere.Id, 0},
+		{"unknown pharmacy", "0000000000", 0},
+	}
+
+	h := New()
+	h.Snapshot("pharmacy_0322_1030", nil, []model.Pharmacy{inTaipei, elsewhere})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := h.Subscribe(ctx, taipei)
+
+	for _, c := range cases {
+		h.Feedback(model.FeedbackEvent{PharmacyID: c.pharmacyID})
+		events := receive(ch)
+		if len(events) != c.want {
+			t.Errorf("%s: got %d events, want %d", c.desc, len(events), c.want)
+			continue
+		}
+		for _, e := range events {
+			if e.Type != model.StreamFeedback || e.Feedback == nil || e.Feedback.PharmacyID != c.pharmacyID {
+				t.Errorf("%s: got event %+v", c.desc, e)
+			}
+		}
+	}
+}
+
+func TestHubDropsEventsOfSlowSubscribers(t *testing.T) {
+	h := New()
+	h.Snapshot("pharmacy_0322_1030", nil, []model.Pharmacy{inTaipei})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := h.Subscribe(ctx, taipei)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*bufferSize; i++ {
+			h.Feedback(model.FeedbackEvent{PharmacyID: inTaipei.Id})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Feedback blocked on a subscriber that does not read")
+	}
+
+	if n := len(receive(ch)); n != bufferSize {
+		t.Errorf("got %d events, want the %d buffered", n, bufferSize)
+	}
+}
+
+func TestHubUnsubscribesOnCancel(t *testing.T) {
+	h := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := h.Subscribe(ctx, taipei)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("got an event, want the channel closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel still open after cancel")
+	}
+
+	// a snapshot after the subscriber left must not send on the closed channel
+	h.Snapshot("pharmacy_0322_1030", nil, []model.Pharmacy{inTaipei})
+	if n := len(h.(*hub).subscribers); n != 0 {
+		t.Errorf("got %d subscribers, want 0", n)
+	}
+}
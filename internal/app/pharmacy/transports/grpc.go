@@ -0,0 +1,148 @@
+package transports
+
+import (
+	"context"
+	"time"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/log"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/util"
+	pb "github.com/cage1016/mask/pb/pharmacy"
+)
+
+type grpcServer struct {
+	pb.UnimplementedPharmacyServiceServer
+
+	query grpctransport.Handler
+}
+
+// NewGRPCServer makes a set of endpoints available as a gRPC PharmacyService.
+func NewGRPCServer(endpoints endpoints.Endpoints, logger log.Logger) pb.PharmacyServiceServer {
+	options := []grpctransport.ServerOption{
+		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(kitjwt.GRPCToContext()),
+	}
+
+	return &grpcServer{
+		query: grpctransport.NewServer(
+			endpoints.QueryEndpoint,
+			decodeGRPCQueryRequest,
+			encodeGRPCQueryResponse,
+			options...,
+		),
+	}
+}
+
+func (s *grpcServer) Query(ctx context.Context, req *pb.QueryRequest) (rep *pb.QueryReply, err error) {
+	_, rp, err := s.query.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcEncodeError(err)
+	}
+	rep = rp.(*pb.QueryReply)
+	return rep, nil
+}
+
+// decodeGRPCQueryRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC request to a user-domain request. Primarily useful in a server.
+func decodeGRPCQueryRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.QueryRequest)
+	b := req.GetBounds()
+	return endpoints.QueryRequest{
+		Center: endpoints.LatLng{Lat: req.GetCenter().GetLat(), Lng: req.GetCenter().GetLng()},
+		Bounds: endpoints.Bounds{
+			Ne: endpoints.LatLng{Lat: b.GetNe().GetLat(), Lng: b.GetNe().GetLng()},
+			Se: endpoints.LatLng{Lat: b.GetSe().GetLat(), Lng: b.GetSe().GetLng()},
+			Sw: endpoints.LatLng{Lat: b.GetSw().GetLat(), Lng: b.GetSw().GetLng()},
+			Nw: endpoints.LatLng{Lat: b.GetNw().GetLat(), Lng: b.GetNw().GetLng()},
+		},
+		Max:  req.GetMax(),
+		Zoom: req.GetZoom(),
+	}, nil
+}
+
+// encodeGRPCQueryResponse is a transport/grpc.EncodeResponseFunc that converts a
+// user-domain response to a gRPC reply. Primarily useful in a server.
+func encodeGRPCQueryResponse(_ context.Context, grpcReply interface{}) (res interface{}, err error) {
+	reply := grpcReply.(endpoints.QueryResponse)
+
+	rep := &pb.QueryReply{
+		Items:    make([]*pb.Pharmacy, 0, len(reply.Items)),
+		Clusters: make([]*pb.Cluster, 0, len(reply.Clusters)),
+	}
+	for _, p := range reply.Items {
+		rep.Items = append(rep.Items, toPBPharmacy(p))
+	}
+	for _, c := range reply.Clusters {
+		rep.Clusters = append(rep.Clusters, &pb.Cluster{
+			Longitude: c.Longitude,
+			Latitude:  c.Latitude,
+			Count:     c.Count,
+			MaskAdult: c.MaskAdult,
+			MaskChild: c.MaskChild,
+		})
+	}
+	return rep, nil
+}
+
+func toPBPharmacy(p model.Pharmacy) *pb.Pharmacy {
+	var updated string
+	if p.Updated != nil && p.Updated.Valid {
+		updated = p.Updated.Time.In(util.Location).Format(time.RFC3339)
+	}
+
+	return &pb.Pharmacy{
+		Id:             p.Id,
+		Distance:       p.Distance,
+		Name:           p.Name,
+		Phone:          p.Phone,
+		Address:        p.Address,
+		MaskAdult:      p.MaskAdult,
+		MaskChild:      p.MaskChild,
+		Updated:        updated,
+		Available:      p.Available,
+		CustomNote:     p.CustomNote,
+		Website:        p.Website,
+		Note:           p.Note,
+		Longitude:      p.Longitude,
+		Latitude:       p.Latitude,
+		ServicePeriods: p.ServicePeriods,
+		ServiceNote:    p.ServiceNote,
+		County:         p.County,
+		Town:           p.Town,
+		Cunli:          p.Cunli,
+	}
+}
+
+// grpcEncodeError maps service errors to gRPC status codes, the way
+// httpEncodeError maps them to HTTP ones.
+func grpcEncodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	code := codes.Internal
+	if errorVal, ok := err.(errors.Error); ok {
+		switch {
+		case errors.Contains(errorVal, service.ErrMalformedEntity),
+			errors.Contains(errorVal, service.ErrInvalidTask),
+			errors.Contains(errorVal, service.ErrTaskCreatFailed):
+			code = codes.InvalidArgument
+		case errors.Contains(errorVal, model.ErrWebhookNotFound),
+			errors.Contains(errorVal, model.ErrWatchNotFound):
+			code = codes.NotFound
+		}
+	}
+
+	if err == kitjwt.ErrTokenContextMissing || err == kitjwt.ErrTokenInvalid {
+		code = codes.Unauthenticated
+	}
+	return status.Error(code, err.Error())
+}
@@ -3,63 +3,211 @@ package transports
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	kitjwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-zoo/bone"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 
 	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
 	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/auth"
 	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/export"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/openapi"
 	"github.com/cage1016/mask/internal/pkg/responses"
+	"github.com/cage1016/mask/internal/pkg/tracing"
 )
 
 const (
-	contentType string = "application/json"
+	defOffset = 0
+	defLimit  = 10
+
+	contentType        string = "application/json"
+	geoJSONContentType string = "application/geo+json"
+
+	formatGeoJSON = "geojson"
+
+	// heartbeatInterval keeps idle event streams from being cut by proxies.
+	heartbeatInterval = 25 * time.Second
+)
+
+type contextKey int
+
+const (
+	contextKeyFormat contextKey = iota
 )
 
-// ShowPharmacy godoc
-// @Summary
-// @Description The endpoint for Mask to fetch pharmacies
-// @Tags pharmacy
-// @Accept json
-// @Produce json
-// @Param query body endpoints.QueryRequest true "Fetch Pharmacies"
-// @Success 200 {object} endpoints.QueryResponse
-// @Failure 400 {object} responses.ErrorRes
-// @Failure 500 {object} responses.ErrorRes
-// @Router /api/pharmacies [post]
+// QueryHandler routes POST /api/pharmacies, also under /api/v2.
 func QueryHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
-	m.Post("/api/pharmacies", httptransport.NewServer(
+	h := httptransport.NewServer(
 		endpoints.QueryEndpoint,
 		decodeHTTPQueryRequest,
 		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext(), formatToContext))...,
+	)
+	m.Post("/api/pharmacies", h)
+	m.Post("/api/v2/pharmacies", h)
+
+}
+
+// ExportHandler routes GET /api/pharmacies/export, also under /api/v2.
+func ExportHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.ExportEndpoint,
+		decodeHTTPExportRequest,
+		encodeExportResponse(logger),
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Get("/api/pharmacies/export", h)
+	m.Get("/api/v2/pharmacies/export", h)
+}
+
+// CreateWebhookHandler routes POST /api/pharmacies/webhooks, also under /api/v2.
+func CreateWebhookHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.CreateWebhookEndpoint,
+		decodeHTTPCreateWebhookRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Post("/api/pharmacies/webhooks", h)
+	m.Post("/api/v2/pharmacies/webhooks", h)
+}
+
+// ListWebhooksHandler routes GET /api/pharmacies/webhooks, also under /api/v2.
+func ListWebhooksHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.ListWebhooksEndpoint,
+		decodeHTTPListWebhooksRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Get("/api/pharmacies/webhooks", h)
+	m.Get("/api/v2/pharmacies/webhooks", h)
+}
+
+// RemoveWebhookHandler routes DELETE /api/pharmacies/webhooks/:id, also under /api/v2.
+func RemoveWebhookHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.RemoveWebhookEndpoint,
+		decodeHTTPRemoveWebhookRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Delete("/api/pharmacies/webhooks/:id", h)
+	m.Delete("/api/v2/pharmacies/webhooks/:id", h)
+}
+
+// WebhookDeliveriesHandler routes GET /api/pharmacies/webhooks/:id/deliveries, also under /api/v2.
+func WebhookDeliveriesHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.WebhookDeliveriesEndpoint,
+		decodeHTTPWebhookDeliveriesRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Get("/api/pharmacies/webhooks/:id/deliveries", h)
+	m.Get("/api/v2/pharmacies/webhooks/:id/deliveries", h)
+}
+
+// FollowHandler routes POST /api/pharmacies/watches, also under /api/v2.
+func FollowHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.FollowEndpoint,
+		decodeHTTPFollowRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Post("/api/pharmacies/watches", h)
+	m.Post("/api/v2/pharmacies/watches", h)
+}
+
+// UnfollowHandler routes DELETE /api/pharmacies/watches/:user_id/:pharmacy_id, also under /api/v2.
+func UnfollowHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.UnfollowEndpoint,
+		decodeHTTPUnfollowRequest,
+		encodeJSONResponse,
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Delete("/api/pharmacies/watches/:user_id/:pharmacy_id", h)
+	m.Delete("/api/v2/pharmacies/watches/:user_id/:pharmacy_id", h)
+}
+
+// WatchesHandler routes GET /api/pharmacies/watches/:user_id, also under /api/v2.
+func WatchesHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.WatchesEndpoint,
+		decodeHTTPWatchesRequest,
+		encodeJSONResponse,
 		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
-	))
+	)
+	m.Get("/api/pharmacies/watches/:user_id", h)
+	m.Get("/api/v2/pharmacies/watches/:user_id", h)
+}
 
+// StreamHandler routes GET /api/pharmacies/stream, also under /api/v2. App
+// Engine standard buffers responses until they complete, so the stream only
+// works where the service runs on Cloud Run or the flexible environment.
+func StreamHandler(m *bone.Mux, endpoints endpoints.Endpoints, options []httptransport.ServerOption, logger log.Logger) {
+	h := httptransport.NewServer(
+		endpoints.StreamEndpoint,
+		decodeHTTPStreamRequest,
+		encodeStreamResponse(logger),
+		append(options, httptransport.ServerBefore(kitjwt.HTTPToContext()))...,
+	)
+	m.Get("/api/pharmacies/stream", h)
+	m.Get("/api/v2/pharmacies/stream", h)
 }
 
 // NewHTTPHandler returns a handler that makes a set of endpoints available on
-// predefined paths.
-func NewHTTPHandler(endpoints endpoints.Endpoints, logger log.Logger) http.Handler { // Zipkin HTTP Server Trace can either be instantiated per endpoint with a
+// predefined paths. /metrics requires adminToken.
+func NewHTTPHandler(endpoints endpoints.Endpoints, adminToken string, logger log.Logger) http.Handler {
+	m := newMux(endpoints, adminToken, logger)
+	return cors.AllowAll().Handler(logging.RequestIDHandler(tracing.HTTPHandler(m, "/metrics")))
+}
+
+// newMux registers the routes of NewHTTPHandler.
+func newMux(endpoints endpoints.Endpoints, adminToken string, logger log.Logger) *bone.Mux {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(httpEncodeError),
 		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerBefore(responses.VersionToContext),
 	}
 
 	m := bone.New()
 	QueryHandler(m, endpoints, options, logger)
+	ExportHandler(m, endpoints, options, logger)
+	CreateWebhookHandler(m, endpoints, options, logger)
+	ListWebhooksHandler(m, endpoints, options, logger)
+	RemoveWebhookHandler(m, endpoints, options, logger)
+	WebhookDeliveriesHandler(m, endpoints, options, logger)
+	FollowHandler(m, endpoints, options, logger)
+	UnfollowHandler(m, endpoints, options, logger)
+	WatchesHandler(m, endpoints, options, logger)
+	StreamHandler(m, endpoints, options, logger)
+	m.Get("/openapi.json", openapi.Handler(OpenAPI()))
+	m.Get("/metrics", auth.Handler(adminToken, promhttp.Handler()))
 	m.GetFunc("/_ah/warmup", func(w http.ResponseWriter, r *http.Request) {
 		logger.Log("/_ah/warmup", "done")
 	})
 	m.GetFunc("/api/pharmacies/health_check", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("ok"))
 	})
-	return cors.AllowAll().Handler(m)
+	return m
 }
 
 // decodeHTTPQueryRequest is a transport/http.DecodeRequestFunc that decodes a
@@ -70,7 +218,109 @@ func decodeHTTPQueryRequest(_ context.Context, r *http.Request) (interface{}, er
 	return req, err
 }
 
-func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
+// decodeHTTPExportRequest is a transport/http.DecodeRequestFunc that decodes
+// the export format from the query string. Primarily useful in a server.
+func decodeHTTPExportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.ExportRequest
+	f, err := export.ParseFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if err != nil {
+		return nil, errors.Wrap(service.ErrMalformedEntity, err)
+	}
+	req.Format = f
+	return req, nil
+}
+
+// decodeHTTPCreateWebhookRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func decodeHTTPCreateWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.CreateWebhookRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+// decodeHTTPListWebhooksRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func decodeHTTPListWebhooksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.ListWebhooksRequest
+	return req, nil
+}
+
+// decodeHTTPRemoveWebhookRequest is a transport/http.DecodeRequestFunc that decodes
+// the webhook id from the path. Primarily useful in a server.
+func decodeHTTPRemoveWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.RemoveWebhookRequest
+	req.ID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+// decodeHTTPWebhookDeliveriesRequest is a transport/http.DecodeRequestFunc that decodes
+// the webhook id and paging from the URL. Primarily useful in a server.
+func decodeHTTPWebhookDeliveriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.WebhookDeliveriesRequest
+	req.ID = bone.GetValue(r, "id")
+
+	var err error
+	req.Offset, err = readUintQuery(r, "offset", defOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Limit, err = readUintQuery(r, "limit", defLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeHTTPFollowRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func decodeHTTPFollowRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.FollowRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+// decodeHTTPUnfollowRequest is a transport/http.DecodeRequestFunc that decodes
+// the user and pharmacy id from the path. Primarily useful in a server.
+func decodeHTTPUnfollowRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.UnfollowRequest
+	req.UserID = bone.GetValue(r, "user_id")
+	req.PharmacyID = bone.GetValue(r, "pharmacy_id")
+	return req, nil
+}
+
+// decodeHTTPWatchesRequest is a transport/http.DecodeRequestFunc that decodes
+// the user id from the path. Primarily useful in a server.
+func decodeHTTPWatchesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.WatchesRequest
+	req.UserID = bone.GetValue(r, "user_id")
+	return req, nil
+}
+
+// decodeHTTPStreamRequest is a transport/http.DecodeRequestFunc that decodes
+// the bounding box from the query string. Primarily useful in a server.
+func decodeHTTPStreamRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.StreamRequest
+	parts := strings.Split(r.URL.Query().Get("bounds"), ",")
+	if len(parts) != 4 {
+		return nil, errors.Wrap(service.ErrMalformedEntity, errors.New("bounds must be swLng,swLat,neLng,neLat"))
+	}
+
+	var coords [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, errors.Wrap(service.ErrMalformedEntity, err)
+		}
+		coords[i] = v
+	}
+	req.Sw = endpoints.LatLng{Lat: coords[1], Lng: coords[0]}
+	req.Ne = endpoints.LatLng{Lat: coords[3], Lng: coords[2]}
+	return req, nil
+}
+
+func httpEncodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	code := http.StatusInternalServerError
 	var message string
 	var errs []errors.Errors
@@ -84,6 +334,9 @@ func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 			errors.Contains(errorVal, service.ErrInvalidTask),
 			errors.Contains(errorVal, service.ErrTaskCreatFailed):
 			code = http.StatusBadRequest
+		case errors.Contains(errorVal, model.ErrWebhookNotFound),
+			errors.Contains(errorVal, model.ErrWatchNotFound):
+			code = http.StatusNotFound
 		}
 
 		if errorVal.Msg() != "" {
@@ -93,7 +346,7 @@ func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 		switch err {
 		case io.ErrUnexpectedEOF, io.EOF:
 			code = http.StatusBadRequest
-		case kitjwt.ErrTokenContextMissing:
+		case kitjwt.ErrTokenContextMissing, kitjwt.ErrTokenInvalid:
 			code = http.StatusUnauthorized
 		default:
 			switch err.(type) {
@@ -107,10 +360,37 @@ func httpEncodeError(_ context.Context, err error, w http.ResponseWriter) {
 	}
 
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(responses.ErrorRes{responses.ErrorResItem{code, message, errs}})
+	item := responses.ErrorResItem{Code: code, Message: message, Errors: errs}
+	if responses.Version(ctx) == 2 {
+		json.NewEncoder(w).Encode(responses.Envelope{APIVersion: service.Version, Error: &item})
+		return
+	}
+	json.NewEncoder(w).Encode(responses.ErrorRes{Error: item})
+}
+
+// formatToContext negotiates the response format from the format query
+// parameter, falling back to the Accept header.
+func formatToContext(ctx context.Context, r *http.Request) context.Context {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return context.WithValue(ctx, contextKeyFormat, strings.ToLower(f))
+	}
+
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mt, _, err := mime.ParseMediaType(strings.TrimSpace(v)); err == nil && mt == geoJSONContentType {
+			return context.WithValue(ctx, contextKeyFormat, formatGeoJSON)
+		}
+	}
+	return ctx
 }
 
-func encodeJSONResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+func encodeJSONResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, _ := ctx.Value(contextKeyFormat).(string); f == formatGeoJSON {
+		if gr, ok := response.(responses.GeoJSONResponser); ok {
+			w.Header().Set("Content-Type", geoJSONContentType)
+			return json.NewEncoder(w).Encode(gr.GeoJSON())
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if headerer, ok := response.(httptransport.Headerer); ok {
 		for k, values := range headerer.Headers() {
@@ -128,9 +408,125 @@ func encodeJSONResponse(_ context.Context, w http.ResponseWriter, response inter
 		return nil
 	}
 
+	if responses.Version(ctx) == 2 {
+		if er, ok := response.(responses.Enveloper); ok {
+			return json.NewEncoder(w).Encode(er.Envelope())
+		}
+		return json.NewEncoder(w).Encode(responses.Envelope{APIVersion: service.Version, Data: response})
+	}
+
 	if ar, ok := response.(responses.Responser); ok {
 		return json.NewEncoder(w).Encode(ar.Response())
 	}
 
 	return json.NewEncoder(w).Encode(response)
 }
+
+// encodeExportResponse streams the export cursor row by row. Once the first
+// row is written the status line is gone, so later failures are only logged.
+func encodeExportResponse(logger log.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		logger := logging.WithRequestID(ctx, logger)
+		res := response.(endpoints.ExportResponse)
+		defer res.Cursor.Close()
+
+		for k, values := range res.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+
+		ew, err := export.NewWriter(w, res.Format, model.PharmacyCSVHeader)
+		if err != nil {
+			level.Error(logger).Log("method", "export.NewWriter", "err", err)
+			return nil
+		}
+
+		for res.Cursor.Next() {
+			p, err := res.Cursor.Pharmacy()
+			if err != nil {
+				level.Error(logger).Log("method", "res.Cursor.Pharmacy", "err", err)
+				break
+			}
+			if err := ew.Write(&p); err != nil {
+				level.Error(logger).Log("method", "ew.Write", "err", err)
+				return nil
+			}
+		}
+		if err := res.Cursor.Err(); err != nil {
+			level.Error(logger).Log("method", "res.Cursor.Err", "err", err)
+		}
+		if err := ew.Flush(); err != nil {
+			level.Error(logger).Log("method", "ew.Flush", "err", err)
+		}
+		return nil
+	}
+}
+
+func readUintQuery(r *http.Request, key string, def uint64) (uint64, error) {
+	vals := bone.GetQuery(r, key)
+	if len(vals) > 1 {
+		return 0, service.ErrMalformedEntity
+	}
+
+	if len(vals) == 0 {
+		return def, nil
+	}
+
+	strval := vals[0]
+	val, err := strconv.ParseUint(strval, 10, 64)
+	if err != nil {
+		return 0, service.ErrMalformedEntity
+	}
+
+	return val, nil
+}
+
+// encodeStreamResponse writes events as Server-Sent Events until the client
+// goes away.
+func encodeStreamResponse(logger log.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		logger := logging.WithRequestID(ctx, logger)
+		res := response.(endpoints.StreamResponse)
+		for k, values := range res.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			level.Error(logger).Log("method", "encodeStreamResponse", "err", "streaming unsupported")
+			return nil
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case e, ok := <-res.Events:
+				if !ok {
+					return nil
+				}
+				b, err := json.Marshal(e)
+				if err != nil {
+					level.Error(logger).Log("method", "json.Marshal", "err", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b); err != nil {
+					return nil
+				}
+			case <-heartbeat.C:
+				if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package transports
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/lib/pq"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/openapi"
+)
+
+// OpenAPI returns the OpenAPI document of the routes of NewHTTPHandler.
+func OpenAPI() *openapi.Document {
+	return openapi.Spec{
+		Info: openapi.Info{
+			Title:       "Mask pharmacy API",
+			Description: "Pharmacies and their mask stock, webhooks and watches on stock changes.",
+			Version:     service.Version,
+		},
+		Tags: []openapi.Tag{
+			{Name: "pharmacy", Description: "Pharmacies and their mask stock"},
+			{Name: "webhook", Description: "Webhook subscriptions to stock events, managed with the admin token"},
+			{Name: "watch", Description: "Restock notifications of the pharmacies a user follows"},
+		},
+		Types: map[reflect.Type]*openapi.Schema{
+			// Pharmacy.MarshalJSON writes the RFC 3339 time, empty when unknown.
+			reflect.TypeOf(pq.NullTime{}): {Type: "string", Format: "date-time"},
+		},
+		Routes: []openapi.Route{
+			{
+				Method:   http.MethodPost,
+				Path:     "/pharmacies",
+				ID:       "queryPharmacies",
+				Summary:  "Fetch the pharmacies nearest to the center within the bounds, as clusters at zoom 1 - 13; zoom 0, the default, returns stores",
+				Tags:     []string{"pharmacy"},
+				Query:    []openapi.Parameter{formatParam("geojson for a GeoJSON FeatureCollection")},
+				Request:  endpoints.QueryRequest{},
+				Response: endpoints.QueryResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/pharmacies/export",
+				ID:       "exportPharmacies",
+				Summary:  "Export the latest pharmacy snapshot",
+				Tags:     []string{"pharmacy"},
+				Query:    []openapi.Parameter{formatParam("csv or ndjson, else negotiated from Accept")},
+				Response: endpoints.ExportResponse{},
+				Produces: []string{"text/csv", "application/x-ndjson"},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/pharmacies/stream",
+				ID:      "streamPharmacies",
+				Summary: "Stream stock and feedback updates inside a bounding box as Server-Sent Events",
+				Tags:    []string{"pharmacy"},
+				Query: []openapi.Parameter{
+					{Name: "bounds", In: "query", Description: "swLng,swLat,neLng,neLat", Required: true, Schema: &openapi.Schema{Type: "string"}},
+				},
+				Response: endpoints.StreamResponse{},
+				Produces: []string{"text/event-stream"},
+				Errors:   []int{http.StatusBadRequest},
+			},
+			{
+				Method:   http.MethodPost,
+				Path:     "/pharmacies/webhooks",
+				ID:       "createWebhook",
+				Summary:  "Subscribe a webhook to pharmacy stock events",
+				Tags:     []string{"webhook"},
+				Request:  endpoints.CreateWebhookRequest{},
+				Response: endpoints.CreateWebhookResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/pharmacies/webhooks",
+				ID:       "listWebhooks",
+				Summary:  "List webhook subscriptions",
+				Tags:     []string{"webhook"},
+				Response: endpoints.ListWebhooksResponse{},
+				Errors:   []int{http.StatusUnauthorized, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodDelete,
+				Path:     "/pharmacies/webhooks/:id",
+				ID:       "removeWebhook",
+				Summary:  "Unsubscribe a webhook",
+				Tags:     []string{"webhook"},
+				Response: endpoints.RemoveWebhookResponse{},
+				Errors:   []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/pharmacies/webhooks/:id/deliveries",
+				ID:       "webhookDeliveries",
+				Summary:  "Fetch the delivery log of a webhook",
+				Tags:     []string{"webhook"},
+				Query:    pageParams(),
+				Response: endpoints.WebhookDeliveriesResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodPost,
+				Path:     "/pharmacies/watches",
+				ID:       "follow",
+				Summary:  "Follow a pharmacy to be notified when masks are restocked",
+				Tags:     []string{"watch"},
+				Request:  endpoints.FollowRequest{},
+				Response: endpoints.FollowResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodDelete,
+				Path:     "/pharmacies/watches/:user_id/:pharmacy_id",
+				ID:       "unfollow",
+				Summary:  "Unfollow a pharmacy",
+				Tags:     []string{"watch"},
+				Response: endpoints.UnfollowResponse{},
+				Errors:   []int{http.StatusNotFound, http.StatusInternalServerError},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/pharmacies/watches/:user_id",
+				ID:       "watches",
+				Summary:  "List the pharmacies a user follows",
+				Tags:     []string{"watch"},
+				Response: endpoints.WatchesResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			},
+		},
+	}.Document()
+}
+
+func formatParam(description string) openapi.Parameter {
+	return openapi.Parameter{Name: "format", In: "query", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func pageParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		{Name: "offset", In: "query", Description: "defaults to 0", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+		{Name: "limit", In: "query", Description: "1 - 100, defaults to 10", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+	}
+}
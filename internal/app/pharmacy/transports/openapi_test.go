@@ -0,0 +1,81 @@
+package transports
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+)
+
+var update = flag.Bool("update", false, "rewrite the committed OpenAPI spec")
+
+const specFile = "../../../../api/openapi/pharmacy.json"
+
+func TestOpenAPI(t *testing.T) {
+	got, err := json.MarshalIndent(OpenAPI(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.WriteFile(specFile, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the routes, run make openapi", specFile)
+	}
+}
+
+var routeParam = regexp.MustCompile(`:([^/]+)`)
+
+// unspecified lists the routes OpenAPI leaves out on purpose.
+var unspecified = map[string]bool{
+	"GET /openapi.json":                true,
+	"GET /metrics":                     true,
+	"GET /_ah/warmup":                  true,
+	"GET /api/pharmacies/health_check": true,
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	routes := map[string]bool{}
+	for method, rs := range newMux(endpoints.Endpoints{}, "", log.NewNopLogger()).Routes {
+		for _, r := range rs {
+			if route := method + " " + routeParam.ReplaceAllString(r.Path, "{$1}"); !unspecified[route] {
+				routes[route] = true
+			}
+		}
+	}
+
+	documented := map[string]bool{}
+	for path, item := range OpenAPI().Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("%s is served but not documented", route)
+		}
+	}
+	for route := range documented {
+		if !routes[route] {
+			t.Errorf("%s is documented but not served", route)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// ErrAddressNotAllowed indicates a delivery to a loopback, link-local,
+// private or otherwise internal address.
+var ErrAddressNotAllowed = errors.New("webhook address not allowed")
+
+// NewClient returns the HTTP client deliveries are posted with. It refuses
+// to connect to internal addresses. The address is checked when it is
+// dialed, after DNS resolution and for every redirect, so a subscriber
+// cannot point the service at its own network through a hostname.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would dial the subscriber on our behalf, unchecked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// dialControl rejects connections to the addresses Allowed refuses.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !Allowed(ip) {
+		return errors.Wrap(ErrAddressNotAllowed, errors.New(host))
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, internal
+// to a provider network like the private ranges.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Allowed reports whether deliveries may connect to ip: public unicast
+// addresses only.
+func Allowed(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip) || ip.Equal(net.IPv4bcast))
+}
@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/level"
+	"github.com/cage1016/mask/internal/pkg/logging"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body,
+	// keyed with the webhook secret.
+	SignatureHeader = "X-Mask-Signature"
+	// SnapshotHeader carries the snapshot the delivered events were computed for.
+	SnapshotHeader = "X-Mask-Snapshot"
+
+	maxErrorLength = 1024
+
+	// pollInterval is how often Run looks for due deliveries besides the
+	// ones Publish records.
+	pollInterval = 10 * time.Second
+	// lease postpones a delivery while it is attempted, so it must outlast
+	// the timeout of the client. A delivery left pending by a stopped
+	// instance is attempted again once its lease expires.
+	lease = time.Minute
+	// batchSize bounds the deliveries leased at once.
+	batchSize = 20
+)
+
+var _ service.EventPublisher = (*Dispatcher)(nil)
+
+// Payload is the JSON body POSTed to subscribers.
+type Payload struct {
+	Snapshot string             `json:"snapshot"`
+	SentAt   time.Time          `json:"sentAt"`
+	Events   []model.StockEvent `json:"events"`
+}
+
+// Dispatcher delivers stock events to webhooks. Publish records the
+// deliveries, Run attempts them.
+type Dispatcher struct {
+	repo         model.WebhookRepository
+	client       *http.Client
+	maxAttempts  int
+	backoff      time.Duration
+	pollInterval time.Duration
+	logger       log.Logger
+	wake         chan struct{}
+}
+
+// New instantiates a webhook dispatcher. Every delivery is attempted up to
+// maxAttempts times, waiting backoff, 2*backoff, 4*backoff... in between,
+// rounded up to the next poll of Run.
+func New(repo model.WebhookRepository, client *http.Client, maxAttempts int, backoff time.Duration, logger log.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:         repo,
+		client:       client,
+		maxAttempts:  maxAttempts,
+		backoff:      backoff,
+		pollInterval: pollInterval,
+		logger:       logger,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Publish records a pending delivery of the events each webhook subscribed
+// to, and wakes up Run to attempt them.
+func (d *Dispatcher) Publish(ctx context.Context, snapshot string, events []model.StockEvent) error {
+	webhooks, err := d.repo.RetrieveAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range webhooks {
+		var accepted []model.StockEvent
+		for _, e := range events {
+			if w.Accepts(e.Type) {
+				accepted = append(accepted, e)
+			}
+		}
+		if len(accepted) == 0 {
+			continue
+		}
+
+		body, err := json.Marshal(Payload{Snapshot: snapshot, SentAt: time.Now(), Events: accepted})
+		if err != nil {
+			level.Error(logging.WithRequestID(ctx, d.logger)).Log("method", "json.Marshal", "webhook", w.ID, "err", err)
+			continue
+		}
+		delivery := model.Delivery{WebhookID: w.ID, Snapshot: snapshot, Status: model.DeliveryPending, Payload: body}
+		if _, err := d.repo.Claim(ctx, delivery); err != nil {
+			level.Error(logging.WithRequestID(ctx, d.logger)).Log("method", "d.repo.Claim", "webhook", w.ID, "err", err)
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run attempts the due deliveries until ctx is done: when it starts, which
+// picks up the deliveries a stopped instance left pending, whenever Publish
+// records new ones and every poll interval for the retries.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.Due(ctx, lease, batchSize)
+		if err != nil {
+			level.Error(logging.WithRequestID(ctx, d.logger)).Log("method", "d.repo.Due", "err", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		webhooks, err := d.repo.RetrieveAll(ctx)
+		if err != nil {
+			level.Error(logging.WithRequestID(ctx, d.logger)).Log("method", "d.repo.RetrieveAll", "err", err)
+			return
+		}
+		byID := make(map[string]model.Webhook, len(webhooks))
+		for _, w := range webhooks {
+			byID[w.ID] = w
+		}
+
+		for _, delivery := range deliveries {
+			// a removed webhook takes its deliveries with it
+			if w, ok := byID[delivery.WebhookID]; ok {
+				d.attempt(ctx, w, delivery)
+			}
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+// attempt posts a delivery once and records the outcome, scheduling the
+// next attempt while attempts remain.
+func (d *Dispatcher) attempt(ctx context.Context, w model.Webhook, delivery model.Delivery) {
+	var err error
+	delivery.Attempts++
+	if len(delivery.Payload) == 0 {
+		// recorded before deliveries kept their payload
+		delivery.LastStatusCode, err = 0, fmt.Errorf("payload of snapshot %s was not recorded", delivery.Snapshot)
+		delivery.Attempts = uint64(d.maxAttempts)
+	} else {
+		delivery.LastStatusCode, err = d.post(ctx, w, delivery.Snapshot, delivery.Payload)
+	}
+	if ctx.Err() != nil {
+		// shutting down: the lease expires and the attempt is made again
+		return
+	}
+
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliveryDelivered
+	case delivery.Attempts >= uint64(d.maxAttempts):
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = truncate(err.Error(), maxErrorLength)
+	default:
+		delivery.LastError = truncate(err.Error(), maxErrorLength)
+		delivery.NextAttemptAt = time.Now().Add(d.backoff << (delivery.Attempts - 1))
+	}
+
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		level.Error(logging.WithRequestID(ctx, d.logger)).Log("method", "d.repo.UpdateDelivery", "webhook", w.ID, "err", err)
+	}
+	if delivery.Status != model.DeliveryPending {
+		level.Info(logging.WithRequestID(ctx, d.logger)).Log("webhook", w.ID, "snapshot", delivery.Snapshot, "status", delivery.Status, "attempts", delivery.Attempts)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, w model.Webhook, snapshot string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SnapshotHeader, snapshot)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body keyed with secret.
+// Subscribers compare it with the SignatureHeader to verify a delivery.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
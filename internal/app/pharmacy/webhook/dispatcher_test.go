@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/memory"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+const snapshot = "pharmacy_0322_1030"
+
+// subscriber answers deliveries with the statuses in turn, then 200.
+type subscriber struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (s *subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+	if len(s.statuses) > 0 {
+		w.WriteHeader(s.statuses[0])
+		s.statuses = s.statuses[1:]
+	}
+}
+
+// start runs a dispatcher posting to srv until the test ends.
+func start(t *testing.T, repo model.WebhookRepository, srv *httptest.Server, maxAttempts int) *Dispatcher {
+	d := New(repo, srv.Client(), maxAttempts, time.Millisecond, log.NewNopLogger())
+	d.pollInterval = 5 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d
+}
+
+func subscribe(t *testing.T, repo model.WebhookRepository, url string, events ...string) model.Webhook {
+	w := model.Webhook{ID: "hook-" + strings.Join(events, "-"), URL: url, Secret: "secret", Events: events}
+	if _, err := repo.Save(context.Background(), w); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// settled waits for the delivery of snapshot to w to be delivered or failed.
+func settled(t *testing.T, repo model.WebhookRepository, w model.Webhook) model.Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		page, err := repo.RetrieveDeliveries(context.Background(), w.ID, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) == 1 && page.Items[0].Status != model.DeliveryPending {
+			if page.Items[0].Payload != nil {
+				t.Error("got the payload in the delivery log")
+			}
+			return page.Items[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("delivery to %s still pending", w.ID)
+	return model.Delivery{}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	sub := &subscriber{}
+	srv := httptest.NewServer(sub)
+	defer srv.Close()
+
+	repo := memory.NewWebhookRepository()
+	w := subscribe(t, repo, srv.URL, model.EventRestocked)
+	subscribe(t, repo, srv.URL, model.EventRemoved)
+	d := start(t, repo, srv, 1)
+
+	events := []model.StockEvent{
+		{Type: model.EventRestocked, PharmacyID: "5901012345", MaskAdult: 10},
+		{Type: model.EventSoldOut, PharmacyID: "5901054321"},
+	}
+	if err := d.Publish(context.Background(), snapshot, events); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := settled(t, repo, w); got.Status != model.DeliveryDelivered || got.Attempts != 1 || got.LastStatusCode != http.StatusOK {
+		t.Fatalf("got %+v, want delivered at once", got)
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if len(sub.requests) != 1 {
+		t.Fatalf("got %d requests, want 1: no event of the second webhook changed", len(sub.requests))
+	}
+	r, body := sub.requests[0], sub.bodies[0]
+	if got, want := r.Header.Get(SignatureHeader), "sha256="+Sign("secret", body); got != want {
+		t.Errorf("got signature %q, want %q", got, want)
+	}
+	if got := r.Header.Get(SnapshotHeader); got != snapshot {
+		t.Errorf("got snapshot header %q, want %q", got, snapshot)
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Snapshot != snapshot || len(p.Events) != 1 || p.Events[0].Type != model.EventRestocked {
+		t.Errorf("got payload %+v, want the restocked event only", p)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	cases := []struct {
+		desc        string
+		statuses    []int
+		maxAttempts int
+		status      string
+		attempts    uint64
+		lastCode    int
+	}{
+		{"recovers", []int{http.StatusInternalServerError, http.StatusBadGateway}, 3, model.DeliveryDelivered, 3, http.StatusOK},
+		{"gives up", []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusGone}, 3, model.DeliveryFailed, 3, http.StatusGone},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			srv := httptest.NewServer(&subscriber{statuses: c.statuses})
+			defer srv.Close()
+
+			repo := memory.NewWebhookRepository()
+			w := subscribe(t, repo, srv.URL)
+			d := start(t, repo, srv, c.maxAttempts)
+			if err := d.Publish(context.Background(), snapshot, []model.StockEvent{{Type: model.EventListed}}); err != nil {
+				t.Fatal(err)
+			}
+
+			got := settled(t, repo, w)
+			if got.Status != c.status || got.Attempts != c.attempts || got.LastStatusCode != c.lastCode {
+				t.Errorf("got %+v, want %s after %d attempts with %d", got, c.status, c.attempts, c.lastCode)
+			}
+			if (got.LastError == "") != (c.status == model.DeliveryDelivered) {
+				t.Errorf("got last error %q", got.LastError)
+			}
+		})
+	}
+}
+
+func TestDispatcherResumesPending(t *testing.T) {
+	srv := httptest.NewServer(&subscriber{})
+	defer srv.Close()
+
+	// recorded by an instance that stopped before attempting it
+	repo := memory.NewWebhookRepository()
+	w := subscribe(t, repo, srv.URL)
+	body, _ := json.Marshal(Payload{Snapshot: snapshot, Events: []model.StockEvent{{Type: model.EventListed}}})
+	if _, err := repo.Claim(context.Background(), model.Delivery{WebhookID: w.ID, Snapshot: snapshot, Status: model.DeliveryPending, Payload: body}); err != nil {
+		t.Fatal(err)
+	}
+
+	start(t, repo, srv, 1)
+	if got := settled(t, repo, w); got.Status != model.DeliveryDelivered {
+		t.Errorf("got %+v, want the pending delivery delivered on start", got)
+	}
+}
+
+func TestSign(t *testing.T) {
+	cases := []struct {
+		secret, body, want string
+	}{
+		// RFC 4231 test case 2
+		{"Jefe", "what do ya want for nothing?", "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{"", "", "b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad"},
+	}
+	for _, c := range cases {
+		if got := Sign(c.secret, []byte(c.body)); got != c.want {
+			t.Errorf("Sign(%q, %q): got %s, want %s", c.secret, c.body, got, c.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"0.0.0.0":         false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"100.64.0.1":      false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	}
+	for addr, want := range cases {
+		if got := Allowed(net.ParseIP(addr)); got != want {
+			t.Errorf("Allowed(%s): got %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestNewClientRefusesInternalAddresses(t *testing.T) {
+	sub := &subscriber{}
+	srv := httptest.NewServer(sub)
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Post(srv.URL, "application/json", nil)
+	if err == nil || !strings.Contains(err.Error(), ErrAddressNotAllowed.Error()) {
+		t.Fatalf("got %v, want %v", err, ErrAddressNotAllowed)
+	}
+	if len(sub.requests) != 0 {
+		t.Error("got a request on the loopback address")
+	}
+}
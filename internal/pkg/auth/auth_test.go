@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	ok := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	for _, tc := range []struct {
+		desc, token, got string
+		want             error
+	}{
+		{desc: "valid", token: "secret", got: "secret"},
+		{desc: "missing", token: "secret", want: kitjwt.ErrTokenContextMissing},
+		{desc: "wrong", token: "secret", got: "guess", want: kitjwt.ErrTokenInvalid},
+		{desc: "no token configured", got: "anything", want: kitjwt.ErrTokenInvalid},
+	} {
+		ctx := context.Background()
+		if tc.got != "" {
+			ctx = context.WithValue(ctx, kitjwt.JWTTokenContextKey, tc.got)
+		}
+		if _, err := Middleware(tc.token)(ok)(ctx, nil); err != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.desc, err, tc.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for header, want := range map[string]int{
+		"Bearer secret": http.StatusOK,
+		"Bearer guess":  http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"":              http.StatusUnauthorized,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("%q: got status %d, want %d", header, w.Code, want)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ok := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	for _, tc := range []struct {
+		desc, token string
+		md          metadata.MD
+		want        codes.Code
+	}{
+		{desc: "valid", token: "secret", md: metadata.Pairs("authorization", "Bearer secret"), want: codes.OK},
+		{desc: "missing", token: "secret", want: codes.Unauthenticated},
+		{desc: "wrong", token: "secret", md: metadata.Pairs("authorization", "Bearer guess"), want: codes.Unauthenticated},
+		{desc: "not bearer", token: "secret", md: metadata.Pairs("authorization", "secret"), want: codes.Unauthenticated},
+		{desc: "no token configured", md: metadata.Pairs("authorization", "Bearer "), want: codes.Unauthenticated},
+	} {
+		ctx := context.Background()
+		if tc.md != nil {
+			ctx = metadata.NewIncomingContext(ctx, tc.md)
+		}
+		if _, err := UnaryServerInterceptor(tc.token)(ctx, nil, &grpc.UnaryServerInfo{}, ok); status.Code(err) != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.desc, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+// Package config loads the configuration of a binary into a typed struct.
+//
+// Every exported field is a setting. Its value is resolved, from lowest to
+// highest precedence, from the `default` tag, a YAML or TOML file, the
+// environment (or the secret provider for fields tagged `secret:"true"`) and
+// the command-line flags:
+//
+//	type Config struct {
+//	    LogLevel string          `config:"log_level" default:"error" oneof:"debug,info,warn,error,none"`
+//	    HTTPPort string          `config:"port" env:"PORT" default:"8080"`
+//	    DB       postgres.Config `config:"db"`
+//	}
+//
+// With the prefix MASK_PHARMACY_ the field DB.Host is read from the db.host
+// file key, the MASK_PHARMACY_DB_HOST variable and the --db-host flag. The
+// file is named by the --config flag or the <prefix>CONFIG_FILE variable, and
+// --print-config writes the resolved configuration, secrets redacted.
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/secrets"
+)
+
+const (
+	configFlag      = "config"
+	printConfigFlag = "print-config"
+	configFileEnv   = "CONFIG_FILE"
+
+	redacted = "[REDACTED]"
+)
+
+var (
+	// ErrPrinted indicates the configuration or the flags usage was printed,
+	// as requested by --print-config or --help, and the binary should exit.
+	ErrPrinted = errors.New("configuration printed")
+
+	// ErrInvalidConfig indicates the resolved configuration failed validation.
+	ErrInvalidConfig = errors.New("invalid configuration")
+
+	// ErrUnsupportedFile indicates the configuration file is neither YAML nor
+	// TOML.
+	ErrUnsupportedFile = errors.New("unsupported configuration file")
+)
+
+// Validator is implemented by configurations with checks beyond the
+// `required` and `oneof` tags.
+type Validator interface {
+	Validate() error
+}
+
+// Options parameterize Load.
+type Options struct {
+	// Name is the binary name shown in the flags usage.
+	Name string
+	// Prefix is prepended to the environment variable of every field without
+	// an env tag.
+	Prefix string
+	// Args are the command-line arguments, without the program name.
+	Args []string
+	// Positional, when set, receives the arguments left after the flags.
+	Positional *[]string
+	// Secrets resolves fields tagged secret:"true" by their environment
+	// variable name. It defaults to the environment.
+	Secrets secrets.Provider
+	// Out receives the configuration printed by --print-config. It defaults
+	// to os.Stdout.
+	Out io.Writer
+}
+
+type field struct {
+	path  []string
+	value reflect.Value
+	tag   reflect.StructTag
+	env   string
+}
+
+func (f field) key() string  { return strings.Join(f.path, ".") }
+func (f field) flag() string { return strings.Replace(strings.Join(f.path, "-"), "_", "-", -1) }
+
+// Load resolves the configuration into dst, a pointer to a struct.
+func Load(dst interface{}, opts Options) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("config destination must be a pointer to a struct")
+	}
+	if opts.Secrets == nil {
+		opts.Secrets = secrets.NewEnvProvider()
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+
+	fields := collect(rv.Elem(), nil, opts.Prefix)
+
+	for _, f := range fields {
+		if d, ok := f.tag.Lookup("default"); ok {
+			if err := set(f.value, d); err != nil {
+				return fieldError(f, "default", err)
+			}
+		}
+	}
+
+	fs := flag.NewFlagSet(opts.Name, flag.ContinueOnError)
+	flags := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		usage := f.tag.Get("usage")
+		if usage != "" {
+			usage += " "
+		}
+		flags[f.flag()] = fs.String(f.flag(), "", fmt.Sprintf("%s(env %s)", usage, f.env))
+	}
+	file := fs.String(configFlag, "", fmt.Sprintf("YAML or TOML configuration file (env %s%s)", opts.Prefix, configFileEnv))
+	printConfig := fs.Bool(printConfigFlag, false, "print the resolved configuration and exit")
+	if err := fs.Parse(opts.Args); err != nil {
+		if err == flag.ErrHelp {
+			return ErrPrinted
+		}
+		return err
+	}
+	if opts.Positional != nil {
+		*opts.Positional = fs.Args()
+	}
+
+	if *file == "" {
+		*file = os.Getenv(opts.Prefix + configFileEnv)
+	}
+	if *file != "" {
+		if err := loadFile(*file, fields); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fields {
+		v, err := lookupEnv(f, opts.Secrets)
+		if err != nil {
+			return err
+		}
+		if v == "" {
+			continue
+		}
+		if err := set(f.value, v); err != nil {
+			return fieldError(f, f.env, err)
+		}
+	}
+
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		p, ok := flags[fl.Name]
+		if !ok || err != nil {
+			return
+		}
+		for _, f := range fields {
+			if f.flag() == fl.Name {
+				if e := set(f.value, *p); e != nil {
+					err = fieldError(f, "--"+fl.Name, e)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	verr := validate(dst, fields)
+	if *printConfig {
+		if err := printFields(opts.Out, fields); err != nil {
+			return err
+		}
+		if verr != nil {
+			return verr
+		}
+		return ErrPrinted
+	}
+	return verr
+}
+
+// printFields writes fields as YAML to w, redacting secrets.
+func printFields(w io.Writer, fields []field) error {
+	out := yaml.MapSlice{}
+	for _, f := range fields {
+		var v interface{}
+		switch {
+		case f.tag.Get("secret") == "true" && !isZero(f.value):
+			v = redacted
+		case f.value.Type() == reflect.TypeOf(time.Duration(0)):
+			v = f.value.Interface().(time.Duration).String()
+		default:
+			v = f.value.Interface()
+		}
+		out = insert(out, f.path, v)
+	}
+
+	b, err := yaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func insert(m yaml.MapSlice, path []string, v interface{}) yaml.MapSlice {
+	if len(path) == 1 {
+		return append(m, yaml.MapItem{Key: path[0], Value: v})
+	}
+	for i := range m {
+		if m[i].Key == path[0] {
+			m[i].Value = insert(m[i].Value.(yaml.MapSlice), path[1:], v)
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: path[0], Value: insert(yaml.MapSlice{}, path[1:], v)})
+}
+
+func collect(v reflect.Value, path []string, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+		p := append(append([]string{}, path...), name)
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			fields = append(fields, collect(fv, p, prefix)...)
+			continue
+		}
+
+		env := sf.Tag.Get("env")
+		if env == "" {
+			env = prefix + strings.ToUpper(strings.Join(p, "_"))
+		}
+		fields = append(fields, field{path: p, value: fv, tag: sf.Tag, env: env})
+	}
+	return fields
+}
+
+func lookupEnv(f field, sp secrets.Provider) (string, error) {
+	if f.tag.Get("secret") != "true" {
+		return os.Getenv(f.env), nil
+	}
+
+	v, err := secrets.Get(context.Background(), sp, f.env, "")
+	if err != nil {
+		return "", errors.Wrap(errors.New(fmt.Sprintf("failed to resolve secret %s", f.env)), err)
+	}
+	return v, nil
+}
+
+func loadFile(path string, fields []field) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(errors.New("failed to read configuration file"), err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	case ".toml":
+		err = toml.Unmarshal(b, &raw)
+	default:
+		return ErrUnsupportedFile
+	}
+	if err != nil {
+		return errors.Wrap(errors.New("failed to parse configuration file"), err)
+	}
+
+	values := map[string]string{}
+	flatten(raw, "", values)
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.key()] = f
+	}
+	for k, v := range values {
+		f, ok := byKey[k]
+		if !ok {
+			return errors.Wrap(ErrInvalidConfig, errors.New(fmt.Sprintf("unknown key %s in %s", k, path)))
+		}
+		if err := set(f.value, v); err != nil {
+			return fieldError(f, path, err)
+		}
+	}
+	return nil
+}
+
+func flatten(in interface{}, prefix string, out map[string]string) {
+	switch m := in.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			flatten(v, join(prefix, k), out)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			flatten(v, join(prefix, fmt.Sprint(k)), out)
+		}
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(m)
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func set(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func validate(dst interface{}, fields []field) error {
+	var msgs []string
+	for _, f := range fields {
+		if f.tag.Get("required") == "true" && isZero(f.value) {
+			msgs = append(msgs, fmt.Sprintf("%s is required (env %s, flag --%s)", f.key(), f.env, f.flag()))
+			continue
+		}
+		if oneof, ok := f.tag.Lookup("oneof"); ok {
+			valid := false
+			for _, o := range strings.Split(oneof, ",") {
+				if fmt.Sprint(f.value.Interface()) == o {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				msgs = append(msgs, fmt.Sprintf("%s must be one of %q", f.key(), oneof))
+			}
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.Wrap(ErrInvalidConfig, errors.New(strings.Join(msgs, "; ")))
+	}
+
+	if v, ok := dst.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return errors.Wrap(ErrInvalidConfig, err)
+		}
+	}
+	return nil
+}
+
+func fieldError(f field, source string, err error) error {
+	return errors.Wrap(ErrInvalidConfig, errors.New(fmt.Sprintf("%s from %s: %s", f.key(), source, err)))
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
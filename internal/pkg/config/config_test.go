@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/secrets"
+)
+
+type db struct {
+	Host string `config:"host" required:"true"`
+	Pass string `config:"pass" secret:"true"`
+}
+
+type testConfig struct {
+	LogLevel  string        `config:"log_level" default:"error" oneof:"debug,info,warn,error"`
+	HTTPPort  string        `config:"port" env:"PORT" default:"8080"`
+	Timeout   time.Duration `config:"timeout" default:"5s"`
+	Threshold float64       `config:"threshold" default:"0.6"`
+	Retries   int           `config:"retries" default:"3"`
+	Verbose   bool
+	Token     string `config:"token" secret:"true"`
+	DB        db     `config:"db"`
+	Skipped   string `config:"-"`
+	unexposed string
+}
+
+// invalid fails the Validator check when Retries is negative.
+type invalid struct {
+	Retries int `config:"retries"`
+}
+
+func (c invalid) Validate() error {
+	if c.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "log_level: info\nretries: 7\ndb:\n  host: from-file\n")
+	tomlFile := writeFile(t, "config.toml", "port = \"9090\"\n[db]\nhost = \"from-toml\"\n")
+	secretsFile := writeFile(t, "secrets.env", "MASK_TEST_TOKEN=from-secrets\nMASK_TEST_DB_PASS=hunter2\n")
+
+	defaults := testConfig{LogLevel: "error", HTTPPort: "8080", Timeout: 5 * time.Second, Threshold: 0.6, Retries: 3, DB: db{Host: "h"}}
+	with := func(f func(*testConfig)) testConfig {
+		c := defaults
+		f(&c)
+		return c
+	}
+
+	cases := []struct {
+		desc    string
+		env     map[string]string
+		args    []string
+		secrets secrets.Provider
+		want    testConfig
+	}{
+		{
+			desc: "defaults",
+			args: []string{"--db-host", "h"},
+			want: defaults,
+		},
+		{
+			desc: "environment",
+			env:  map[string]string{"MASK_TEST_LOG_LEVEL": "debug", "PORT": "3000", "MASK_TEST_TIMEOUT": "1m", "MASK_TEST_VERBOSE": "true", "MASK_TEST_DB_HOST": "h"},
+			want: with(func(c *testConfig) { c.LogLevel, c.HTTPPort, c.Timeout, c.Verbose = "debug", "3000", time.Minute, true }),
+		},
+		{
+			desc: "flags override the environment",
+			env:  map[string]string{"MASK_TEST_RETRIES": "5", "MASK_TEST_DB_HOST": "env"},
+			args: []string{"--retries", "9", "--db-host", "h", "--threshold", "0.25"},
+			want: with(func(c *testConfig) { c.Retries, c.Threshold = 9, 0.25 }),
+		},
+		{
+			desc: "yaml file",
+			args: []string{"--config", yamlFile},
+			want: with(func(c *testConfig) { c.LogLevel, c.Retries, c.DB.Host = "info", 7, "from-file" }),
+		},
+		{
+			desc: "environment overrides the file",
+			env:  map[string]string{"MASK_TEST_CONFIG_FILE": yamlFile, "MASK_TEST_RETRIES": "1"},
+			want: with(func(c *testConfig) { c.LogLevel, c.Retries, c.DB.Host = "info", 1, "from-file" }),
+		},
+		{
+			desc: "toml file",
+			args: []string{"--config", tomlFile},
+			want: with(func(c *testConfig) { c.HTTPPort, c.DB.Host = "9090", "from-toml" }),
+		},
+		{
+			desc:    "secrets come from the provider",
+			env:     map[string]string{"MASK_TEST_TOKEN": "from-env", "MASK_TEST_DB_HOST": "h"},
+			secrets: secrets.NewFileProvider(secretsFile),
+			want:    with(func(c *testConfig) { c.Token, c.DB.Pass = "from-secrets", "hunter2" }),
+		},
+		{
+			desc: "secrets default to the environment",
+			env:  map[string]string{"MASK_TEST_TOKEN": "from-env", "MASK_TEST_DB_HOST": "h"},
+			want: with(func(c *testConfig) { c.Token = "from-env" }),
+		},
+		{
+			desc: "ignored fields",
+			env:  map[string]string{"MASK_TEST_SKIPPED": "x", "MASK_TEST_UNEXPOSED": "x", "MASK_TEST_DB_HOST": "h"},
+			want: defaults,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			for k, v := range c.env {
+				t.Setenv(k, v)
+			}
+
+			var got testConfig
+			if err := Load(&got, Options{Name: "test", Prefix: "MASK_TEST_", Args: c.args, Secrets: c.secrets}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	jsonFile := writeFile(t, "config.json", "{}")
+	unknownKey := writeFile(t, "config.yaml", "db:\n  name: mask\n")
+
+	cases := []struct {
+		desc string
+		dst  interface{}
+		args []string
+		want errors.Error
+		msg  string
+	}{
+		{"required", &testConfig{}, nil, ErrInvalidConfig, "db.host is required (env MASK_TEST_DB_HOST, flag --db-host)"},
+		{"oneof", &testConfig{}, []string{"--db-host", "h", "--log-level", "trace"}, ErrInvalidConfig, `log_level must be one of "debug,info,warn,error"`},
+		{"malformed int", &testConfig{}, []string{"--db-host", "h", "--retries", "many"}, ErrInvalidConfig, "retries from --retries"},
+		{"malformed duration", &testConfig{}, []string{"--db-host", "h", "--timeout", "5"}, ErrInvalidConfig, "timeout from --timeout"},
+		{"unknown file key", &testConfig{}, []string{"--config", unknownKey}, ErrInvalidConfig, "unknown key db.name"},
+		{"unsupported file", &testConfig{}, []string{"--config", jsonFile}, ErrUnsupportedFile, ""},
+		{"validator", &invalid{}, []string{"--retries", "-1"}, ErrInvalidConfig, "retries must not be negative"},
+	}
+
+	for _, c := range cases {
+		err := Load(c.dst, Options{Name: "test", Prefix: "MASK_TEST_", Args: c.args})
+		if err == nil {
+			t.Errorf("%s: got no error", c.desc)
+			continue
+		}
+		if e, ok := err.(errors.Error); !ok || !errors.Contains(e, c.want) || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%s: got %v, want %v mentioning %q", c.desc, err, c.want, c.msg)
+		}
+	}
+
+	if err := Load(testConfig{}, Options{}); err == nil {
+		t.Error("got no error loading into a struct value")
+	}
+}
+
+func TestLoadPrintConfig(t *testing.T) {
+	t.Setenv("MASK_TEST_TOKEN", "s3cret")
+
+	var (
+		cfg testConfig
+		out bytes.Buffer
+	)
+	err := Load(&cfg, Options{Name: "test", Prefix: "MASK_TEST_", Args: []string{"--print-config", "--db-host", "h"}, Out: &out})
+	if err != ErrPrinted {
+		t.Fatalf("got %v, want %v", err, ErrPrinted)
+	}
+
+	want := `log_level: error
+port: "8080"
+timeout: 5s
+threshold: 0.6
+retries: 3
+verbose: false
+token: '[REDACTED]'
+db:
+  host: h
+  pass: ""
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestLoadPositional(t *testing.T) {
+	var (
+		cfg  testConfig
+		args []string
+	)
+	if err := Load(&cfg, Options{Name: "test", Prefix: "MASK_TEST_", Args: []string{"--db-host", "h", "down", "2"}, Positional: &args}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"down", "2"}) {
+		t.Errorf("got %v, want [down 2]", args)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"Verbose":         "verbose",
+		"LogLevel":        "log_level",
+		"HTTPPort":        "http_port",
+		"SSLRootCert":     "ssl_root_cert",
+		"MaxOpenConns":    "max_open_conns",
+		"PseudonymKey":    "pseudonym_key",
+		"ConnectAttempts": "connect_attempts",
+	}
+	for in, want := range cases {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q): got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package dataloader coalesces the loads issued while one request is being
+// resolved into batches, so resolving the same field of N objects calls the
+// backend once instead of N times.
+//
+// A Loader caches every key it loaded and is meant to live as long as the
+// request that uses it.
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+const (
+	defWait     = time.Millisecond
+	defMaxBatch = 100
+)
+
+// ErrResultCount indicates a BatchFunc returned a different number of results
+// than the keys it was given.
+var ErrResultCount = errors.New("batch returned a result count different from its key count")
+
+// Result is the outcome of loading one key.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// BatchFunc loads keys, returning one result per key in the same order.
+type BatchFunc func(ctx context.Context, keys []string) []Result
+
+// Option configures a Loader.
+type Option func(*Loader)
+
+// Wait sets how long a batch collects keys after its first one, 1ms by
+// default.
+func Wait(d time.Duration) Option {
+	return func(l *Loader) {
+		l.wait = d
+	}
+}
+
+// MaxBatch dispatches a batch as soon as it holds n keys, 100 by default.
+func MaxBatch(n int) Option {
+	return func(l *Loader) {
+		l.maxBatch = n
+	}
+}
+
+// Loader batches and caches the loads of one request.
+type Loader struct {
+	fn       BatchFunc
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	calls   map[string]*call
+	pending *batch
+}
+
+type call struct {
+	done chan struct{}
+	res  Result
+}
+
+type batch struct {
+	keys       []string
+	calls      []*call
+	dispatched bool
+}
+
+// New returns a loader calling fn.
+func New(fn BatchFunc, opts ...Option) *Loader {
+	l := &Loader{
+		fn:       fn,
+		wait:     defWait,
+		maxBatch: defMaxBatch,
+		calls:    map[string]*call{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load returns the value of key, waiting for the batch it joins. The batch
+// runs with the context of the load that opened it.
+func (l *Loader) Load(ctx context.Context, key string) (interface{}, error) {
+	l.mu.Lock()
+	c, ok := l.calls[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		l.calls[key] = c
+
+		b := l.pending
+		if b == nil {
+			b = &batch{}
+			l.pending = b
+			time.AfterFunc(l.wait, func() { l.dispatch(ctx, b) })
+		}
+		b.keys = append(b.keys, key)
+		b.calls = append(b.calls, c)
+		if len(b.keys) >= l.maxBatch {
+			go l.dispatch(ctx, b)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.res.Value, c.res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dispatch runs b unless it already ran.
+func (l *Loader) dispatch(ctx context.Context, b *batch) {
+	l.mu.Lock()
+	if b.dispatched {
+		l.mu.Unlock()
+		return
+	}
+	b.dispatched = true
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	results := l.fn(ctx, b.keys)
+	for i, c := range b.calls {
+		if len(results) != len(b.keys) {
+			c.res = Result{Err: ErrResultCount}
+		} else {
+			c.res = results[i]
+		}
+		close(c.done)
+	}
+}
@@ -0,0 +1,81 @@
+package dataloader
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// upper records its batches and loads every key as its upper case.
+type upper struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (u *upper) load(_ context.Context, keys []string) []Result {
+	u.mu.Lock()
+	u.batches = append(u.batches, append([]string(nil), keys...))
+	u.mu.Unlock()
+
+	results := make([]Result, len(keys))
+	for i, k := range keys {
+		results[i] = Result{Value: strings.ToUpper(k)}
+	}
+	return results
+}
+
+func loadAll(t *testing.T, l *Loader, keys ...string) {
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.Load(context.Background(), k)
+			if err != nil || v != strings.ToUpper(k) {
+				t.Errorf("load %q: got %v, %v", k, v, err)
+			}
+		}(k)
+	}
+	wg.Wait()
+}
+
+func TestLoadBatches(t *testing.T) {
+	u := &upper{}
+	l := New(u.load, Wait(10*time.Millisecond))
+
+	loadAll(t, l, "a", "b", "c", "a")
+	if len(u.batches) != 1 {
+		t.Fatalf("got batches %v, want one", u.batches)
+	}
+	got := u.batches[0]
+	sort.Strings(got)
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("got keys %v, want a,b,c once each", got)
+	}
+
+	loadAll(t, l, "b", "d")
+	if len(u.batches) != 2 || strings.Join(u.batches[1], ",") != "d" {
+		t.Errorf("got batches %v, want cached b and a batch of d", u.batches)
+	}
+}
+
+func TestLoadMaxBatch(t *testing.T) {
+	u := &upper{}
+	l := New(u.load, Wait(time.Hour), MaxBatch(2))
+
+	loadAll(t, l, "a", "b")
+	if len(u.batches) != 1 || len(u.batches[0]) != 2 {
+		t.Errorf("got batches %v, want one full batch", u.batches)
+	}
+}
+
+func TestLoadResultCount(t *testing.T) {
+	l := New(func(context.Context, []string) []Result { return nil })
+
+	if _, err := l.Load(context.Background(), "a"); err != ErrResultCount {
+		t.Errorf("got %v, want %v", err, ErrResultCount)
+	}
+}
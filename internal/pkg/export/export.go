@@ -0,0 +1,130 @@
+// Package export writes rows as CSV or newline delimited JSON, one record at
+// a time, so whole tables never have to be buffered in memory.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	csvContentType    = "text/csv; charset=utf-8"
+	ndjsonContentType = "application/x-ndjson"
+
+	// flushEvery is the number of records written between two flushes of the
+	// underlying http.ResponseWriter.
+	flushEvery = 500
+)
+
+// ErrUnsupportedFormat indicates the requested export format is unknown.
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Record is a single exportable row.
+type Record interface {
+	// CSVRecord returns the row as CSV fields, in header order.
+	CSVRecord() []string
+}
+
+// Writer writes records in a given format.
+type Writer interface {
+	Write(Record) error
+	Flush() error
+}
+
+// ParseFormat resolves the export format from the format query parameter,
+// falling back to the Accept header and finally to CSV.
+func ParseFormat(format, accept string) (string, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV, FormatNDJSON:
+		return strings.ToLower(format), nil
+	case "":
+	default:
+		return "", ErrUnsupportedFormat
+	}
+
+	if strings.Contains(accept, ndjsonContentType) {
+		return FormatNDJSON, nil
+	}
+	return FormatCSV, nil
+}
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	if format == FormatNDJSON {
+		return ndjsonContentType
+	}
+	return csvContentType
+}
+
+// NewWriter returns a Writer emitting format to w. CSV output starts with
+// header. If w is an http.Flusher it is flushed periodically.
+func NewWriter(w io.Writer, format string, header []string) (Writer, error) {
+	flusher, _ := w.(http.Flusher)
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw, flusher: flusher}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), flusher: flusher}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	flusher http.Flusher
+	n       int
+}
+
+func (c *csvWriter) Write(r Record) error {
+	if err := c.w.Write(r.CSVRecord()); err != nil {
+		return err
+	}
+	if c.n++; c.n%flushEvery == 0 {
+		return c.Flush()
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	if c.flusher != nil {
+		c.flusher.Flush()
+	}
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+	n       int
+}
+
+func (j *ndjsonWriter) Write(r Record) error {
+	if err := j.enc.Encode(r); err != nil {
+		return err
+	}
+	if j.n++; j.n%flushEvery == 0 {
+		return j.Flush()
+	}
+	return nil
+}
+
+func (j *ndjsonWriter) Flush() error {
+	if j.flusher != nil {
+		j.flusher.Flush()
+	}
+	return nil
+}
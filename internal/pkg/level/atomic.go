@@ -0,0 +1,74 @@
+package level
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// ErrInvalidLevel indicates a level name is not one of debug, info, warn,
+// error or none.
+var ErrInvalidLevel = errors.New("invalid log level")
+
+var levelNames = map[string]level{
+	"debug": levelError | levelWarn | levelInfo | levelDebug,
+	"info":  levelError | levelWarn | levelInfo,
+	"warn":  levelError | levelWarn,
+	"error": levelError,
+	"none":  0,
+}
+
+// Parse returns the Option allowing the named level and every more severe
+// one.
+func Parse(name string) (Option, error) {
+	l, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrInvalidLevel
+	}
+	return allowed(l), nil
+}
+
+// AtomicLevel is a level that can be changed while loggers filtering on it
+// are in use.
+type AtomicLevel struct {
+	allowed uint32
+	name    atomic.Value
+}
+
+// NewAtomicLevel returns an AtomicLevel set to the named level.
+func NewAtomicLevel(name string) (*AtomicLevel, error) {
+	a := &AtomicLevel{}
+	if err := a.Set(name); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Set changes the level to the named one.
+func (a *AtomicLevel) Set(name string) error {
+	name = strings.ToLower(name)
+	l, ok := levelNames[name]
+	if !ok {
+		return ErrInvalidLevel
+	}
+	atomic.StoreUint32(&a.allowed, uint32(l))
+	a.name.Store(name)
+	return nil
+}
+
+// String returns the name of the current level.
+func (a *AtomicLevel) String() string {
+	name, _ := a.name.Load().(string)
+	return name
+}
+
+func (a *AtomicLevel) level() level {
+	return level(atomic.LoadUint32(&a.allowed))
+}
+
+// AllowAtomic makes the filter consult a on every log event, so that changes
+// made with a.Set apply immediately.
+func AllowAtomic(a *AtomicLevel) Option {
+	return func(l *logger) { l.atomic = a }
+}
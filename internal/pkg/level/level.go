@@ -26,11 +26,13 @@ func Debug(logger log.Logger) log.Logger {
 // the Option functions for a detailed description of how to configure levels.
 // If no options are provided, all leveled log events created with Debug,
 // Info, Warn or Error helper methods are squelched and non-leveled log
-// events are passed to next unmodified.
+// events are passed to next with the values of SensitiveKeys redacted.
 func NewFilter(next log.Logger, options ...Option) log.Logger {
 	l := &logger{
-		next: next,
+		next:   next,
+		redact: map[string]struct{}{},
 	}
+	Redact(SensitiveKeys...)(l)
 	for _, option := range options {
 		option(l)
 	}
@@ -40,17 +42,24 @@ func NewFilter(next log.Logger, options ...Option) log.Logger {
 type logger struct {
 	next           log.Logger
 	allowed        level
+	atomic         *AtomicLevel
+	redact         map[string]struct{}
 	squelchNoLevel bool
 	errNotAllowed  error
 	errNoLevel     error
 }
 
 func (l *logger) Log(keyvals ...interface{}) error {
+	allowed := l.allowed
+	if l.atomic != nil {
+		allowed = l.atomic.level()
+	}
+
 	var hasLevel, levelAllowed bool
 	for i := 1; i < len(keyvals); i += 2 {
 		if v, ok := keyvals[i].(*levelValue); ok {
 			hasLevel = true
-			levelAllowed = l.allowed&v.level != 0
+			levelAllowed = allowed&v.level != 0
 			break
 		}
 	}
@@ -60,7 +69,7 @@ func (l *logger) Log(keyvals ...interface{}) error {
 	if hasLevel && !levelAllowed {
 		return l.errNotAllowed
 	}
-	return l.next.Log(keyvals...)
+	return l.next.Log(l.redactKeyvals(keyvals)...)
 }
 
 // Option sets a parameter for the leveled logger.
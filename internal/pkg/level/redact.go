@@ -0,0 +1,51 @@
+package level
+
+import "strings"
+
+// Redacted replaces the value of sensitive keys in filtered log events.
+const Redacted = "[REDACTED]"
+
+// SensitiveKeys are the keys whose values NewFilter always redacts, compared
+// case-insensitively.
+var SensitiveKeys = []string{
+	"password",
+	"pass",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"dsn",
+	"pseudonymKey",
+}
+
+// Redact adds keys to the SensitiveKeys redacted by the filter.
+func Redact(keys ...string) Option {
+	return func(l *logger) {
+		for _, k := range keys {
+			l.redact[strings.ToLower(k)] = struct{}{}
+		}
+	}
+}
+
+// redactKeyvals returns keyvals with the value of every sensitive key
+// replaced. keyvals is copied only when something has to be redacted.
+func (l *logger) redactKeyvals(keyvals []interface{}) []interface{} {
+	var redacted []interface{}
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		k, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		if _, ok := l.redact[strings.ToLower(k)]; !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = append(make([]interface{}, 0, len(keyvals)), keyvals...)
+		}
+		redacted[i+1] = Redacted
+	}
+	if redacted == nil {
+		return keyvals
+	}
+	return redacted
+}
@@ -0,0 +1,90 @@
+package level
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// capture records the keyvals of the last event logged.
+type capture struct {
+	keyvals []interface{}
+}
+
+func (c *capture) Log(keyvals ...interface{}) error {
+	c.keyvals = keyvals
+	return nil
+}
+
+func TestRedact(t *testing.T) {
+	cases := []struct {
+		desc    string
+		options []Option
+		keyvals []interface{}
+		want    []interface{}
+	}{
+		{
+			desc:    "nothing sensitive",
+			keyvals: []interface{}{"method", "Query", "err", "boom"},
+			want:    []interface{}{"method", "Query", "err", "boom"},
+		},
+		{
+			desc:    "sensitive keys",
+			keyvals: []interface{}{"user", "postgres", "pass", "hunter2", "token", "abc"},
+			want:    []interface{}{"user", "postgres", "pass", Redacted, "token", Redacted},
+		},
+		{
+			desc:    "keys compared case-insensitively",
+			keyvals: []interface{}{"Authorization", "Bearer abc", "PSEUDONYMKEY", "k"},
+			want:    []interface{}{"Authorization", Redacted, "PSEUDONYMKEY", Redacted},
+		},
+		{
+			desc:    "extra keys",
+			options: []Option{Redact("SSLKey")},
+			keyvals: []interface{}{"sslkey", "-----BEGIN", "host", "db"},
+			want:    []interface{}{"sslkey", Redacted, "host", "db"},
+		},
+		{
+			desc:    "values are not keys",
+			keyvals: []interface{}{"msg", "password", "n", 1},
+			want:    []interface{}{"msg", "password", "n", 1},
+		},
+		{
+			desc:    "non string keys",
+			keyvals: []interface{}{1, "secret", "secret", "s"},
+			want:    []interface{}{1, "secret", "secret", Redacted},
+		},
+		{
+			desc:    "dangling key",
+			keyvals: []interface{}{"a", 1, "secret"},
+			want:    []interface{}{"a", 1, "secret"},
+		},
+	}
+
+	for _, c := range cases {
+		next := &capture{}
+		logger := NewFilter(next, append([]Option{AllowAll()}, c.options...)...)
+
+		keyvals := append([]interface{}(nil), c.keyvals...)
+		if err := logger.Log(keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(next.keyvals, c.want) {
+			t.Errorf("%s: got %v, want %v", c.desc, next.keyvals, c.want)
+		}
+		if !reflect.DeepEqual(keyvals, c.keyvals) {
+			t.Errorf("%s: the caller's keyvals were modified: %v", c.desc, keyvals)
+		}
+	}
+}
+
+func TestRedactLeveled(t *testing.T) {
+	next := &capture{}
+	logger := log.With(NewFilter(next, AllowInfo()), "dsn", "postgres://u:p@h/db")
+
+	Error(logger).Log("err", "boom")
+	if want := []interface{}{Key(), ErrorValue(), "dsn", Redacted, "err", "boom"}; !reflect.DeepEqual(next.keyvals, want) {
+		t.Errorf("got %v, want %v", next.keyvals, want)
+	}
+}
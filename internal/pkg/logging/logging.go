@@ -0,0 +1,173 @@
+// Package logging builds the leveled JSON logger shared by every command and
+// ties log lines to the HTTP request or gRPC call they were emitted for.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/cage1016/mask/internal/pkg/auth"
+	"github.com/cage1016/mask/internal/pkg/level"
+)
+
+const (
+	// RequestIDHeader is read from incoming requests and echoed on responses.
+	RequestIDHeader = "X-Request-ID"
+	// LevelPath is where AdminHandler serves the log level.
+	LevelPath = "/admin/log_level"
+
+	maxRequestIDLength = 128
+)
+
+type contextKey int
+
+const contextKeyRequestID contextKey = iota
+
+// New returns a JSON logger writing to w, filtered at the named level. The
+// returned AtomicLevel changes the level of the logger while it is in use.
+func New(w io.Writer, name string) (log.Logger, *level.AtomicLevel, error) {
+	lvl, err := level.NewAtomicLevel(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger := log.NewJSONLogger(log.NewSyncWriter(w))
+	logger = level.NewFilter(logger, level.AllowAtomic(lvl))
+	logger = log.With(logger, "timestamp", log.DefaultTimestampUTC)
+	logger = log.With(logger, "caller", log.DefaultCaller)
+	return logger, lvl, nil
+}
+
+// WithRequestID returns logger annotated with the request ID carried by ctx,
+// or logger itself when there is none.
+func WithRequestID(ctx context.Context, logger log.Logger) log.Logger {
+	if id := RequestID(ctx); id != "" {
+		return log.With(logger, "request_id", id)
+	}
+	return logger
+}
+
+// RequestID returns the request ID carried by ctx.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKeyRequestID).(string)
+	return id
+}
+
+// RequestIDHandler wraps next so that every request carries a request ID in
+// its context and response headers. The caller's RequestIDHeader is reused
+// when present, otherwise a random ID is generated.
+func RequestIDHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyRequestID, id)))
+	})
+}
+
+// UnaryServerInterceptor is RequestIDHandler for unary gRPC calls, reusing
+// the caller's RequestIDHeader metadata when present.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withIncomingRequestID(ctx), req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, requestIDStream{ss, withIncomingRequestID(ss.Context())})
+	}
+}
+
+// requestIDStream carries the context holding the request ID of its call.
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s requestIDStream) Context() context.Context {
+	return s.ctx
+}
+
+func withIncomingRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDHeader); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if id == "" || len(id) > maxRequestIDLength {
+		id = newRequestID()
+	}
+	return context.WithValue(ctx, contextKeyRequestID, id)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler returns a handler reporting lvl on GET and changing it on PUT
+// with a {"level": "debug|info|warn|error|none"} body. Requests must carry
+// token as a bearer Authorization header, as checked by auth.Handler.
+func LevelHandler(lvl *level.AtomicLevel, token string, logger log.Logger) http.Handler {
+	return auth.Handler(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body levelBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			prev := lvl.String()
+			if err := lvl.Set(body.Level); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			level.Warn(WithRequestID(r.Context(), logger)).Log("method", "LevelHandler", "from", prev, "to", lvl.String())
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		json.NewEncoder(w).Encode(levelBody{Level: lvl.String()})
+	}))
+}
+
+// AdminHandler serves LevelHandler on LevelPath in front of next. Without a
+// token the level cannot be changed at runtime and next is returned as is.
+func AdminHandler(next http.Handler, lvl *level.AtomicLevel, token string, logger log.Logger) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(LevelPath, LevelHandler(lvl, token, logger))
+	mux.Handle("/", next)
+	return mux
+}
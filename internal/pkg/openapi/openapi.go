@@ -0,0 +1,303 @@
+// Package openapi builds OpenAPI 3 documents from the request and response
+// types of the services, so the published spec follows the code it
+// describes instead of annotations next to it.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cage1016/mask/internal/pkg/responses"
+)
+
+// Version is the OpenAPI version of the documents.
+const Version = "3.0.3"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version"`
+	Contact     *Contact `json:"contact,omitempty"`
+	License     *License `json:"license,omitempty"`
+}
+
+type Contact struct {
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type License struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower case method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema is the subset of the OpenAPI schema object the services need.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Route describes an endpoint served under both /api and /api/v2.
+type Route struct {
+	Method string
+	// Path is the bone pattern of the route below the version prefix, e.g.
+	// /pharmacies/webhooks/:id.
+	Path    string
+	ID      string
+	Summary string
+	Tags    []string
+	// Query lists the query parameters; path parameters follow from Path.
+	Query []Parameter
+	// Request is a value of the JSON request body, nil for none.
+	Request interface{}
+	// Response is a value of the endpoint response. Its StatusCode, Response
+	// and Envelope methods give the documented status and bodies, the way
+	// the JSON encoders of the transports use them.
+	Response interface{}
+	// Produces lists the media types of a response streamed by its own
+	// encoder instead of a JSON body.
+	Produces []string
+	// Errors lists the error statuses of the route.
+	Errors []int
+}
+
+// Spec describes the routes of a service.
+type Spec struct {
+	Info   Info
+	Tags   []Tag
+	Routes []Route
+	// Types overrides the schema of types rendered by custom marshalers.
+	Types map[reflect.Type]*Schema
+}
+
+type statusCoder interface {
+	StatusCode() int
+}
+
+// Document builds the document of s.
+func (s Spec) Document() *Document {
+	g := &generator{types: s.Types, schemas: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.Info,
+		Tags:    s.Tags,
+		Paths:   map[string]PathItem{},
+	}
+
+	for _, r := range s.Routes {
+		for _, v := range []int{1, 2} {
+			path, params := pathParams(r.Path)
+			if v == 2 {
+				path = strings.TrimSuffix(responses.V2Prefix, "/") + path
+			} else {
+				path = "/api" + path
+			}
+
+			op := &Operation{
+				OperationID: r.ID,
+				Summary:     r.Summary,
+				Tags:        r.Tags,
+				Parameters:  append(params, r.Query...),
+				Responses:   map[string]*Response{},
+			}
+			if v == 2 {
+				op.OperationID += "V2"
+			}
+			if r.Request != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]MediaType{"application/json": {Schema: g.schema(reflect.ValueOf(r.Request))}},
+				}
+			}
+			g.responses(op, r, v, s.Info.Version)
+
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = PathItem{}
+			}
+			doc.Paths[path][strings.ToLower(r.Method)] = op
+		}
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// responses documents the success and error responses of r on version v.
+func (g *generator) responses(op *Operation, r Route, v int, apiVersion string) {
+	code := http.StatusOK
+	if sc, ok := r.Response.(statusCoder); ok {
+		code = sc.StatusCode()
+	}
+
+	res := &Response{Description: http.StatusText(code)}
+	switch {
+	case len(r.Produces) > 0:
+		res.Content = map[string]MediaType{}
+		for _, mt := range r.Produces {
+			res.Content[mt] = MediaType{Schema: &Schema{Type: "string"}}
+		}
+	case r.Response != nil && code != http.StatusNoContent:
+		var body interface{} = r.Response
+		if v == 2 {
+			if er, ok := r.Response.(responses.Enveloper); ok {
+				body = er.Envelope()
+			} else {
+				body = responses.Envelope{APIVersion: apiVersion, Data: r.Response}
+			}
+		} else if ar, ok := r.Response.(responses.Responser); ok {
+			body = ar.Response()
+		}
+		res.Content = map[string]MediaType{"application/json": {Schema: g.schema(reflect.ValueOf(body))}}
+		if gr, ok := r.Response.(responses.GeoJSONResponser); ok {
+			res.Content["application/geo+json"] = MediaType{Schema: g.schema(reflect.ValueOf(gr.GeoJSON()))}
+		}
+	}
+	op.Responses[strconv.Itoa(code)] = res
+
+	var body interface{} = responses.ErrorRes{}
+	if v == 2 {
+		body = responses.Envelope{APIVersion: apiVersion, Error: &responses.ErrorResItem{}}
+	}
+	for _, code := range r.Errors {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]MediaType{"application/json": {Schema: g.schema(reflect.ValueOf(body))}},
+		}
+	}
+}
+
+// pathParams converts the bone pattern p to an OpenAPI path and its
+// parameters.
+func pathParams(p string) (string, []Parameter) {
+	var params []Parameter
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		if strings.HasPrefix(s, ":") {
+			name := s[1:]
+			parts[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// Merge combines docs into one document described by info, versioned by the
+// versions of docs unless info has one. Paths, tags and schemas of later
+// documents do not replace those of earlier ones.
+func Merge(info Info, docs ...*Document) *Document {
+	if info.Version == "" {
+		var versions []string
+		for _, doc := range docs {
+			if v := doc.Info.Title + " " + doc.Info.Version; doc.Info.Version != "" {
+				versions = append(versions, v)
+			}
+		}
+		info.Version = strings.Join(versions, ", ")
+	}
+
+	out := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+
+	tags := map[string]bool{}
+	for _, doc := range docs {
+		for _, t := range doc.Tags {
+			if !tags[t.Name] {
+				tags[t.Name] = true
+				out.Tags = append(out.Tags, t)
+			}
+		}
+		for p, item := range doc.Paths {
+			if _, ok := out.Paths[p]; !ok {
+				out.Paths[p] = item
+			}
+		}
+		for name, s := range doc.Components.Schemas {
+			if _, ok := out.Components.Schemas[name]; !ok {
+				out.Components.Schemas[name] = s
+			}
+		}
+	}
+	return out
+}
+
+// Handler serves doc as JSON.
+func Handler(doc *Document) http.Handler {
+	b, err := json.Marshal(doc)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(b)
+	})
+}
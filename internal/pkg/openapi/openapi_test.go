@@ -0,0 +1,114 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cage1016/mask/internal/pkg/responses"
+)
+
+type item struct {
+	ID    string   `json:"id"`
+	Tags  []string `json:"tags,omitempty"`
+	Point [2]float64
+	skip  string
+}
+
+type itemsResponse struct {
+	Items []item `json:"items"`
+	Err   error  `json:"-"`
+}
+
+func (r itemsResponse) Response() interface{} {
+	return responses.DataRes{APIVersion: "1.0.0", Data: r}
+}
+
+func (r itemsResponse) Envelope() responses.Envelope {
+	return responses.Envelope{APIVersion: "1.0.0", Data: r.Items, Paging: responses.NewPaging(0, 0, 0, 0)}
+}
+
+type removeResponse struct{}
+
+func (r removeResponse) StatusCode() int { return http.StatusNoContent }
+
+func testDocument() *Document {
+	return Spec{
+		Info: Info{Title: "items", Version: "1.0.0"},
+		Routes: []Route{
+			{Method: http.MethodGet, Path: "/items", ID: "listItems", Response: itemsResponse{}, Errors: []int{http.StatusInternalServerError}},
+			{Method: http.MethodDelete, Path: "/items/:id", ID: "removeItem", Response: removeResponse{}},
+		},
+	}.Document()
+}
+
+func TestDocument(t *testing.T) {
+	doc := testDocument()
+
+	item := doc.Components.Schemas["openapi.item"]
+	if item == nil {
+		t.Fatalf("got schemas %v, want openapi.item", doc.Components.Schemas)
+	}
+	if _, ok := item.Properties["skip"]; ok {
+		t.Error("item: got the unexported field")
+	}
+	if p := item.Properties["Point"]; p == nil || p.Type != "array" || *p.MinItems != 2 || *p.MaxItems != 2 {
+		t.Errorf("item: got Point %+v, want an array of 2", p)
+	}
+	if _, ok := item.Properties["tags"]; !ok {
+		t.Error("item: got no omitempty tags")
+	}
+
+	v1 := doc.Paths["/api/items"]["get"].Responses["200"].Content["application/json"].Schema
+	if d := v1.Properties["data"]; d == nil || d.Ref != "#/components/schemas/openapi.itemsResponse" {
+		t.Errorf("v1: got data %+v", d)
+	}
+
+	v2 := doc.Paths["/api/v2/items"]["get"]
+	if v2.OperationID != "listItemsV2" {
+		t.Errorf("v2: got operation id %s", v2.OperationID)
+	}
+	env := v2.Responses["200"].Content["application/json"].Schema
+	if d := env.Properties["data"]; d == nil || d.Type != "array" || d.Items.Ref != "#/components/schemas/openapi.item" {
+		t.Errorf("v2: got data %+v", d)
+	}
+	if _, ok := env.Properties["paging"]; !ok {
+		t.Error("v2: got no paging")
+	}
+	if _, ok := env.Properties["error"]; ok {
+		t.Error("v2: got an error on success")
+	}
+	if e := v2.Responses["500"].Content["application/json"].Schema; e.Properties["error"] == nil || e.Properties["data"] != nil {
+		t.Errorf("v2: got error %+v", e)
+	}
+
+	remove := doc.Paths["/api/items/{id}"]["delete"]
+	if remove == nil {
+		t.Fatalf("got paths %v", doc.Paths)
+	}
+	if res := remove.Responses["204"]; res == nil || res.Content != nil {
+		t.Errorf("remove: got responses %+v, want 204 without content", remove.Responses)
+	}
+	if want := []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}; !reflect.DeepEqual(remove.Parameters, want) {
+		t.Errorf("remove: got parameters %+v", remove.Parameters)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	other := Spec{
+		Info:   Info{Title: "others", Version: "2.0.0"},
+		Tags:   []Tag{{Name: "others"}},
+		Routes: []Route{{Method: http.MethodGet, Path: "/others", ID: "listOthers", Response: itemsResponse{}}},
+	}.Document()
+
+	doc := Merge(Info{Title: "all"}, testDocument(), other)
+	if doc.Info.Version != "items 1.0.0, others 2.0.0" {
+		t.Errorf("got version %q", doc.Info.Version)
+	}
+	if len(doc.Paths) != 6 || len(doc.Tags) != 1 {
+		t.Errorf("got %d paths and tags %v, want 6 paths and the others tag", len(doc.Paths), doc.Tags)
+	}
+	if doc := Merge(Info{Title: "all", Version: "3.0.0"}, other); doc.Info.Version != "3.0.0" {
+		t.Errorf("got version %q, want the given one", doc.Info.Version)
+	}
+}
@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// appPath marks the packages of the services, whose schemas are named after
+// the service as well, e.g. pharmacy.model.Pharmacy.
+const appPath = "/internal/app/"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// generator collects the named schemas of a document.
+type generator struct {
+	types   map[reflect.Type]*Schema
+	schemas map[string]*Schema
+}
+
+// schema returns the schema of v. Named structs are referenced from the
+// components; structs holding interfaces, such as the response envelopes,
+// are inlined from the value, since their schema depends on what it holds.
+func (g *generator) schema(v reflect.Value) *Schema {
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Zero(t)
+		}
+	}
+
+	if s, ok := g.types[t]; ok {
+		c := *s
+		return &c
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Interface:
+		if v.IsNil() {
+			return &Schema{}
+		}
+		return g.schema(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		elem := reflect.Zero(t.Elem())
+		if v.Len() > 0 {
+			elem = v.Index(0)
+		}
+		s := &Schema{Type: "array", Items: g.schema(elem)}
+		if t.Kind() == reflect.Array {
+			n := t.Len()
+			s.MinItems, s.MaxItems = &n, &n
+		}
+		return s
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(reflect.Zero(t.Elem()))}
+	case reflect.Struct:
+		if dynamic(t, map[reflect.Type]bool{}) {
+			return g.object(v, true)
+		}
+		if t.Name() == "" {
+			return g.object(v, false)
+		}
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.object(reflect.Zero(t), false)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// object returns the object schema of the struct v. Values leave out the
+// omitempty pointers and interfaces they do not set, as encoding/json does.
+func (g *generator) object(v reflect.Value, value bool) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(s, v, value)
+	return s
+}
+
+func (g *generator) fields(s *Schema, v reflect.Value, value bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, omitempty, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft, fv = ft.Elem(), reflect.Zero(ft.Elem())
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(s, fv, value)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if value && omitempty && (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+		s.Properties[name] = g.schema(fv)
+	}
+}
+
+// jsonName returns the JSON name of f, empty for the Go name, and whether
+// encoding/json writes f at all.
+func jsonName(f reflect.StructField) (name string, omitempty, ok bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, o := range parts[1:] {
+		if o == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, true
+}
+
+// dynamic reports whether the schema of t depends on its value, i.e. whether
+// t holds an interface other than through a skipped field.
+func dynamic(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		if t == timeType {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if _, _, ok := jsonName(f); ok && dynamic(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaName names the schema of t after its package: the service and layer
+// for the services, e.g. pharmacy.endpoints.QueryRequest, the package
+// otherwise, e.g. responses.Paging.
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.Index(pkg, appPath); i >= 0 {
+		pkg = strings.Replace(pkg[i+len(appPath):], "/", ".", -1)
+	} else if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return pkg + "." + t.Name()
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// maxIdentifierLength is the length past which PostgreSQL truncates
+// identifiers.
+const maxIdentifierLength = 63
+
+var (
+	// ErrTableNotAllowed indicates a table name outside the allowlist of the
+	// repository splicing it into SQL.
+	ErrTableNotAllowed = errors.New("table name not allowed")
+	// ErrInvalidIdentifier indicates a name no SQL identifier can hold.
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+)
+
+// QuoteIdentifier quotes name for use as an identifier in SQL, doubling any
+// embedded quotes. It must wrap every identifier spliced into a query. Names
+// that are empty or contain a NUL byte, which PostgreSQL would cut the
+// identifier at, return ErrInvalidIdentifier.
+func QuoteIdentifier(name string) (string, error) {
+	if name == "" || strings.IndexByte(name, 0) >= 0 {
+		return "", ErrInvalidIdentifier
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`, nil
+}
+
+// Tables allowlists the names of the tables a repository splices into SQL,
+// such as the snapshot tables picked at runtime.
+type Tables struct {
+	patterns []*regexp.Regexp
+}
+
+// NewTables allowlists the table names matching any of the patterns in
+// full. It panics on invalid patterns, like regexp.MustCompile.
+func NewTables(patterns ...string) Tables {
+	t := Tables{patterns: make([]*regexp.Regexp, len(patterns))}
+	for i, p := range patterns {
+		t.patterns[i] = regexp.MustCompile(`^(?:` + p + `)$`)
+	}
+	return t
+}
+
+// Quote returns name quoted by QuoteIdentifier, or ErrTableNotAllowed when
+// it is not allowlisted.
+func (t Tables) Quote(name string) (string, error) {
+	if name == "" || len(name) > maxIdentifierLength {
+		return "", ErrTableNotAllowed
+	}
+	for _, p := range t.patterns {
+		if p.MatchString(name) {
+			if quoted, err := QuoteIdentifier(name); err == nil {
+				return quoted, nil
+			}
+		}
+	}
+	return "", ErrTableNotAllowed
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+// unquote reverses QuoteIdentifier, failing when the identifier ends before
+// the closing quote, i.e. when name could break out of it.
+func unquote(t *testing.T, quoted string) string {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		t.Fatalf("%q is not quoted", quoted)
+	}
+	inner := quoted[1 : len(quoted)-1]
+	if strings.Contains(strings.Replace(inner, `""`, "", -1), `"`) {
+		t.Fatalf("%q closes its quotes early", quoted)
+	}
+	if strings.IndexByte(inner, 0) >= 0 {
+		t.Fatalf("%q contains a NUL byte", quoted)
+	}
+	return strings.Replace(inner, `""`, `"`, -1)
+}
+
+func FuzzQuoteIdentifier(f *testing.F) {
+	tables := NewTables(`pharmacy_[0-9]{4}_[0-9]{4}`, `feedback`)
+	// every name matches the pattern of all, leaving the rest to Quote
+	all := NewTables(`(?s).*`)
+
+	f.Add("pharmacy_2020_0301")
+	f.Add("feedback")
+	f.Add(`pharmacy_1"; drop table feedback; --`)
+	f.Add(`feedback" union select 1 --`)
+	f.Add("pharmacy_1\x00; drop")
+	f.Add("pharmacy_1\n")
+	f.Fuzz(func(t *testing.T, name string) {
+		invalid := name == "" || strings.IndexByte(name, 0) >= 0
+		quoted, err := QuoteIdentifier(name)
+		switch {
+		case invalid && err != ErrInvalidIdentifier:
+			t.Fatalf("QuoteIdentifier(%q): got %q, %v, want %v", name, quoted, err, ErrInvalidIdentifier)
+		case !invalid && err != nil:
+			t.Fatalf("QuoteIdentifier(%q): %v", name, err)
+		case !invalid:
+			if got := unquote(t, quoted); got != name {
+				t.Fatalf("QuoteIdentifier(%q) names %q", name, got)
+			}
+		}
+
+		if _, err := all.Quote(name); (err == nil) == (invalid || len(name) > maxIdentifierLength) {
+			t.Fatalf("Quote(%q): got %v", name, err)
+		}
+
+		quoted, err = tables.Quote(name)
+		if err != nil {
+			return
+		}
+		if quoted != `"`+name+`"` {
+			t.Fatalf("allowlisted %q quoted as %s", name, quoted)
+		}
+		if name != "feedback" && !strings.HasPrefix(name, "pharmacy_") {
+			t.Fatalf("%q passed the allowlist", name)
+		}
+	})
+}
@@ -1,187 +1,151 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/postgres"
+	"github.com/XSAM/otelsql"
+	"github.com/go-kit/kit/log"
 	"github.com/gomurphyx/sqlx"
-	_ "github.com/lib/pq" // required for SQL access
-	migrate "github.com/rubenv/sql-migrate"
+	_ "github.com/jackc/pgx/v4/stdlib" // registers the pgx driver
+	_ "github.com/lib/pq"              // required for SQL access
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/level"
+)
+
+const (
+	// DriverCloudSQL connects through the Cloud SQL proxy dialer, Host is the
+	// project:region:instance connection name.
+	DriverCloudSQL = "cloudsql"
+	// DriverPostgres connects with lib/pq over TCP or a unix socket.
+	DriverPostgres = "postgres"
+	// DriverPgx connects with pgx over TCP or a unix socket.
+	DriverPgx = "pgx"
 )
 
+var driverNames = map[string]string{
+	DriverCloudSQL: "cloudsqlpostgres",
+	DriverPostgres: "postgres",
+	DriverPgx:      "pgx",
+}
+
+// ErrUnsupportedDriver indicates Config.Driver is not one of the Driver*
+// constants.
+var ErrUnsupportedDriver = errors.New("unsupported postgres driver")
+
 // Config defines the options that are used when connecting to a PostgreSQL instance
 type Config struct {
-	Host        string
-	Port        string
-	User        string
-	Pass        string
-	Name        string
-	SSLMode     string
-	SSLCert     string
-	SSLKey      string
-	SSLRootCert string
+	Driver      string `config:"driver" default:"cloudsql" oneof:"cloudsql,postgres,pgx"`
+	Host        string `config:"host" required:"true"`
+	Port        string `config:"port" default:"5432"`
+	User        string `config:"user" default:"postgres"`
+	Pass        string `config:"pass" secret:"true"`
+	Name        string `config:"name" required:"true"`
+	SSLMode     string `config:"ssl_mode" default:"disable" oneof:"disable,allow,prefer,require,verify-ca,verify-full"`
+	SSLCert     string `config:"ssl_cert"`
+	SSLKey      string `config:"ssl_key"`
+	SSLRootCert string `config:"ssl_root_cert"`
+
+	// Migrations is one of MigrateApply, MigrateVerify or MigrateIgnore.
+	Migrations string `config:"migrations" default:"apply" oneof:"apply,verify,ignore"`
+
+	MaxOpenConns    int           `config:"max_open_conns" default:"10"`
+	MaxIdleConns    int           `config:"max_idle_conns" default:"5"`
+	ConnMaxLifetime time.Duration `config:"conn_max_lifetime" default:"30m"`
+	ConnMaxIdleTime time.Duration `config:"conn_max_idle_time" default:"5m"`
+
+	// ConnectAttempts bounds how many times Connect tries to reach the
+	// database, waiting ConnectBackoff, then twice as long, in between.
+	ConnectAttempts int           `config:"connect_attempts" default:"5"`
+	ConnectBackoff  time.Duration `config:"connect_backoff" default:"1s"`
+}
+
+// Connect creates a connection to the PostgreSQL instance and applies or
+// verifies the database migrations as set by cfg.Migrations. A non-nil error
+// is returned to indicate failure.
+func Connect(ctx context.Context, cfg Config, logger log.Logger) (*sqlx.DB, error) {
+	db, err := Open(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateDB(ctx, db, cfg.Migrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
-// Connect creates a connection to the PostgreSQL instance and applies any
-// unapplied database migrations. A non-nil error is returned to indicate
-// failure.
-func Connect(cfg Config) (*sqlx.DB, error) {
-	url := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s sslcert=%s sslkey=%s sslrootcert=%s", cfg.Host, cfg.Port, cfg.User, cfg.Name, cfg.Pass, cfg.SSLMode, cfg.SSLCert, cfg.SSLKey, cfg.SSLRootCert)
+// Open creates a connection pool to the PostgreSQL instance without touching
+// the schema. Unreachable databases are retried with backoff until
+// cfg.ConnectAttempts is exhausted or ctx is done.
+func Open(ctx context.Context, cfg Config, logger log.Logger) (*sqlx.DB, error) {
+	driverName, ok := driverNames[cfg.Driver]
+	if !ok {
+		return nil, ErrUnsupportedDriver
+	}
 
-	db, err := sqlx.Open("cloudsqlpostgres", url)
+	// every statement is traced as a child of the span carried by its context
+	sqlDB, err := otelsql.Open(driverName, dataSourceName(cfg), otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	db := sqlx.NewDb(sqlDB, driverName)
 
-	if err := migrateDB(db); err != nil {
+	if err := ping(ctx, db, cfg, logger); err != nil {
+		db.Close()
 		return nil, err
 	}
 	return db, nil
 }
 
-func migrateDB(db *sqlx.DB) error {
-	migrations := &migrate.MemoryMigrationSource{
-		Migrations: []*migrate.Migration{
-			{
-				Id: "pharmacy_1",
-				Up: []string{`
-					create table if not exists pharmacies
-					(
-						id varchar(10) not null
-							constraint pharmacies_pkey
-								primary key,
-						name varchar(254) not null,
-						phone varchar(254) not null,
-						address varchar(254) not null,
-						mask_adult integer not null,
-						mask_child integer not null,
-						available varchar(1024) not null,
-						note varchar(1024) not null,
-						longitude double precision not null,
-						latitude double precision not null,
-						updated timestamp with time zone,
-						custom_note varchar(1024) default ''::character varying not null,
-						website varchar(1024) default ''::character varying not null
-					);
-					
-					alter table pharmacies owner to postgres;
-
-					CREATE EXTENSION if not exists cube;
-					CREATE EXTENSION if not exists earthdistance;
-					`,
-				},
-				Down: []string{"DROP TABLE pharmacies"},
-			},
-			{
-				Id: "pharmacy_2",
-				Up: []string{`
-					alter table pharmacies
-						add service_periods varchar(21) default '' not null;
-					
-					alter table pharmacies
-						add service_note varchar(1024) default '' not null;
-					
-					alter table pharmacies
-						add county varchar(19) default '' not null;
-					
-					alter table pharmacies
-						add town varchar(10) default '' not null;
-					
-					alter table pharmacies
-						add cunli varchar(10) default '' not null;
-				`},
-				Down: []string{`
-					alter table pharmacies drop column service_periods;
-					alter table pharmacies drop column service_note;
-					alter table pharmacies drop column county;
-					alter table pharmacies drop column town;
-					alter table pharmacies drop column cunli;
-				`},
-			},
-			{
-				Id: "pharmacy_3",
-				Up: []string{`
-					alter table pharmacies alter column name set default '';
-					alter table pharmacies alter column phone set default '';
-					alter table pharmacies alter column address set default '';
-					alter table pharmacies alter column mask_adult set default 0;
-					alter table pharmacies alter column mask_child set default 0;
-					alter table pharmacies alter column available set default '';
-					alter table pharmacies alter column note set default '';
-					alter table pharmacies alter column longitude set default 0.0;
-					alter table pharmacies alter column latitude set default 0.0;
-				`},
-				Down: []string{``},
-			},
-			{
-				Id: "pharmacy_4",
-				Up: []string{`
-					create or replace view latest_pharmacy_table as
-					SELECT table_schema,
-						   table_name
-					FROM information_schema.tables
-					WHERE table_type = 'BASE TABLE'
-					  AND table_schema = 'public'
-					  and table_name like 'pharmacy_%'
-					order by table_name desc
-					limit 1;
-
-					CREATE OR REPLACE FUNCTION footgun(IN _tablename TEXT, IN _keepcount int)
-						RETURNS void
-						LANGUAGE plpgsql
-					AS
-					$$
-					DECLARE
-						row record;
-					BEGIN
-						FOR row IN
-							SELECT table_schema,
-								   table_name
-							FROM information_schema.tables
-							where table_name not in (
-								SELECT table_name
-								FROM information_schema.tables
-								WHERE table_type = 'BASE TABLE'
-								  AND table_schema = 'public'
-								  and table_name like _tablename
-								order by table_name desc
-								limit _keepcount
-							)
-							  AND table_schema = 'public'
-							  and table_name like _tablename
-							LOOP
-								EXECUTE 'DROP TABLE ' || quote_ident(row.table_schema) || '.' || quote_ident(row.table_name);
-								RAISE INFO 'Dropped table: %', quote_ident(row.table_schema) || '.' || quote_ident(row.table_name);
-							END LOOP;
-					END;
-					$$;
-				`},
-				Down: []string{},
-			},
-			{
-				Id: "feedback_1",
-				Up: []string{`
-					create table if not exists options
-					(
-						id   varchar(21)  not null
-							constraint options_pkey
-								primary key,
-						name varchar(254) not null
-					);
-					
-					alter table options
-						owner to postgres;
-
-					INSERT INTO public.options (id, name) VALUES ('IRESxM58KC~dqg5XLCH~n', '自訂') ON CONFLICT (id) DO NOTHING;
-					INSERT INTO public.options (id, name) VALUES ('ddCp1m88O4g5SU1GDJRPi', '當天已售完') ON CONFLICT (id) DO NOTHING;;
-					INSERT INTO public.options (id, name) VALUES ('uYrYL~7Gd65IN2wWsWa9A', '號碼牌已發送完畢') ON CONFLICT (id) DO NOTHING;;
-					INSERT INTO public.options (id, name) VALUES ('nAn6pj8UkrXST1syShrzV', '發放號碼牌') ON CONFLICT (id) DO NOTHING;;
-				`},
-				Down: []string{``},
-			},
-		},
+func ping(ctx context.Context, db *sqlx.DB, cfg Config, logger log.Logger) (err error) {
+	backoff := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectAttempts {
+			return err
+		}
+
+		level.Warn(logger).Log("method", "db.PingContext", "attempt", attempt, "retryIn", backoff, "err", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func dataSourceName(cfg Config) string {
+	params := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"dbname", cfg.Name},
+		{"password", cfg.Pass},
+		{"sslmode", cfg.SSLMode},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+		{"sslrootcert", cfg.SSLRootCert},
 	}
 
-	_, err := migrate.Exec(db.DB, "postgres", migrations, migrate.Up)
-	return err
+	var kvs []string
+	for _, p := range params {
+		if p.value != "" {
+			kvs = append(kvs, fmt.Sprintf("%s='%s'", p.key, strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(p.value)))
+		}
+	}
+	return strings.Join(kvs, " ")
 }
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/proxy"
+	"github.com/lib/pq"
+)
+
+// FeedbackChannel is the NOTIFY channel the feedback service announces newly
+// recorded feedback on.
+const FeedbackChannel = "feedback_inserted"
+
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+)
+
+// instanceRegexp parses the '[project:region:instance]:port' address lib/pq
+// hands to dialers.
+var instanceRegexp = regexp.MustCompile(`^\[(.+)\]:[0-9]+$`)
+
+// cloudSQLDialer dials Cloud SQL instances the same way the cloudsqlpostgres
+// driver does, so LISTEN connections reach the same database.
+type cloudSQLDialer struct{}
+
+func (cloudSQLDialer) Dial(_, addr string) (net.Conn, error) {
+	matches := instanceRegexp.FindStringSubmatch(addr)
+	if len(matches) != 2 {
+		return nil, fmt.Errorf("failed to parse addr: %q. It should conform to the regular expression %q", addr, instanceRegexp)
+	}
+	return proxy.Dial(matches[1])
+}
+
+func (cloudSQLDialer) DialTimeout(_, _ string, _ time.Duration) (net.Conn, error) {
+	return nil, fmt.Errorf("timeout is not currently supported for cloudsqlpostgres dialer")
+}
+
+// Listen opens a dedicated connection that LISTENs on channel. Received
+// notifications are delivered on the returned listener's Notify channel.
+func Listen(cfg Config, channel string) (*pq.Listener, error) {
+	var l *pq.Listener
+	switch cfg.Driver {
+	case DriverCloudSQL:
+		l = pq.NewDialListener(cloudSQLDialer{}, dataSourceName(cfg), minReconnectInterval, maxReconnectInterval, nil)
+	case DriverPostgres, DriverPgx:
+		l = pq.NewListener(dataSourceName(cfg), minReconnectInterval, maxReconnectInterval, nil)
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+	if err := l.Listen(channel); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
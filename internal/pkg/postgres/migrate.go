@@ -0,0 +1,274 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomurphyx/sqlx"
+	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+const (
+	// MigrateApply applies pending migrations on startup, refusing to start
+	// at the first one only cmd/migrate may apply.
+	MigrateApply = "apply"
+	// MigrateVerify refuses to start while migrations are pending, leaving
+	// them to cmd/migrate.
+	MigrateVerify = "verify"
+	// MigrateIgnore neither applies nor checks migrations.
+	MigrateIgnore = "ignore"
+
+	dialect = "postgres"
+
+	// migrationLockID keys the advisory lock serializing instances applying
+	// migrations on startup.
+	migrationLockID = 0x6d61736b
+
+	// MigrationDigits is the width migration numbers are zero-padded to.
+	// sql-migrate compares IDs without a leading number as strings, so
+	// pharmacy_10 would sort before pharmacy_2 without the padding.
+	MigrationDigits = 4
+
+	// manualDirective marks a migration too heavy to apply on startup, e.g.
+	// one holding table locks while it copies rows. Only cmd/migrate applies
+	// it; MigrateApply stops short of it.
+	manualDirective = "-- mask:manual"
+)
+
+// ErrPendingMigrations indicates the database schema is behind the
+// migrations embedded in the binary.
+var ErrPendingMigrations = errors.New("database has pending migrations")
+
+// ErrManualMigration indicates a pending migration must be applied with
+// cmd/migrate before the service starts.
+var ErrManualMigration = errors.New("pending migration must be applied with cmd/migrate")
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationStatus describes one embedded migration and whether it has been
+// applied.
+type MigrationStatus struct {
+	ID        string
+	AppliedAt *time.Time
+}
+
+type embedSource struct{}
+
+// FindMigrations parses the embedded migrations. IDs are the file names
+// without the .sql extension.
+func (embedSource) FindMigrations() ([]*migrate.Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*migrate.Migration
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		f, err := migrationFiles.Open("migrations/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, err := migrate.ParseMigration(strings.TrimSuffix(e.Name(), ".sql"), f.(readSeekCloser))
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrap(errors.New("failed to parse migration "+e.Name()), err)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Less(migrations[j]) })
+	return migrations, nil
+}
+
+// manual reports whether the embedded migration id carries the manual
+// directive.
+func manual(id string) (bool, error) {
+	b, err := migrationFiles.ReadFile("migrations/" + id + ".sql")
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) == manualDirective {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type readSeekCloser interface {
+	Read([]byte) (int, error)
+	Seek(int64, int) (int64, error)
+	Close() error
+}
+
+// Migrations returns the migrations embedded in the binary.
+func Migrations() migrate.MigrationSource {
+	return embedSource{}
+}
+
+// MigrateUp applies at most max pending migrations, all of them when max is
+// 0, and returns how many were applied. It waits for the instances applying
+// migrations on startup.
+func MigrateUp(db *sql.DB, max int) (int, error) {
+	unlock, err := lock(context.Background(), db)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	return migrateUp(db, max)
+}
+
+func migrateUp(db *sql.DB, max int) (int, error) {
+	if err := renumber(db); err != nil {
+		return 0, err
+	}
+	return migrate.ExecMax(db, dialect, Migrations(), migrate.Up, max)
+}
+
+// MigrateDown reverts at most max applied migrations, all of them when max
+// is 0, and returns how many were reverted. Like sql-migrate, it reverts
+// the applied migrations with the greatest IDs, which is not the order
+// they were applied in across prefixes: every pharmacy_ migration is
+// reverted before the feedback_ ones.
+func MigrateDown(db *sql.DB, max int) (int, error) {
+	unlock, err := lock(context.Background(), db)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := renumber(db); err != nil {
+		return 0, err
+	}
+	return migrate.ExecMax(db, dialect, Migrations(), migrate.Down, max)
+}
+
+// Status returns every embedded migration, in order, with the time it was
+// applied.
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	if err := renumber(db); err != nil {
+		return nil, err
+	}
+	migrations, err := Migrations().FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+	records, err := migrate.GetMigrationRecords(db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{ID: m.Id}
+		if t, ok := applied[m.Id]; ok {
+			s.AppliedAt = &t
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// renumber pads the IDs sql-migrate recorded for the migrations applied
+// before their files were zero-padded, e.g. pharmacy_1 to pharmacy_0001, so
+// they are not taken for pending ones.
+func renumber(db *sql.DB) error {
+	var exists bool
+	if err := db.QueryRow(`select to_regclass('gorp_migrations') is not null`).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	_, err := db.Exec(`update gorp_migrations
+		set id = split_part(id, '_', 1) || '_' || lpad(split_part(id, '_', 2), $1, '0')
+		where id ~ '^[a-z][a-z0-9]*_[0-9]+$' and length(split_part(id, '_', 2)) < $1`, MigrationDigits)
+	return err
+}
+
+// migrateDB brings the schema up to date according to mode.
+func migrateDB(ctx context.Context, db *sqlx.DB, mode string) error {
+	switch mode {
+	case MigrateIgnore:
+		return nil
+	case MigrateVerify:
+		status, err := Status(db.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			if s.AppliedAt == nil {
+				return errors.Wrap(ErrPendingMigrations, errors.New(s.ID))
+			}
+		}
+		return nil
+	}
+
+	unlock, err := lock(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := renumber(db.DB); err != nil {
+		return err
+	}
+	planned, _, err := migrate.PlanMigration(db.DB, dialect, Migrations(), migrate.Up, 0)
+	if err != nil {
+		return err
+	}
+	for i, m := range planned {
+		isManual, err := manual(m.Id)
+		if err != nil {
+			return err
+		}
+		if !isManual {
+			continue
+		}
+		// apply the migrations before it, which the next start can rely on
+		if i > 0 {
+			if _, err := migrateUp(db.DB, i); err != nil {
+				return err
+			}
+		}
+		return errors.Wrap(ErrManualMigration, errors.New(m.Id))
+	}
+
+	_, err = migrateUp(db.DB, 0)
+	return err
+}
+
+// lock holds a session level advisory lock so that instances booting
+// together, and cmd/migrate, apply the migrations once, one after another.
+// The migrations run on a second connection, so the pool must allow at least
+// two.
+func lock(ctx context.Context, db *sql.DB) (unlock func(), err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `select pg_advisory_lock($1)`, migrationLockID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.Background(), `select pg_advisory_unlock($1)`, migrationLockID)
+		conn.Close()
+	}, nil
+}
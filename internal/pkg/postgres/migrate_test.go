@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestMigrationsOrder(t *testing.T) {
+	migrations, err := Migrations().FindMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := regexp.MustCompile(fmt.Sprintf(`^[a-z][a-z0-9]*_[0-9]{%d}$`, MigrationDigits))
+	for i, m := range migrations {
+		if !id.MatchString(m.Id) {
+			t.Errorf("migration %s: want <prefix>_<%d digits>", m.Id, MigrationDigits)
+		}
+		// sql-migrate compares these IDs as strings, which must agree with
+		// the order of the numbers
+		if i > 0 && migrations[i-1].Id >= m.Id {
+			t.Errorf("migration %s sorts after %s", migrations[i-1].Id, m.Id)
+		}
+	}
+}
+
+func TestManualMigrations(t *testing.T) {
+	cases := map[string]bool{
+		"feedback_0001": false,
+		"feedback_0002": true,
+		"feedback_0005": false,
+	}
+	for id, want := range cases {
+		got, err := manual(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("manual(%q): got %t, want %t", id, got, want)
+		}
+	}
+}
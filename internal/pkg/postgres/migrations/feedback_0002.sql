@@ -0,0 +1,100 @@
+-- The copy of the former feedback_YYYY_MMDD tables runs in one transaction:
+-- feedback and every table copied stay locked, and feedback cannot be
+-- written, until the last row is copied. It is therefore left to
+-- cmd/migrate, run while the feedback service is scaled down.
+-- mask:manual
+
+-- +migrate Up
+-- The primary key of a partitioned table must include the partition key, so
+-- (id, created_at) no longer makes id unique on its own: a repeated id with
+-- another created_at is accepted. Ids are 21 character nanoids generated by
+-- the feedback service, which are not expected to repeat; nothing checks it.
+create table feedback
+(
+	id          varchar(21)                                    not null,
+	user_id     varchar(30)   default ''::character varying    not null,
+	pharmacy_id varchar(10)   default ''::character varying    not null,
+	option_id   varchar(21)   default ''::character varying    not null,
+	description varchar(1024) default ''::character varying    not null,
+	longitude   double precision default 0.0                   not null,
+	latitude    double precision default 0.0                   not null,
+	created_at  timestamp with time zone default now()         not null,
+	constraint feedback_pkey primary key (id, created_at)
+) partition by range (created_at);
+
+alter table feedback
+	owner to postgres;
+
+-- feedback_create_partitions creates the daily partitions, cut at midnight
+-- Asia/Taipei, of the given number of days starting at start_day.
+-- +migrate StatementBegin
+create or replace function feedback_create_partitions(start_day date, days integer) returns void as $$
+declare
+	d date;
+begin
+	for i in 0..days - 1 loop
+		d := start_day + i;
+		execute format('create table if not exists %I partition of feedback for values from (%L) to (%L)',
+			'feedback_p' || to_char(d, 'YYYYMMDD'),
+			d::timestamp at time zone 'Asia/Taipei',
+			(d + 1)::timestamp at time zone 'Asia/Taipei');
+	end loop;
+end;
+$$ language plpgsql;
+-- +migrate StatementEnd
+
+-- move the rows of the former feedback_YYYY_MMDD tables into the partitions
+-- +migrate StatementBegin
+do $$
+declare
+	t    record;
+	low  date;
+	high date;
+begin
+	for t in select tablename from pg_catalog.pg_tables
+			where schemaname = 'public' and tablename ~ '^feedback_\d{4}_\d{4}$'
+			order by tablename loop
+		execute format('select min((created_at at time zone ''Asia/Taipei'')::date), max((created_at at time zone ''Asia/Taipei'')::date) from %I', t.tablename)
+			into low, high;
+		if low is not null then
+			perform feedback_create_partitions(low, high - low + 1);
+			execute format('insert into feedback (id, user_id, pharmacy_id, option_id, description, longitude, latitude, created_at)
+				select id, user_id, pharmacy_id, option_id, description, longitude, latitude, created_at from %I', t.tablename);
+		end if;
+		execute format('drop table %I', t.tablename);
+	end loop;
+end;
+$$;
+-- +migrate StatementEnd
+
+select feedback_create_partitions((now() at time zone 'Asia/Taipei')::date, 7);
+
+-- +migrate Down
+-- +migrate StatementBegin
+do $$
+declare
+	d record;
+	t text;
+begin
+	for d in select distinct (created_at at time zone 'Asia/Taipei')::date as day from feedback order by 1 loop
+		t := 'feedback_' || to_char(d.day, 'YYYY_MMDD');
+		execute format('create table %I
+			(
+				id          varchar(21) not null constraint %I primary key,
+				user_id     varchar(30) default ''''::character varying not null,
+				pharmacy_id varchar(10) default ''''::character varying not null,
+				option_id   varchar(21) default ''''::character varying not null,
+				description varchar(1024) default ''''::character varying not null,
+				longitude   double precision default 0.0 not null,
+				latitude    double precision default 0.0 not null,
+				created_at  timestamp with time zone default now() not null
+			)', t, t || '_pkey');
+		execute format('insert into %I select id, user_id, pharmacy_id, option_id, description, longitude, latitude, created_at
+			from feedback where (created_at at time zone ''Asia/Taipei'')::date = %L', t, d.day);
+	end loop;
+end;
+$$;
+-- +migrate StatementEnd
+
+drop table feedback;
+drop function feedback_create_partitions(date, integer);
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- indexes on the partitioned table cascade to every partition, including
+-- those created later by feedback_create_partitions
+create index if not exists feedback_user_id_created_at_idx
+	on feedback (user_id, created_at desc);
+
+create index if not exists feedback_pharmacy_id_created_at_idx
+	on feedback (pharmacy_id, created_at desc);
+
+-- +migrate Down
+drop index if exists feedback_pharmacy_id_created_at_idx;
+drop index if exists feedback_user_id_created_at_idx;
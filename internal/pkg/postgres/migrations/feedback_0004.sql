@@ -0,0 +1,19 @@
+-- +migrate Up
+-- feedback_suspects holds the abuse scores of users; the feedback of banned
+-- users is left out of the feedback of pharmacies
+create table if not exists feedback_suspects
+(
+	user_id    varchar(30)                            not null
+		constraint feedback_suspects_pkey
+			primary key,
+	score      double precision default 0.0           not null,
+	reports    bigint           default 0             not null,
+	banned     boolean          default false         not null,
+	updated_at timestamp with time zone default now() not null
+);
+
+alter table feedback_suspects
+	owner to postgres;
+
+-- +migrate Down
+drop table feedback_suspects;
@@ -0,0 +1,65 @@
+-- +migrate Up
+-- feedback_default takes the rows no daily partition covers, e.g. when
+-- partitions were not created ahead, so inserts never fail
+create table if not exists feedback_default partition of feedback default;
+
+-- feedback_create_partitions creates the daily partitions, cut at midnight
+-- Asia/Taipei, of the given number of days starting at start_day. The rows
+-- feedback_default holds for a day are moved into its partition, which is
+-- attached once filled since it may not overlap them.
+-- +migrate StatementBegin
+create or replace function feedback_create_partitions(start_day date, days integer) returns void as $$
+declare
+	d    date;
+	t    text;
+	low  timestamptz;
+	high timestamptz;
+begin
+	for i in 0..days - 1 loop
+		d := start_day + i;
+		t := 'feedback_p' || to_char(d, 'YYYYMMDD');
+		if to_regclass(t) is not null then
+			continue;
+		end if;
+		low := d::timestamp at time zone 'Asia/Taipei';
+		high := (d + 1)::timestamp at time zone 'Asia/Taipei';
+		execute format('create table %I (like feedback including defaults including constraints)', t);
+		execute format('with moved as (delete from feedback_default where created_at >= %L and created_at < %L returning *)
+			insert into %I select * from moved', low, high, t);
+		execute format('alter table feedback attach partition %I for values from (%L) to (%L)', t, low, high);
+	end loop;
+end;
+$$ language plpgsql;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- move the rows of feedback_default into daily partitions first
+-- +migrate StatementBegin
+do $$
+declare
+	d record;
+begin
+	for d in select distinct (created_at at time zone 'Asia/Taipei')::date as day from feedback_default loop
+		perform feedback_create_partitions(d.day, 1);
+	end loop;
+end;
+$$;
+-- +migrate StatementEnd
+
+drop table feedback_default;
+
+-- +migrate StatementBegin
+create or replace function feedback_create_partitions(start_day date, days integer) returns void as $$
+declare
+	d date;
+begin
+	for i in 0..days - 1 loop
+		d := start_day + i;
+		execute format('create table if not exists %I partition of feedback for values from (%L) to (%L)',
+			'feedback_p' || to_char(d, 'YYYYMMDD'),
+			d::timestamp at time zone 'Asia/Taipei',
+			(d + 1)::timestamp at time zone 'Asia/Taipei');
+	end loop;
+end;
+$$ language plpgsql;
+-- +migrate StatementEnd
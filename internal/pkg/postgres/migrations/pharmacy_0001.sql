@@ -0,0 +1,27 @@
+-- +migrate Up
+create table if not exists pharmacies
+(
+	id varchar(10) not null
+		constraint pharmacies_pkey
+			primary key,
+	name varchar(254) not null,
+	phone varchar(254) not null,
+	address varchar(254) not null,
+	mask_adult integer not null,
+	mask_child integer not null,
+	available varchar(1024) not null,
+	note varchar(1024) not null,
+	longitude double precision not null,
+	latitude double precision not null,
+	updated timestamp with time zone,
+	custom_note varchar(1024) default ''::character varying not null,
+	website varchar(1024) default ''::character varying not null
+);
+
+alter table pharmacies owner to postgres;
+
+create extension if not exists cube;
+create extension if not exists earthdistance;
+
+-- +migrate Down
+drop table pharmacies;
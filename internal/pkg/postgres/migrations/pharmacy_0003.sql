@@ -0,0 +1,21 @@
+-- +migrate Up
+alter table pharmacies alter column name set default '';
+alter table pharmacies alter column phone set default '';
+alter table pharmacies alter column address set default '';
+alter table pharmacies alter column mask_adult set default 0;
+alter table pharmacies alter column mask_child set default 0;
+alter table pharmacies alter column available set default '';
+alter table pharmacies alter column note set default '';
+alter table pharmacies alter column longitude set default 0.0;
+alter table pharmacies alter column latitude set default 0.0;
+
+-- +migrate Down
+alter table pharmacies alter column name drop default;
+alter table pharmacies alter column phone drop default;
+alter table pharmacies alter column address drop default;
+alter table pharmacies alter column mask_adult drop default;
+alter table pharmacies alter column mask_child drop default;
+alter table pharmacies alter column available drop default;
+alter table pharmacies alter column note drop default;
+alter table pharmacies alter column longitude drop default;
+alter table pharmacies alter column latitude drop default;
@@ -0,0 +1,32 @@
+-- +migrate Up
+create table if not exists webhooks
+(
+	id varchar(21) not null
+		constraint webhooks_pkey
+			primary key,
+	url varchar(2048) not null,
+	secret varchar(254) not null,
+	events text[] default '{}' not null,
+	created_at timestamp with time zone default now() not null
+);
+
+create table if not exists webhook_deliveries
+(
+	webhook_id varchar(21) not null
+		constraint webhook_deliveries_webhook_id_fkey
+			references webhooks
+				on delete cascade,
+	snapshot varchar(254) not null,
+	status varchar(10) not null,
+	attempts integer default 0 not null,
+	last_status_code integer default 0 not null,
+	last_error varchar(1024) default '' not null,
+	created_at timestamp with time zone default now() not null,
+	updated_at timestamp with time zone default now() not null,
+	constraint webhook_deliveries_pkey
+		primary key (webhook_id, snapshot)
+);
+
+-- +migrate Down
+drop table webhook_deliveries;
+drop table webhooks;
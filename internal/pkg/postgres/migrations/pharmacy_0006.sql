@@ -0,0 +1,17 @@
+-- +migrate Up
+create table if not exists watches
+(
+	user_id varchar(30) not null,
+	pharmacy_id varchar(10) not null,
+	mask_adult integer default 0 not null,
+	mask_child integer default 0 not null,
+	last_notified_snapshot varchar(254) default '' not null,
+	created_at timestamp with time zone default now() not null,
+	constraint watches_pkey
+		primary key (user_id, pharmacy_id)
+);
+
+create index if not exists watches_pharmacy_id_idx on watches (pharmacy_id);
+
+-- +migrate Down
+drop table watches;
@@ -0,0 +1,27 @@
+-- +migrate Up
+-- pharmacy_locations backs the PostGIS pharmacy repository. It is only
+-- created where the postgis extension is available, the earthdistance
+-- repository does not need it.
+-- +migrate StatementBegin
+do $$
+begin
+	if exists(select 1 from pg_available_extensions where name = 'postgis') then
+		create extension if not exists postgis;
+
+		create table if not exists pharmacy_locations
+		(
+			id   varchar(10)            not null
+				constraint pharmacy_locations_pkey
+					primary key,
+			geog geography(Point, 4326) not null
+		);
+
+		create index if not exists pharmacy_locations_geog_idx
+			on pharmacy_locations using gist (geog);
+	end if;
+end;
+$$;
+-- +migrate StatementEnd
+
+-- +migrate Down
+drop table if exists pharmacy_locations;
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- A delivery keeps its payload so that any instance can attempt it, and is
+-- leased by pushing next_attempt_at forward while an attempt is under way.
+alter table webhook_deliveries
+	add payload bytea default ''::bytea not null;
+
+alter table webhook_deliveries
+	add next_attempt_at timestamp with time zone default now() not null;
+
+create index if not exists webhook_deliveries_due_idx
+	on webhook_deliveries (next_attempt_at) where status = 'pending';
+
+-- +migrate Down
+drop index if exists webhook_deliveries_due_idx;
+alter table webhook_deliveries drop column next_attempt_at;
+alter table webhook_deliveries drop column payload;
@@ -0,0 +1,95 @@
+-- +migrate Up
+-- Snapshot tables are named like pharmacy_0322_1030. In like patterns _ matches
+-- any character, so 'pharmacy_%' also picked other tables such as
+-- pharmacy_locations, which sorts after every snapshot: the view listed it as
+-- the latest snapshot and footgun dropped it with the old snapshots.
+create or replace view latest_pharmacy_table as
+SELECT table_schema,
+	   table_name
+FROM information_schema.tables
+WHERE table_type = 'BASE TABLE'
+  AND table_schema = 'public'
+  and table_name ~ '^pharmacy_\d{4}_\d{4}$'
+order by table_name desc
+limit 1;
+
+-- footgun keeps the _keepcount latest snapshot tables matching _tablename
+-- and drops the older ones; tables not named like a snapshot are never
+-- dropped.
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION footgun(IN _tablename TEXT, IN _keepcount int)
+	RETURNS void
+	LANGUAGE plpgsql
+AS
+$$
+DECLARE
+	row record;
+BEGIN
+	FOR row IN
+		SELECT table_schema,
+			   table_name
+		FROM information_schema.tables
+		where table_name not in (
+			SELECT table_name
+			FROM information_schema.tables
+			WHERE table_type = 'BASE TABLE'
+			  AND table_schema = 'public'
+			  and table_name like _tablename
+			  and table_name ~ '^pharmacy_\d{4}_\d{4}$'
+			order by table_name desc
+			limit _keepcount
+		)
+		  AND table_type = 'BASE TABLE'
+		  AND table_schema = 'public'
+		  and table_name like _tablename
+		  and table_name ~ '^pharmacy_\d{4}_\d{4}$'
+		LOOP
+			EXECUTE 'DROP TABLE ' || quote_ident(row.table_schema) || '.' || quote_ident(row.table_name);
+			RAISE INFO 'Dropped table: %', quote_ident(row.table_schema) || '.' || quote_ident(row.table_name);
+		END LOOP;
+END;
+$$;
+-- +migrate StatementEnd
+
+-- +migrate Down
+create or replace view latest_pharmacy_table as
+SELECT table_schema,
+	   table_name
+FROM information_schema.tables
+WHERE table_type = 'BASE TABLE'
+  AND table_schema = 'public'
+  and table_name like 'pharmacy_%'
+order by table_name desc
+limit 1;
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION footgun(IN _tablename TEXT, IN _keepcount int)
+	RETURNS void
+	LANGUAGE plpgsql
+AS
+$$
+DECLARE
+	row record;
+BEGIN
+	FOR row IN
+		SELECT table_schema,
+			   table_name
+		FROM information_schema.tables
+		where table_name not in (
+			SELECT table_name
+			FROM information_schema.tables
+			WHERE table_type = 'BASE TABLE'
+			  AND table_schema = 'public'
+			  and table_name like _tablename
+			order by table_name desc
+			limit _keepcount
+		)
+		  AND table_schema = 'public'
+		  and table_name like _tablename
+		LOOP
+			EXECUTE 'DROP TABLE ' || quote_ident(row.table_schema) || '.' || quote_ident(row.table_name);
+			RAISE INFO 'Dropped table: %', quote_ident(row.table_schema) || '.' || quote_ident(row.table_name);
+		END LOOP;
+END;
+$$;
+-- +migrate StatementEnd
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- A claimed notification stays pending on its watch until the sender
+-- delivers it; pending_until is when it may be leased again.
+alter table watches
+	add column if not exists pending_notification jsonb,
+	add column if not exists pending_until timestamp with time zone;
+
+create index if not exists watches_pending_until_idx on watches (pending_until)
+	where pending_notification is not null;
+
+-- +migrate Down
+drop index if exists watches_pending_until_idx;
+
+alter table watches
+	drop column if exists pending_notification,
+	drop column if exists pending_until;
@@ -0,0 +1,67 @@
+// Package pgtest connects tests to a disposable local database configured
+// through MASK_TEST_DB_* (e.g. MASK_TEST_DB_DRIVER=postgres
+// MASK_TEST_DB_HOST=localhost MASK_TEST_DB_NAME=mask_test). Without one, the
+// repository tests and benchmarks are skipped and the contract tests run on
+// the in-memory repositories.
+package pgtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	appconfig "github.com/cage1016/mask/internal/pkg/config"
+	psql "github.com/cage1016/mask/internal/pkg/postgres"
+)
+
+// Prefix prefixes the environment variables configuring the tests.
+const Prefix = "MASK_TEST_"
+
+// Open connects to the MASK_TEST_DB_* database, or returns nil when none is
+// configured. Every migration is applied, including those the services
+// leave to cmd/migrate, and the connection is closed when tb ends.
+func Open(tb testing.TB) *sqlx.DB {
+	tb.Helper()
+	cfg, err := config()
+	if err != nil {
+		return nil
+	}
+	return connect(tb, cfg)
+}
+
+// Require is Open skipping tb when no database is configured.
+func Require(tb testing.TB) *sqlx.DB {
+	tb.Helper()
+	cfg, err := config()
+	if err != nil {
+		tb.Skipf("no test database: %s", err)
+	}
+	return connect(tb, cfg)
+}
+
+func config() (psql.Config, error) {
+	var cfg struct {
+		DB psql.Config `config:"db"`
+	}
+	if err := appconfig.Load(&cfg, appconfig.Options{Prefix: Prefix}); err != nil {
+		return psql.Config{}, err
+	}
+	cfg.DB.ConnectAttempts = 1
+	return cfg.DB, nil
+}
+
+func connect(tb testing.TB, cfg psql.Config) *sqlx.DB {
+	tb.Helper()
+	db, err := psql.Open(context.Background(), cfg, log.NewNopLogger())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if _, err := psql.MigrateUp(db.DB, 0); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
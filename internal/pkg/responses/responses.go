@@ -1,5 +1,15 @@
 package responses
 
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// V2Prefix is the path prefix of the /api/v2 route tree. Routes under /api
+// stay frozen for released app versions.
+const V2Prefix = "/api/v2/"
+
 type DataRes struct {
 	APIVersion string      `json:"apiVersion"`
 	Data       interface{} `json:"data"`
@@ -9,9 +19,62 @@ type Responser interface {
 	Response() interface{}
 }
 
+// GeoJSONResponser is implemented by responses that can be rendered as a
+// GeoJSON document.
+type GeoJSONResponser interface {
+	GeoJSON() interface{}
+}
+
 type Paging struct {
 	CurrentItemCount int64 `json:"currentItemCount"`
 	ItemsPage        int64 `json:"itemsPage"`
 	StartIndex       int64 `json:"startIndex"`
 	TotalItems       int64 `json:"totalItems"`
-}
\ No newline at end of file
+}
+
+// NewPaging describes a page of count items starting at offset, out of
+// total, requested limit items at a time.
+func NewPaging(count int, offset, limit, total uint64) *Paging {
+	return &Paging{
+		CurrentItemCount: int64(count),
+		ItemsPage:        int64(limit),
+		StartIndex:       int64(offset),
+		TotalItems:       int64(total),
+	}
+}
+
+// Envelope is the single response document of the /api/v2 routes: data, and
+// paging for a page of a longer list, on success; error on failure.
+type Envelope struct {
+	APIVersion string        `json:"apiVersion"`
+	Data       interface{}   `json:"data,omitempty"`
+	Paging     *Paging       `json:"paging,omitempty"`
+	Error      *ErrorResItem `json:"error,omitempty"`
+}
+
+// Enveloper is implemented by responses served on the /api/v2 routes.
+type Enveloper interface {
+	Envelope() Envelope
+}
+
+type contextKey int
+
+const contextKeyVersion contextKey = iota
+
+// VersionToContext records the API version addressed by the request path, so
+// that encoders answer in the matching envelope.
+func VersionToContext(ctx context.Context, r *http.Request) context.Context {
+	if strings.HasPrefix(r.URL.Path, V2Prefix) {
+		return context.WithValue(ctx, contextKeyVersion, 2)
+	}
+	return ctx
+}
+
+// Version returns the API version recorded by VersionToContext, 1 unless the
+// request addressed the /api/v2 routes.
+func Version(ctx context.Context) int {
+	if v, ok := ctx.Value(contextKeyVersion).(int); ok {
+		return v
+	}
+	return 1
+}
@@ -0,0 +1,140 @@
+// Package secrets resolves credentials from the environment, a dotenv style
+// file or a directory of mounted secrets, so they never have to be committed
+// with the deployment descriptors.
+package secrets
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// ErrSecretNotFound indicates no provider holds the requested secret.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Provider resolves secrets by name.
+type Provider interface {
+	// Get returns the value of the named secret.
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Get returns the named secret from p, or fallback when p does not hold it.
+func Get(ctx context.Context, p Provider, name, fallback string) (string, error) {
+	v, err := p.Get(ctx, name)
+	if err == ErrSecretNotFound {
+		return fallback, nil
+	}
+	return v, err
+}
+
+type envProvider struct{}
+
+// NewEnvProvider returns a Provider reading environment variables.
+func NewEnvProvider() Provider {
+	return envProvider{}
+}
+
+func (envProvider) Get(_ context.Context, name string) (string, error) {
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+type fileProvider struct {
+	path string
+}
+
+// NewFileProvider returns a Provider reading NAME=value lines of the file at
+// path. Blank lines and lines starting with # are ignored. The file is read
+// on every Get so rotated secrets are picked up.
+func NewFileProvider(path string) Provider {
+	return fileProvider{path: path}
+}
+
+func (fp fileProvider) Get(_ context.Context, name string) (string, error) {
+	f, err := os.Open(fp.path)
+	if err != nil {
+		return "", errors.Wrap(errors.New("failed to open secrets file"), err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) != name {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(kv[1]), `"'`), nil
+	}
+	if err := s.Err(); err != nil {
+		return "", errors.Wrap(errors.New("failed to read secrets file"), err)
+	}
+	return "", ErrSecretNotFound
+}
+
+type mountProvider struct {
+	dir string
+}
+
+// NewMountProvider returns a Provider reading one file per secret from dir,
+// the layout used by Docker and Kubernetes secret volumes.
+func NewMountProvider(dir string) Provider {
+	return mountProvider{dir: dir}
+}
+
+func (mp mountProvider) Get(_ context.Context, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", ErrSecretNotFound
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(mp.dir, name))
+	if os.IsNotExist(err) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", errors.Wrap(errors.New("failed to read mounted secret"), err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+type chain []Provider
+
+// Chain returns a Provider asking each of providers in turn and returning the
+// first secret found.
+func Chain(providers ...Provider) Provider {
+	return chain(providers)
+}
+
+func (c chain) Get(ctx context.Context, name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Get(ctx, name)
+		if err == ErrSecretNotFound {
+			continue
+		}
+		return v, err
+	}
+	return "", ErrSecretNotFound
+}
+
+// FromEnv returns the Provider configured by the <prefix>SECRETS_DIR and
+// <prefix>SECRETS_FILE variables, falling back to the environment.
+func FromEnv(prefix string) Provider {
+	var providers []Provider
+	if dir := os.Getenv(prefix + "SECRETS_DIR"); dir != "" {
+		providers = append(providers, NewMountProvider(dir))
+	}
+	if file := os.Getenv(prefix + "SECRETS_FILE"); file != "" {
+		providers = append(providers, NewFileProvider(file))
+	}
+	return Chain(append(providers, NewEnvProvider())...)
+}
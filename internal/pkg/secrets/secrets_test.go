@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cage1016/mask/internal/pkg/errors"
+)
+
+// static holds the secrets of a map, or fails every Get with err.
+type static struct {
+	secrets map[string]string
+	err     error
+}
+
+func (s static) Get(_ context.Context, name string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if v, ok := s.secrets[name]; ok {
+		return v, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type lookup struct {
+	name string
+	want string
+	err  error
+}
+
+func check(t *testing.T, desc string, p Provider, cases []lookup) {
+	t.Helper()
+	for _, c := range cases {
+		got, err := p.Get(context.Background(), c.name)
+		if err != c.err || got != c.want {
+			t.Errorf("%s %q: got %q, %v, want %q, %v", desc, c.name, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("MASK_TEST_SECRET", "s3cret")
+	t.Setenv("MASK_TEST_EMPTY", "")
+
+	check(t, "env", NewEnvProvider(), []lookup{
+		{"MASK_TEST_SECRET", "s3cret", nil},
+		{"MASK_TEST_EMPTY", "", ErrSecretNotFound},
+		{"MASK_TEST_UNSET", "", ErrSecretNotFound},
+	})
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.env")
+	write(t, path, `# database
+MASK_DB_PASS = hunter2
+
+MASK_QUOTED="with spaces "
+MASK_SINGLE='single'
+MASK_EQUALS=a=b=c
+#MASK_COMMENTED=no
+not a pair
+`)
+
+	check(t, "file", NewFileProvider(path), []lookup{
+		{"MASK_DB_PASS", "hunter2", nil},
+		{"MASK_QUOTED", "with spaces ", nil},
+		{"MASK_SINGLE", "single", nil},
+		{"MASK_EQUALS", "a=b=c", nil},
+		{"MASK_COMMENTED", "", ErrSecretNotFound},
+		{"MASK_MISSING", "", ErrSecretNotFound},
+	})
+
+	// rotated secrets are picked up on the next Get
+	write(t, path, "MASK_DB_PASS=rotated\n")
+	check(t, "rotated file", NewFileProvider(path), []lookup{{"MASK_DB_PASS", "rotated", nil}})
+
+	if _, err := NewFileProvider(filepath.Join(t.TempDir(), "missing")).Get(context.Background(), "MASK_DB_PASS"); err == nil || err == ErrSecretNotFound {
+		t.Errorf("missing file: got %v, want an open error", err)
+	}
+}
+
+func TestMountProvider(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "MASK_DB_PASS"), "hunter2\n")
+	write(t, filepath.Join(dir, "MASK_CRLF"), "windows\r\n")
+	write(t, filepath.Join(filepath.Dir(dir), "outside"), "leaked")
+
+	check(t, "mount", NewMountProvider(dir), []lookup{
+		{"MASK_DB_PASS", "hunter2", nil},
+		{"MASK_CRLF", "windows", nil},
+		{"MASK_MISSING", "", ErrSecretNotFound},
+		{"", "", ErrSecretNotFound},
+		{".", "", ErrSecretNotFound},
+		{"..", "", ErrSecretNotFound},
+		{"../outside", "", ErrSecretNotFound},
+		{`..\outside`, "", ErrSecretNotFound},
+	})
+}
+
+func TestChain(t *testing.T) {
+	broken := errors.New("provider unavailable")
+	cases := []struct {
+		desc      string
+		providers []Provider
+		want      string
+		err       error
+	}{
+		{"first wins", []Provider{static{secrets: map[string]string{"k": "first"}}, static{secrets: map[string]string{"k": "second"}}}, "first", nil},
+		{"falls through", []Provider{static{}, static{secrets: map[string]string{"k": "second"}}}, "second", nil},
+		{"none holds it", []Provider{static{}, static{}}, "", ErrSecretNotFound},
+		{"empty", nil, "", ErrSecretNotFound},
+		{"errors stop the chain", []Provider{static{err: broken}, static{secrets: map[string]string{"k": "second"}}}, "", broken},
+	}
+
+	for _, c := range cases {
+		check(t, c.desc, Chain(c.providers...), []lookup{{"k", c.want, c.err}})
+	}
+}
+
+func TestGet(t *testing.T) {
+	p := static{secrets: map[string]string{"k": "v"}}
+	for name, want := range map[string]string{"k": "v", "missing": "fallback"} {
+		if got, err := Get(context.Background(), p, name, "fallback"); err != nil || got != want {
+			t.Errorf("Get(%q): got %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "secrets.env")
+	write(t, filepath.Join(dir, "MASK_TEST_PASS"), "mounted")
+	write(t, file, "MASK_TEST_PASS=file\nMASK_TEST_TOKEN=file\n")
+	t.Setenv("MASK_TEST_PASS", "env")
+	t.Setenv("MASK_TEST_TOKEN", "env")
+	t.Setenv("MASK_TEST_KEY", "env")
+
+	t.Setenv("MASK_TEST_SECRETS_DIR", dir)
+	t.Setenv("MASK_TEST_SECRETS_FILE", file)
+	check(t, "dir, file and env", FromEnv("MASK_TEST_"), []lookup{
+		{"MASK_TEST_PASS", "mounted", nil},
+		{"MASK_TEST_TOKEN", "file", nil},
+		{"MASK_TEST_KEY", "env", nil},
+	})
+
+	os.Unsetenv("MASK_TEST_SECRETS_DIR")
+	os.Unsetenv("MASK_TEST_SECRETS_FILE")
+	check(t, "env only", FromEnv("MASK_TEST_"), []lookup{{"MASK_TEST_PASS", "env", nil}})
+}
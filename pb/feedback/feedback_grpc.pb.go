@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: pb/feedback/feedback.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FeedbackService_Options_FullMethodName           = "/mask.feedback.FeedbackService/Options"
+	FeedbackService_PharmacyFeedBacks_FullMethodName = "/mask.feedback.FeedbackService/PharmacyFeedBacks"
+	FeedbackService_UserFeedBacks_FullMethodName     = "/mask.feedback.FeedbackService/UserFeedBacks"
+	FeedbackService_InsertFeedBack_FullMethodName    = "/mask.feedback.FeedbackService/InsertFeedBack"
+	FeedbackService_Export_FullMethodName            = "/mask.feedback.FeedbackService/Export"
+)
+
+// FeedbackServiceClient is the client API for FeedbackService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FeedbackServiceClient interface {
+	// Options lists the feedback options.
+	Options(ctx context.Context, in *OptionsRequest, opts ...grpc.CallOption) (*OptionsReply, error)
+	// PharmacyFeedBacks pages through the feedbacks of a pharmacy on a day.
+	PharmacyFeedBacks(ctx context.Context, in *PharmacyFeedBacksRequest, opts ...grpc.CallOption) (*FeedbackPage, error)
+	// UserFeedBacks pages through the feedbacks of a user on a day.
+	UserFeedBacks(ctx context.Context, in *UserFeedBacksRequest, opts ...grpc.CallOption) (*FeedbackPage, error)
+	// InsertFeedBack records a feedback and returns its id.
+	InsertFeedBack(ctx context.Context, in *InsertFeedBackRequest, opts ...grpc.CallOption) (*InsertFeedBackReply, error)
+	// Export streams the feedbacks between two days with pseudonymized user
+	// ids.
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (FeedbackService_ExportClient, error)
+}
+
+type feedbackServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFeedbackServiceClient(cc grpc.ClientConnInterface) FeedbackServiceClient {
+	return &feedbackServiceClient{cc}
+}
+
+func (c *feedbackServiceClient) Options(ctx context.Context, in *OptionsRequest, opts ...grpc.CallOption) (*OptionsReply, error) {
+	out := new(OptionsReply)
+	err := c.cc.Invoke(ctx, FeedbackService_Options_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedbackServiceClient) PharmacyFeedBacks(ctx context.Context, in *PharmacyFeedBacksRequest, opts ...grpc.CallOption) (*FeedbackPage, error) {
+	out := new(FeedbackPage)
+	err := c.cc.Invoke(ctx, FeedbackService_PharmacyFeedBacks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedbackServiceClient) UserFeedBacks(ctx context.Context, in *UserFeedBacksRequest, opts ...grpc.CallOption) (*FeedbackPage, error) {
+	out := new(FeedbackPage)
+	err := c.cc.Invoke(ctx, FeedbackService_UserFeedBacks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedbackServiceClient) InsertFeedBack(ctx context.Context, in *InsertFeedBackRequest, opts ...grpc.CallOption) (*InsertFeedBackReply, error) {
+	out := new(InsertFeedBackReply)
+	err := c.cc.Invoke(ctx, FeedbackService_InsertFeedBack_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedbackServiceClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (FeedbackService_ExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FeedbackService_ServiceDesc.Streams[0], FeedbackService_Export_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &feedbackServiceExportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FeedbackService_ExportClient interface {
+	Recv() (*FeedbackItem, error)
+	grpc.ClientStream
+}
+
+type feedbackServiceExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *feedbackServiceExportClient) Recv() (*FeedbackItem, error) {
+	m := new(FeedbackItem)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FeedbackServiceServer is the server API for FeedbackService service.
+// All implementations must embed UnimplementedFeedbackServiceServer
+// for forward compatibility
+type FeedbackServiceServer interface {
+	// Options lists the feedback options.
+	Options(context.Context, *OptionsRequest) (*OptionsReply, error)
+	// PharmacyFeedBacks pages through the feedbacks of a pharmacy on a day.
+	PharmacyFeedBacks(context.Context, *PharmacyFeedBacksRequest) (*FeedbackPage, error)
+	// UserFeedBacks pages through the feedbacks of a user on a day.
+	UserFeedBacks(context.Context, *UserFeedBacksRequest) (*FeedbackPage, error)
+	// InsertFeedBack records a feedback and returns its id.
+	InsertFeedBack(context.Context, *InsertFeedBackRequest) (*InsertFeedBackReply, error)
+	// Export streams the feedbacks between two days with pseudonymized user
+	// ids.
+	Export(*ExportRequest, FeedbackService_ExportServer) error
+	mustEmbedUnimplementedFeedbackServiceServer()
+}
+
+// UnimplementedFeedbackServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFeedbackServiceServer struct {
+}
+
+func (UnimplementedFeedbackServiceServer) Options(context.Context, *OptionsRequest) (*OptionsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Options not implemented")
+}
+func (UnimplementedFeedbackServiceServer) PharmacyFeedBacks(context.Context, *PharmacyFeedBacksRequest) (*FeedbackPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PharmacyFeedBacks not implemented")
+}
+func (UnimplementedFeedbackServiceServer) UserFeedBacks(context.Context, *UserFeedBacksRequest) (*FeedbackPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UserFeedBacks not implemented")
+}
+func (UnimplementedFeedbackServiceServer) InsertFeedBack(context.Context, *InsertFeedBackRequest) (*InsertFeedBackReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertFeedBack not implemented")
+}
+func (UnimplementedFeedbackServiceServer) Export(*ExportRequest, FeedbackService_ExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedFeedbackServiceServer) mustEmbedUnimplementedFeedbackServiceServer() {}
+
+// UnsafeFeedbackServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FeedbackServiceServer will
+// result in compilation errors.
+type UnsafeFeedbackServiceServer interface {
+	mustEmbedUnimplementedFeedbackServiceServer()
+}
+
+func RegisterFeedbackServiceServer(s grpc.ServiceRegistrar, srv FeedbackServiceServer) {
+	s.RegisterService(&FeedbackService_ServiceDesc, srv)
+}
+
+func _FeedbackService_Options_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedbackServiceServer).Options(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedbackService_Options_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedbackServiceServer).Options(ctx, req.(*OptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedbackService_PharmacyFeedBacks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PharmacyFeedBacksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedbackServiceServer).PharmacyFeedBacks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedbackService_PharmacyFeedBacks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedbackServiceServer).PharmacyFeedBacks(ctx, req.(*PharmacyFeedBacksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedbackService_UserFeedBacks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserFeedBacksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedbackServiceServer).UserFeedBacks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedbackService_UserFeedBacks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedbackServiceServer).UserFeedBacks(ctx, req.(*UserFeedBacksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedbackService_InsertFeedBack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertFeedBackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedbackServiceServer).InsertFeedBack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedbackService_InsertFeedBack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedbackServiceServer).InsertFeedBack(ctx, req.(*InsertFeedBackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedbackService_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FeedbackServiceServer).Export(m, &feedbackServiceExportServer{stream})
+}
+
+type FeedbackService_ExportServer interface {
+	Send(*FeedbackItem) error
+	grpc.ServerStream
+}
+
+type feedbackServiceExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *feedbackServiceExportServer) Send(m *FeedbackItem) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// FeedbackService_ServiceDesc is the grpc.ServiceDesc for FeedbackService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FeedbackService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mask.feedback.FeedbackService",
+	HandlerType: (*FeedbackServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Options",
+			Handler:    _FeedbackService_Options_Handler,
+		},
+		{
+			MethodName: "PharmacyFeedBacks",
+			Handler:    _FeedbackService_PharmacyFeedBacks_Handler,
+		},
+		{
+			MethodName: "UserFeedBacks",
+			Handler:    _FeedbackService_UserFeedBacks_Handler,
+		},
+		{
+			MethodName: "InsertFeedBack",
+			Handler:    _FeedbackService_InsertFeedBack_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Export",
+			Handler:       _FeedbackService_Export_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/feedback/feedback.proto",
+}
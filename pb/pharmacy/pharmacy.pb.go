@@ -0,0 +1,774 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        (unknown)
+// source: pb/pharmacy/pharmacy.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LatLng struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+}
+
+func (x *LatLng) Reset() {
+	*x = LatLng{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LatLng) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LatLng) ProtoMessage() {}
+
+func (x *LatLng) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LatLng.ProtoReflect.Descriptor instead.
+func (*LatLng) Descriptor() ([]byte, []int) {
+	return file_pb_pharmacy_pharmacy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LatLng) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *LatLng) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+type Bounds struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ne *LatLng `protobuf:"bytes,1,opt,name=ne,proto3" json:"ne,omitempty"`
+	Se *LatLng `protobuf:"bytes,2,opt,name=se,proto3" json:"se,omitempty"`
+	Sw *LatLng `protobuf:"bytes,3,opt,name=sw,proto3" json:"sw,omitempty"`
+	Nw *LatLng `protobuf:"bytes,4,opt,name=nw,proto3" json:"nw,omitempty"`
+}
+
+func (x *Bounds) Reset() {
+	*x = Bounds{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Bounds) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bounds) ProtoMessage() {}
+
+func (x *Bounds) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bounds.ProtoReflect.Descriptor instead.
+func (*Bounds) Descriptor() ([]byte, []int) {
+	return file_pb_pharmacy_pharmacy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Bounds) GetNe() *LatLng {
+	if x != nil {
+		return x.Ne
+	}
+	return nil
+}
+
+func (x *Bounds) GetSe() *LatLng {
+	if x != nil {
+		return x.Se
+	}
+	return nil
+}
+
+func (x *Bounds) GetSw() *LatLng {
+	if x != nil {
+		return x.Sw
+	}
+	return nil
+}
+
+func (x *Bounds) GetNw() *LatLng {
+	if x != nil {
+		return x.Nw
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Center *LatLng `protobuf:"bytes,1,opt,name=center,proto3" json:"center,omitempty"`
+	Bounds *Bounds `protobuf:"bytes,2,opt,name=bounds,proto3" json:"bounds,omitempty"`
+	Max    uint64  `protobuf:"varint,3,opt,name=max,proto3" json:"max,omitempty"`
+	// zoom is the map zoom level of the client, 0 - 22. Zero, the default,
+	// turns clustering off.
+	Zoom uint64 `protobuf:"varint,4,opt,name=zoom,proto3" json:"zoom,omitempty"`
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_pb_pharmacy_pharmacy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryRequest) GetCenter() *LatLng {
+	if x != nil {
+		return x.Center
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetBounds() *Bounds {
+	if x != nil {
+		return x.Bounds
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetMax() uint64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetZoom() uint64 {
+	if x != nil {
+		return x.Zoom
+	}
+	return 0
+}
+
+type Pharmacy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// distance from the center in miles.
+	Distance  float64 `protobuf:"fixed64,2,opt,name=distance,proto3" json:"distance,omitempty"`
+	Name      string  `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Phone     string  `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	Address   string  `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	MaskAdult uint64  `protobuf:"varint,6,opt,name=mask_adult,json=maskAdult,proto3" json:"mask_adult,omitempty"`
+	MaskChild uint64  `protobuf:"varint,7,opt,name=mask_child,json=maskChild,proto3" json:"mask_child,omitempty"`
+	// updated is RFC 3339 in Asia/Taipei, empty when unknown.
+	Updated        string  `protobuf:"bytes,8,opt,name=updated,proto3" json:"updated,omitempty"`
+	Available      string  `protobuf:"bytes,9,opt,name=available,proto3" json:"available,omitempty"`
+	CustomNote     string  `protobuf:"bytes,10,opt,name=custom_note,json=customNote,proto3" json:"custom_note,omitempty"`
+	Website        string  `protobuf:"bytes,11,opt,name=website,proto3" json:"website,omitempty"`
+	Note           string  `protobuf:"bytes,12,opt,name=note,proto3" json:"note,omitempty"`
+	Longitude      float64 `protobuf:"fixed64,13,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude       float64 `protobuf:"fixed64,14,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	ServicePeriods string  `protobuf:"bytes,15,opt,name=service_periods,json=servicePeriods,proto3" json:"service_periods,omitempty"`
+	ServiceNote    string  `protobuf:"bytes,16,opt,name=service_note,json=serviceNote,proto3" json:"service_note,omitempty"`
+	County         string  `protobuf:"bytes,17,opt,name=county,proto3" json:"county,omitempty"`
+	Town           string  `protobuf:"bytes,18,opt,name=town,proto3" json:"town,omitempty"`
+	Cunli          string  `protobuf:"bytes,19,opt,name=cunli,proto3" json:"cunli,omitempty"`
+}
+
+func (x *Pharmacy) Reset() {
+	*x = Pharmacy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Pharmacy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pharmacy) ProtoMessage() {}
+
+func (x *Pharmacy) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pharmacy.ProtoReflect.Descriptor instead.
+func (*Pharmacy) Descriptor() ([]byte, []int) {
+	return file_pb_pharmacy_pharmacy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Pharmacy) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *Pharmacy) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetMaskAdult() uint64 {
+	if x != nil {
+		return x.MaskAdult
+	}
+	return 0
+}
+
+func (x *Pharmacy) GetMaskChild() uint64 {
+	if x != nil {
+		return x.MaskChild
+	}
+	return 0
+}
+
+func (x *Pharmacy) GetUpdated() string {
+	if x != nil {
+		return x.Updated
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetAvailable() string {
+	if x != nil {
+		return x.Available
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetCustomNote() string {
+	if x != nil {
+		return x.CustomNote
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetWebsite() string {
+	if x != nil {
+		return x.Website
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Pharmacy) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Pharmacy) GetServicePeriods() string {
+	if x != nil {
+		return x.ServicePeriods
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetServiceNote() string {
+	if x != nil {
+		return x.ServiceNote
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetCounty() string {
+	if x != nil {
+		return x.County
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetTown() string {
+	if x != nil {
+		return x.Town
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetCunli() string {
+	if x != nil {
+		return x.Cunli
+	}
+	return ""
+}
+
+type Cluster struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Longitude float64 `protobuf:"fixed64,1,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude  float64 `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Count     uint64  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	MaskAdult uint64  `protobuf:"varint,4,opt,name=mask_adult,json=maskAdult,proto3" json:"mask_adult,omitempty"`
+	MaskChild uint64  `protobuf:"varint,5,opt,name=mask_child,json=maskChild,proto3" json:"mask_child,omitempty"`
+}
+
+func (x *Cluster) Reset() {
+	*x = Cluster{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Cluster) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cluster) ProtoMessage() {}
+
+func (x *Cluster) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cluster.ProtoReflect.Descriptor instead.
+func (*Cluster) Descriptor() ([]byte, []int) {
+	return file_pb_pharmacy_pharmacy_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Cluster) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Cluster) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Cluster) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Cluster) GetMaskAdult() uint64 {
+	if x != nil {
+		return x.MaskAdult
+	}
+	return 0
+}
+
+func (x *Cluster) GetMaskChild() uint64 {
+	if x != nil {
+		return x.MaskChild
+	}
+	return 0
+}
+
+type QueryReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items    []*Pharmacy `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	Clusters []*Cluster  `protobuf:"bytes,2,rep,name=clusters,proto3" json:"clusters,omitempty"`
+}
+
+func (x *QueryReply) Reset() {
+	*x = QueryReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryReply) ProtoMessage() {}
+
+func (x *QueryReply) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_pharmacy_pharmacy_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryReply.ProtoReflect.Descriptor instead.
+func (*QueryReply) Descriptor() ([]byte, []int) {
+	return file_pb_pharmacy_pharmacy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *QueryReply) GetItems() []*Pharmacy {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *QueryReply) GetClusters() []*Cluster {
+	if x != nil {
+		return x.Clusters
+	}
+	return nil
+}
+
+var File_pb_pharmacy_pharmacy_proto protoreflect.FileDescriptor
+
+var file_pb_pharmacy_pharmacy_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x70, 0x62, 0x2f, 0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2f, 0x70, 0x68,
+	0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x6d, 0x61,
+	0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x22, 0x2c, 0x0a, 0x06, 0x4c,
+	0x61, 0x74, 0x4c, 0x6e, 0x67, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67, 0x22, 0xa4, 0x01, 0x0a, 0x06, 0x42, 0x6f,
+	0x75, 0x6e, 0x64, 0x73, 0x12, 0x25, 0x0a, 0x02, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x15, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79,
+	0x2e, 0x4c, 0x61, 0x74, 0x4c, 0x6e, 0x67, 0x52, 0x02, 0x6e, 0x65, 0x12, 0x25, 0x0a, 0x02, 0x73,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70,
+	0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x4c, 0x61, 0x74, 0x4c, 0x6e, 0x67, 0x52, 0x02,
+	0x73, 0x65, 0x12, 0x25, 0x0a, 0x02, 0x73, 0x77, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15,
+	0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x4c,
+	0x61, 0x74, 0x4c, 0x6e, 0x67, 0x52, 0x02, 0x73, 0x77, 0x12, 0x25, 0x0a, 0x02, 0x6e, 0x77, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61,
+	0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x4c, 0x61, 0x74, 0x4c, 0x6e, 0x67, 0x52, 0x02, 0x6e, 0x77,
+	0x22, 0x92, 0x01, 0x0a, 0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x2d, 0x0a, 0x06, 0x63, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63,
+	0x79, 0x2e, 0x4c, 0x61, 0x74, 0x4c, 0x6e, 0x67, 0x52, 0x06, 0x63, 0x65, 0x6e, 0x74, 0x65, 0x72,
+	0x12, 0x2d, 0x0a, 0x06, 0x62, 0x6f, 0x75, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x15, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79,
+	0x2e, 0x42, 0x6f, 0x75, 0x6e, 0x64, 0x73, 0x52, 0x06, 0x62, 0x6f, 0x75, 0x6e, 0x64, 0x73, 0x12,
+	0x10, 0x0a, 0x03, 0x6d, 0x61, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6d, 0x61,
+	0x78, 0x12, 0x12, 0x0a, 0x04, 0x7a, 0x6f, 0x6f, 0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x04, 0x7a, 0x6f, 0x6f, 0x6d, 0x22, 0x87, 0x04, 0x0a, 0x08, 0x50, 0x68, 0x61, 0x72, 0x6d, 0x61,
+	0x63, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x61, 0x73, 0x6b, 0x5f, 0x61, 0x64, 0x75, 0x6c, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6d, 0x61, 0x73, 0x6b, 0x41, 0x64, 0x75, 0x6c,
+	0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x61, 0x73, 0x6b, 0x5f, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6d, 0x61, 0x73, 0x6b, 0x43, 0x68, 0x69, 0x6c, 0x64,
+	0x12, 0x18, 0x0a, 0x07, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x61, 0x76,
+	0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61,
+	0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x75, 0x73, 0x74,
+	0x6f, 0x6d, 0x5f, 0x6e, 0x6f, 0x74, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63,
+	0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4e, 0x6f, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x77, 0x65, 0x62,
+	0x73, 0x69, 0x74, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x77, 0x65, 0x62, 0x73,
+	0x69, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x6f, 0x74, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x6f, 0x74, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69,
+	0x74, 0x75, 0x64, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67,
+	0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64,
+	0x65, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64,
+	0x65, 0x12, 0x27, 0x0a, 0x0f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x70, 0x65, 0x72,
+	0x69, 0x6f, 0x64, 0x73, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x50, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x6e, 0x6f, 0x74, 0x65, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4e, 0x6f, 0x74, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x79, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x6f, 0x77, 0x6e, 0x18, 0x12, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x6f, 0x77, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x75, 0x6e,
+	0x6c, 0x69, 0x18, 0x13, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x75, 0x6e, 0x6c, 0x69, 0x22,
+	0x97, 0x01, 0x0a, 0x07, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x6c,
+	0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09,
+	0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x74,
+	0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6c, 0x61, 0x74,
+	0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x6d,
+	0x61, 0x73, 0x6b, 0x5f, 0x61, 0x64, 0x75, 0x6c, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x09, 0x6d, 0x61, 0x73, 0x6b, 0x41, 0x64, 0x75, 0x6c, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x61,
+	0x73, 0x6b, 0x5f, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09,
+	0x6d, 0x61, 0x73, 0x6b, 0x43, 0x68, 0x69, 0x6c, 0x64, 0x22, 0x6f, 0x0a, 0x0a, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2d, 0x0a, 0x05, 0x69, 0x74, 0x65, 0x6d, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68,
+	0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x50, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x52,
+	0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x12, 0x32, 0x0a, 0x08, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65,
+	0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e,
+	0x70, 0x68, 0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72,
+	0x52, 0x08, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x73, 0x32, 0x52, 0x0a, 0x0f, 0x50, 0x68,
+	0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3f, 0x0a,
+	0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x1b, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68,
+	0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x68, 0x61, 0x72, 0x6d,
+	0x61, 0x63, 0x79, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x29,
+	0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x61, 0x67,
+	0x65, 0x31, 0x30, 0x31, 0x36, 0x2f, 0x6d, 0x61, 0x73, 0x6b, 0x2f, 0x70, 0x62, 0x2f, 0x70, 0x68,
+	0x61, 0x72, 0x6d, 0x61, 0x63, 0x79, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_pb_pharmacy_pharmacy_proto_rawDescOnce sync.Once
+	file_pb_pharmacy_pharmacy_proto_rawDescData = file_pb_pharmacy_pharmacy_proto_rawDesc
+)
+
+func file_pb_pharmacy_pharmacy_proto_rawDescGZIP() []byte {
+	file_pb_pharmacy_pharmacy_proto_rawDescOnce.Do(func() {
+		file_pb_pharmacy_pharmacy_proto_rawDescData = protoimpl.X.CompressGZIP(file_pb_pharmacy_pharmacy_proto_rawDescData)
+	})
+	return file_pb_pharmacy_pharmacy_proto_rawDescData
+}
+
+var file_pb_pharmacy_pharmacy_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_pb_pharmacy_pharmacy_proto_goTypes = []interface{}{
+	(*LatLng)(nil),       // 0: mask.pharmacy.LatLng
+	(*Bounds)(nil),       // 1: mask.pharmacy.Bounds
+	(*QueryRequest)(nil), // 2: mask.pharmacy.QueryRequest
+	(*Pharmacy)(nil),     // 3: mask.pharmacy.Pharmacy
+	(*Cluster)(nil),      // 4: mask.pharmacy.Cluster
+	(*QueryReply)(nil),   // 5: mask.pharmacy.QueryReply
+}
+var file_pb_pharmacy_pharmacy_proto_depIdxs = []int32{
+	0, // 0: mask.pharmacy.Bounds.ne:type_name -> mask.pharmacy.LatLng
+	0, // 1: mask.pharmacy.Bounds.se:type_name -> mask.pharmacy.LatLng
+	0, // 2: mask.pharmacy.Bounds.sw:type_name -> mask.pharmacy.LatLng
+	0, // 3: mask.pharmacy.Bounds.nw:type_name -> mask.pharmacy.LatLng
+	0, // 4: mask.pharmacy.QueryRequest.center:type_name -> mask.pharmacy.LatLng
+	1, // 5: mask.pharmacy.QueryRequest.bounds:type_name -> mask.pharmacy.Bounds
+	3, // 6: mask.pharmacy.QueryReply.items:type_name -> mask.pharmacy.Pharmacy
+	4, // 7: mask.pharmacy.QueryReply.clusters:type_name -> mask.pharmacy.Cluster
+	2, // 8: mask.pharmacy.PharmacyService.Query:input_type -> mask.pharmacy.QueryRequest
+	5, // 9: mask.pharmacy.PharmacyService.Query:output_type -> mask.pharmacy.QueryReply
+	9, // [9:10] is the sub-list for method output_type
+	8, // [8:9] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_pb_pharmacy_pharmacy_proto_init() }
+func file_pb_pharmacy_pharmacy_proto_init() {
+	if File_pb_pharmacy_pharmacy_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pb_pharmacy_pharmacy_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LatLng); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_pharmacy_pharmacy_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Bounds); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_pharmacy_pharmacy_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_pharmacy_pharmacy_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Pharmacy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_pharmacy_pharmacy_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Cluster); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_pharmacy_pharmacy_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_pb_pharmacy_pharmacy_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pb_pharmacy_pharmacy_proto_goTypes,
+		DependencyIndexes: file_pb_pharmacy_pharmacy_proto_depIdxs,
+		MessageInfos:      file_pb_pharmacy_pharmacy_proto_msgTypes,
+	}.Build()
+	File_pb_pharmacy_pharmacy_proto = out.File
+	file_pb_pharmacy_pharmacy_proto_rawDesc = nil
+	file_pb_pharmacy_pharmacy_proto_goTypes = nil
+	file_pb_pharmacy_pharmacy_proto_depIdxs = nil
+}
@@ -0,0 +1,113 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: pb/pharmacy/pharmacy.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PharmacyService_Query_FullMethodName = "/mask.pharmacy.PharmacyService/Query"
+)
+
+// PharmacyServiceClient is the client API for PharmacyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PharmacyServiceClient interface {
+	// Query returns the pharmacies nearest to center within bounds, or their
+	// clusters when zoom is 1 - 13.
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryReply, error)
+}
+
+type pharmacyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPharmacyServiceClient(cc grpc.ClientConnInterface) PharmacyServiceClient {
+	return &pharmacyServiceClient{cc}
+}
+
+func (c *pharmacyServiceClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryReply, error) {
+	out := new(QueryReply)
+	err := c.cc.Invoke(ctx, PharmacyService_Query_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PharmacyServiceServer is the server API for PharmacyService service.
+// All implementations must embed UnimplementedPharmacyServiceServer
+// for forward compatibility
+type PharmacyServiceServer interface {
+	// Query returns the pharmacies nearest to center within bounds, or their
+	// clusters when zoom is 1 - 13.
+	Query(context.Context, *QueryRequest) (*QueryReply, error)
+	mustEmbedUnimplementedPharmacyServiceServer()
+}
+
+// UnimplementedPharmacyServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPharmacyServiceServer struct {
+}
+
+func (UnimplementedPharmacyServiceServer) Query(context.Context, *QueryRequest) (*QueryReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedPharmacyServiceServer) mustEmbedUnimplementedPharmacyServiceServer() {}
+
+// UnsafePharmacyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PharmacyServiceServer will
+// result in compilation errors.
+type UnsafePharmacyServiceServer interface {
+	mustEmbedUnimplementedPharmacyServiceServer()
+}
+
+func RegisterPharmacyServiceServer(s grpc.ServiceRegistrar, srv PharmacyServiceServer) {
+	s.RegisterService(&PharmacyService_ServiceDesc, srv)
+}
+
+func _PharmacyService_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PharmacyServiceServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PharmacyService_Query_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PharmacyServiceServer).Query(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PharmacyService_ServiceDesc is the grpc.ServiceDesc for PharmacyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PharmacyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mask.pharmacy.PharmacyService",
+	HandlerType: (*PharmacyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Query",
+			Handler:    _PharmacyService_Query_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pb/pharmacy/pharmacy.proto",
+}
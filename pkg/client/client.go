@@ -0,0 +1,285 @@
+// Package client calls the pharmacy and feedback services over their
+// /api/v2 HTTP routes.
+//
+// Requests and results reuse the types of the services. Failed calls return a
+// StatusError, which unwinds the error envelope into the chain of errors the
+// service failed with, so errors.Is matches the exported errors of this
+// package. Idempotent calls are retried on transport errors and 5xx replies.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/pkg/errors"
+	"github.com/cage1016/mask/internal/pkg/responses"
+)
+
+const (
+	defTimeout     = 10 * time.Second
+	defMaxAttempts = 3
+	defBackoff     = 100 * time.Millisecond
+
+	// maxErrorBody bounds how much of a reply without an error envelope is
+	// read into its StatusError.
+	maxErrorBody = 1024
+)
+
+var (
+	// ErrMalformedEntity indicates the service rejected the request as malformed.
+	ErrMalformedEntity = service.ErrMalformedEntity
+
+	// ErrWebhookNotFound indicates the webhook subscription does not exist.
+	ErrWebhookNotFound = model.ErrWebhookNotFound
+
+	// ErrWatchNotFound indicates the user does not follow the pharmacy.
+	ErrWatchNotFound = model.ErrWatchNotFound
+
+	// ErrUnexpectedResponse indicates a reply without the error envelope, e.g.
+	// from a proxy in front of the service.
+	ErrUnexpectedResponse = errors.New("unexpected response")
+)
+
+// Config tunes a client. Zero fields take the defaults.
+type Config struct {
+	// Timeout bounds every attempt of a call, 10s by default.
+	Timeout time.Duration
+	// MaxAttempts bounds the attempts of idempotent calls, 3 by default.
+	MaxAttempts int
+	// Backoff is the wait before the second attempt, doubled after every
+	// further one, 100ms by default.
+	Backoff time.Duration
+	// HTTPClient sends the requests, http.DefaultClient by default.
+	HTTPClient *http.Client
+	// Token is sent as a bearer token, as the admin routes such as the
+	// feedback export require.
+	Token string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = defTimeout
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defMaxAttempts
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = defBackoff
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+var _ errors.Error = (*StatusError)(nil)
+
+// StatusError is the error of a call the service answered with a non
+// successful status.
+type StatusError struct {
+	// Code is the HTTP status code of the reply.
+	Code int
+	err  errors.Error
+}
+
+func (e StatusError) Errors() []errors.Errors {
+	return e.err.Errors()
+}
+
+func (e StatusError) Error() string {
+	return e.err.Error()
+}
+
+func (e StatusError) Msg() string {
+	return e.err.Msg()
+}
+
+func (e StatusError) Err() errors.Error {
+	return e.err.Err()
+}
+
+// Is reports whether target is one of the errors the service failed with.
+func (e StatusError) Is(target error) bool {
+	return errors.Contains(e.err, target)
+}
+
+// temporary reports whether a failed attempt may succeed when retried.
+func temporary(err error) bool {
+	switch e := err.(type) {
+	case StatusError:
+		return e.Code >= http.StatusInternalServerError || e.Code == http.StatusTooManyRequests
+	case *url.Error:
+		return true
+	}
+	return false
+}
+
+// decodeError reads the error envelope of a failed reply. The envelope lists
+// the messages of the error chain outermost first; they are wrapped back
+// together in that order.
+func decodeError(r *http.Response) error {
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxErrorBody))
+	if err != nil {
+		return StatusError{Code: r.StatusCode, err: errors.Wrap(ErrUnexpectedResponse, err)}
+	}
+
+	var res responses.ErrorRes
+	if err := json.Unmarshal(b, &res); err != nil || len(res.Error.Errors) == 0 {
+		text := strings.TrimSpace(string(b))
+		if text == "" {
+			text = http.StatusText(r.StatusCode)
+		}
+		return StatusError{Code: r.StatusCode, err: errors.Wrap(ErrUnexpectedResponse, errors.New(text))}
+	}
+
+	errs := res.Error.Errors
+	chain := errors.New(errs[len(errs)-1].Message)
+	for i := len(errs) - 2; i >= 0; i-- {
+		chain = errors.Wrap(errors.New(errs[i].Message), chain)
+	}
+	return StatusError{Code: r.StatusCode, err: chain}
+}
+
+// decodeEnvelope returns a transport/http.DecodeResponseFunc that reads the
+// data and paging of the envelope into a value made by newData.
+func decodeEnvelope(newData func(*responses.Paging) (data interface{}, res func() interface{})) httptransport.DecodeResponseFunc {
+	return func(_ context.Context, r *http.Response) (interface{}, error) {
+		if r.StatusCode >= http.StatusBadRequest {
+			return nil, decodeError(r)
+		}
+
+		var env struct {
+			Data   json.RawMessage   `json:"data"`
+			Paging *responses.Paging `json:"paging"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+			return nil, errors.Wrap(ErrUnexpectedResponse, err)
+		}
+
+		data, res := newData(env.Paging)
+		if err := json.Unmarshal(env.Data, data); err != nil {
+			return nil, errors.Wrap(ErrUnexpectedResponse, err)
+		}
+		return res(), nil
+	}
+}
+
+// decodeNoContent is a transport/http.DecodeResponseFunc for calls answered
+// without a body.
+func decodeNoContent(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	return nil, nil
+}
+
+// decodeStream is a transport/http.DecodeResponseFunc handing over the body
+// of a successful reply to the caller, who must close it.
+func decodeStream(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		defer r.Body.Close()
+		return nil, decodeError(r)
+	}
+	return r.Body, nil
+}
+
+// encodeJSON is a transport/http.EncodeRequestFunc that sends the request as
+// JSON to the path returned by path.
+func encodeJSON(path func(request interface{}) string) httptransport.EncodeRequestFunc {
+	return func(ctx context.Context, r *http.Request, request interface{}) error {
+		r.URL.Path = path(request)
+		return httptransport.EncodeJSONRequest(ctx, r, request)
+	}
+}
+
+// encodeURL is a transport/http.EncodeRequestFunc that sends the request in
+// the URL returned by target.
+func encodeURL(target func(request interface{}) (path string, query url.Values)) httptransport.EncodeRequestFunc {
+	return func(_ context.Context, r *http.Request, request interface{}) error {
+		path, query := target(request)
+		r.URL.Path = path
+		r.URL.RawQuery = query.Encode()
+		return nil
+	}
+}
+
+// newEndpoint builds the endpoint of one call. Every attempt is bounded by
+// cfg.Timeout and, when the call is idempotent, retried.
+func newEndpoint(base *url.URL, method string, enc httptransport.EncodeRequestFunc, dec httptransport.DecodeResponseFunc, cfg Config, idempotent bool) endpoint.Endpoint {
+	e := httptransport.NewClient(method, base, enc, dec, clientOptions(cfg)...).Endpoint()
+	e = timeout(cfg.Timeout)(e)
+	if idempotent {
+		e = retry(cfg.MaxAttempts, cfg.Backoff)(e)
+	}
+	return e
+}
+
+// newStreamEndpoint builds the endpoint of a call whose reply body is handed
+// over to the caller. It is neither bounded nor retried; the caller's context
+// governs it until the body is closed.
+func newStreamEndpoint(base *url.URL, method string, enc httptransport.EncodeRequestFunc, cfg Config) endpoint.Endpoint {
+	return httptransport.NewClient(method, base, enc, decodeStream, append(clientOptions(cfg), httptransport.BufferedStream(true))...).Endpoint()
+}
+
+func clientOptions(cfg Config) []httptransport.ClientOption {
+	options := []httptransport.ClientOption{httptransport.SetClient(cfg.HTTPClient)}
+	if cfg.Token != "" {
+		options = append(options, httptransport.ClientBefore(func(ctx context.Context, r *http.Request) context.Context {
+			r.Header.Set("Authorization", "Bearer "+cfg.Token)
+			return ctx
+		}))
+	}
+	return options
+}
+
+// timeout bounds every call of the endpoint by d.
+func timeout(d time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
+
+// retry calls the endpoint up to maxAttempts times while it fails
+// temporarily, waiting backoff, 2*backoff, 4*backoff... in between.
+func retry(maxAttempts int, backoff time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+				response, err = next(ctx, request)
+				if err == nil || attempt >= maxAttempts || !temporary(err) {
+					return response, err
+				}
+
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return nil, err
+				}
+				wait *= 2
+			}
+		}
+	}
+}
+
+func parseInstance(instance string) (*url.URL, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	return url.Parse(instance)
+}
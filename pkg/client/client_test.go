@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flaky answers 503 to the first fails requests and then a watch list.
+func flaky(fails int32, calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= fails {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": {"code": 503, "message": "snapshot not loaded", "errors": [{"message": "snapshot not loaded"}]}}`))
+			return
+		}
+		w.Write([]byte(`{"apiVersion": "v2", "data": []}`))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		desc      string
+		fails     int32
+		wantCalls int32
+		wantCode  int
+	}{
+		{"recovers", 2, 3, 0},
+		{"gives up", 5, 3, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		var calls int32
+		srv := httptest.NewServer(flaky(tc.fails, &calls))
+		c, err := NewPharmacy(srv.URL, Config{Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.Watches(context.Background(), "user")
+		srv.Close()
+
+		if calls != tc.wantCalls {
+			t.Errorf("%s: got %d calls, want %d", tc.desc, calls, tc.wantCalls)
+		}
+		se, _ := err.(StatusError)
+		if se.Code != tc.wantCode || (err == nil) != (tc.wantCode == 0) {
+			t.Errorf("%s: got %v, want status %d", tc.desc, err, tc.wantCode)
+		}
+	}
+}
+
+func TestRetrySkipsInserts(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(flaky(1, &calls))
+	defer srv.Close()
+
+	c, err := NewFeedback(srv.URL, Config{Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FeedBack(context.Background(), FeedBackRequest{}); err == nil || calls != 1 {
+		t.Errorf("got %v after %d calls, want one failed call", err, calls)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	c, err := NewPharmacy(srv.URL, Config{Timeout: 20 * time.Millisecond, MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = c.ListWebhooks(context.Background())
+	if err == nil || time.Since(start) > time.Second {
+		t.Errorf("got %v after %v, want a timeout", err, time.Since(start))
+	}
+}
+
+func TestUnexpectedResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c, err := NewPharmacy(srv.URL, Config{MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ListWebhooks(context.Background())
+	if !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("got %v, want %v", err, ErrUnexpectedResponse)
+	}
+	if se, ok := err.(StatusError); !ok || se.Code != http.StatusBadGateway {
+		t.Errorf("got %#v, want a 502 StatusError", err)
+	}
+}
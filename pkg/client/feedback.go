@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/cage1016/mask/internal/app/feedback/endpoints"
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/pkg/responses"
+)
+
+// Requests of the feedback service.
+type (
+	FeedBackRequest          = endpoints.FeedBackRequest
+	PharmacyFeedBacksRequest = endpoints.PharmacyFeedBacksRequest
+	UserFeedBacksRequest     = endpoints.UserFeedBacksRequest
+	FeedbackExportRequest    = endpoints.ExportRequest
+)
+
+// Results of the feedback service.
+type (
+	Option           = model.Option
+	Feedback         = model.Feedback
+	FeedbackItemPage = model.FeedbackItemPage
+)
+
+const feedbackPath = responses.V2Prefix + "feedback"
+
+// FeedbackClient calls the feedback service.
+type FeedbackClient struct {
+	options           endpoint.Endpoint
+	pharmacyFeedBacks endpoint.Endpoint
+	userFeedBacks     endpoint.Endpoint
+	feedBack          endpoint.Endpoint
+	export            endpoint.Endpoint
+}
+
+// NewFeedback returns a client of the feedback service listening at instance,
+// e.g. "localhost:8080" or "https://mask.example.com".
+func NewFeedback(instance string, cfg Config) (*FeedbackClient, error) {
+	base, err := parseInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	decodePage := decodeEnvelope(func(paging *responses.Paging) (interface{}, func() interface{}) {
+		var res FeedbackItemPage
+		return &res.Items, func() interface{} {
+			res.Offset, res.Limit, res.Total = fromPaging(paging)
+			return res
+		}
+	})
+
+	return &FeedbackClient{
+		options: newEndpoint(base, http.MethodGet,
+			encodeURL(func(interface{}) (string, url.Values) {
+				return feedbackPath + "/options", nil
+			}),
+			decodeEnvelope(func(*responses.Paging) (interface{}, func() interface{}) {
+				var res []Option
+				return &res, func() interface{} { return res }
+			}),
+			cfg, true),
+		pharmacyFeedBacks: newEndpoint(base, http.MethodGet,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				req := request.(PharmacyFeedBacksRequest)
+				return feedbackPath + "/pharmacies/" + url.PathEscape(req.PharmacyID), dateQuery(req.Date, req.Offset, req.Limit)
+			}),
+			decodePage,
+			cfg, true),
+		userFeedBacks: newEndpoint(base, http.MethodGet,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				req := request.(UserFeedBacksRequest)
+				return feedbackPath + "/users/" + url.PathEscape(req.UserID), dateQuery(req.Date, req.Offset, req.Limit)
+			}),
+			decodePage,
+			cfg, true),
+		feedBack: newEndpoint(base, http.MethodPost,
+			encodeJSON(func(interface{}) string { return feedbackPath }),
+			decodeEnvelope(func(*responses.Paging) (interface{}, func() interface{}) {
+				var res endpoints.FeedBackResponse
+				return &res, func() interface{} { return res }
+			}),
+			cfg, false),
+		export: newStreamEndpoint(base, http.MethodGet,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				req := request.(FeedbackExportRequest)
+				q := url.Values{"format": {req.Format}}
+				if req.From != "" {
+					q.Set("from", req.From)
+				}
+				if req.To != "" {
+					q.Set("to", req.To)
+				}
+				return feedbackPath + "/export", q
+			}),
+			cfg),
+	}, nil
+}
+
+// Options returns the options a feedback picks from.
+func (c *FeedbackClient) Options(ctx context.Context) ([]Option, error) {
+	res, err := c.options(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]Option), nil
+}
+
+// PharmacyFeedBacks returns a page of the feedback about req.PharmacyID on
+// req.Date, today when unset.
+func (c *FeedbackClient) PharmacyFeedBacks(ctx context.Context, req PharmacyFeedBacksRequest) (FeedbackItemPage, error) {
+	res, err := c.pharmacyFeedBacks(ctx, req)
+	if err != nil {
+		return FeedbackItemPage{}, err
+	}
+	return res.(FeedbackItemPage), nil
+}
+
+// UserFeedBacks returns a page of the feedback of req.UserID on req.Date,
+// today when unset.
+func (c *FeedbackClient) UserFeedBacks(ctx context.Context, req UserFeedBacksRequest) (FeedbackItemPage, error) {
+	res, err := c.userFeedBacks(ctx, req)
+	if err != nil {
+		return FeedbackItemPage{}, err
+	}
+	return res.(FeedbackItemPage), nil
+}
+
+// FeedBack saves req and returns the id of the new feedback. It is not
+// retried, so a failed call may still have saved it.
+func (c *FeedbackClient) FeedBack(ctx context.Context, req FeedBackRequest) (string, error) {
+	res, err := c.feedBack(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return res.(endpoints.FeedBackResponse).ID, nil
+}
+
+// Export returns the feedback from req.From to req.To, both today when unset,
+// in req.Format ("csv" or "ndjson"). The caller must close it.
+func (c *FeedbackClient) Export(ctx context.Context, req FeedbackExportRequest) (io.ReadCloser, error) {
+	res, err := c.export(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res.(io.ReadCloser), nil
+}
+
+// dateQuery encodes the date and paging of a feedback page request.
+func dateQuery(date string, offset, limit uint64) url.Values {
+	q := pageQuery(offset, limit)
+	if date != "" {
+		q.Set("date", date)
+	}
+	return q
+}
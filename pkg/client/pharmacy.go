@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/responses"
+)
+
+// Requests of the pharmacy service.
+type (
+	LatLng                   = endpoints.LatLng
+	Bounds                   = endpoints.Bounds
+	QueryRequest             = endpoints.QueryRequest
+	CreateWebhookRequest     = endpoints.CreateWebhookRequest
+	WebhookDeliveriesRequest = endpoints.WebhookDeliveriesRequest
+	FollowRequest            = endpoints.FollowRequest
+)
+
+// Results of the pharmacy service.
+type (
+	Pharmacy     = model.Pharmacy
+	Cluster      = model.Cluster
+	Webhook      = model.Webhook
+	Delivery     = model.Delivery
+	DeliveryPage = model.DeliveryPage
+	Watch        = model.Watch
+)
+
+const pharmaciesPath = responses.V2Prefix + "pharmacies"
+
+// PharmacyClient calls the pharmacy service.
+type PharmacyClient struct {
+	query             endpoint.Endpoint
+	export            endpoint.Endpoint
+	createWebhook     endpoint.Endpoint
+	listWebhooks      endpoint.Endpoint
+	removeWebhook     endpoint.Endpoint
+	webhookDeliveries endpoint.Endpoint
+	follow            endpoint.Endpoint
+	unfollow          endpoint.Endpoint
+	watches           endpoint.Endpoint
+}
+
+// NewPharmacy returns a client of the pharmacy service listening at instance,
+// e.g. "localhost:8080" or "https://mask.example.com".
+func NewPharmacy(instance string, cfg Config) (*PharmacyClient, error) {
+	base, err := parseInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	fixed := func(path string) func(interface{}) string {
+		return func(interface{}) string { return path }
+	}
+
+	return &PharmacyClient{
+		// Query only reads, so it is retried although it is a POST.
+		query: newEndpoint(base, http.MethodPost,
+			encodeJSON(fixed(pharmaciesPath)),
+			decodeEnvelope(func(*responses.Paging) (interface{}, func() interface{}) {
+				var res endpoints.QueryResponse
+				return &res, func() interface{} { return res }
+			}),
+			cfg, true),
+		export: newStreamEndpoint(base, http.MethodGet,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				return pharmaciesPath + "/export", url.Values{"format": {request.(string)}}
+			}),
+			cfg),
+		createWebhook: newEndpoint(base, http.MethodPost,
+			encodeJSON(fixed(pharmaciesPath+"/webhooks")),
+			decodeEnvelope(func(*responses.Paging) (interface{}, func() interface{}) {
+				var res Webhook
+				return &res, func() interface{} { return res }
+			}),
+			cfg, false),
+		listWebhooks: newEndpoint(base, http.MethodGet,
+			encodeURL(func(interface{}) (string, url.Values) {
+				return pharmaciesPath + "/webhooks", nil
+			}),
+			decodeEnvelope(func(*responses.Paging) (interface{}, func() interface{}) {
+				var res []Webhook
+				return &res, func() interface{} { return res }
+			}),
+			cfg, true),
+		removeWebhook: newEndpoint(base, http.MethodDelete,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				return pharmaciesPath + "/webhooks/" + url.PathEscape(request.(string)), nil
+			}),
+			decodeNoContent,
+			cfg, true),
+		webhookDeliveries: newEndpoint(base, http.MethodGet,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				req := request.(WebhookDeliveriesRequest)
+				return pharmaciesPath + "/webhooks/" + url.PathEscape(req.ID) + "/deliveries", pageQuery(req.Offset, req.Limit)
+			}),
+			decodeEnvelope(func(paging *responses.Paging) (interface{}, func() interface{}) {
+				var res DeliveryPage
+				return &res.Items, func() interface{} {
+					res.Offset, res.Limit, res.Total = fromPaging(paging)
+					return res
+				}
+			}),
+			cfg, true),
+		// Following again only updates the thresholds, so it is retried.
+		follow: newEndpoint(base, http.MethodPost,
+			encodeJSON(fixed(pharmaciesPath+"/watches")),
+			decodeNoContent,
+			cfg, true),
+		unfollow: newEndpoint(base, http.MethodDelete,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				req := request.(endpoints.UnfollowRequest)
+				return pharmaciesPath + "/watches/" + url.PathEscape(req.UserID) + "/" + url.PathEscape(req.PharmacyID), nil
+			}),
+			decodeNoContent,
+			cfg, true),
+		watches: newEndpoint(base, http.MethodGet,
+			encodeURL(func(request interface{}) (string, url.Values) {
+				return pharmaciesPath + "/watches/" + url.PathEscape(request.(string)), nil
+			}),
+			decodeEnvelope(func(*responses.Paging) (interface{}, func() interface{}) {
+				var res []Watch
+				return &res, func() interface{} { return res }
+			}),
+			cfg, true),
+	}, nil
+}
+
+// Query returns the pharmacies within the bounds of req, or their clusters
+// when req asks for a zoom level that clusters them.
+func (c *PharmacyClient) Query(ctx context.Context, req QueryRequest) (items []Pharmacy, clusters []Cluster, err error) {
+	res, err := c.query(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	rs := res.(endpoints.QueryResponse)
+	return rs.Items, rs.Clusters, nil
+}
+
+// Export returns the snapshot in format ("csv", "ndjson" or "geojson"). The
+// caller must close it.
+func (c *PharmacyClient) Export(ctx context.Context, format string) (io.ReadCloser, error) {
+	res, err := c.export(ctx, format)
+	if err != nil {
+		return nil, err
+	}
+	return res.(io.ReadCloser), nil
+}
+
+// CreateWebhook subscribes req.URL to the events of req and returns the new
+// subscription. It is not retried.
+func (c *PharmacyClient) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (Webhook, error) {
+	res, err := c.createWebhook(ctx, req)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return res.(Webhook), nil
+}
+
+// ListWebhooks returns every webhook subscription.
+func (c *PharmacyClient) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	res, err := c.listWebhooks(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]Webhook), nil
+}
+
+// RemoveWebhook removes the webhook subscription id.
+func (c *PharmacyClient) RemoveWebhook(ctx context.Context, id string) error {
+	_, err := c.removeWebhook(ctx, id)
+	return err
+}
+
+// WebhookDeliveries returns a page of the deliveries to webhook req.ID.
+func (c *PharmacyClient) WebhookDeliveries(ctx context.Context, req WebhookDeliveriesRequest) (DeliveryPage, error) {
+	res, err := c.webhookDeliveries(ctx, req)
+	if err != nil {
+		return DeliveryPage{}, err
+	}
+	return res.(DeliveryPage), nil
+}
+
+// Follow notifies req.UserID when the stock of req.PharmacyID drops below
+// the thresholds of req.
+func (c *PharmacyClient) Follow(ctx context.Context, req FollowRequest) error {
+	_, err := c.follow(ctx, req)
+	return err
+}
+
+// Unfollow stops the notifications of userID about pharmacyID.
+func (c *PharmacyClient) Unfollow(ctx context.Context, userID, pharmacyID string) error {
+	_, err := c.unfollow(ctx, endpoints.UnfollowRequest{UserID: userID, PharmacyID: pharmacyID})
+	return err
+}
+
+// Watches returns the pharmacies userID follows.
+func (c *PharmacyClient) Watches(ctx context.Context, userID string) ([]Watch, error) {
+	res, err := c.watches(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]Watch), nil
+}
+
+// pageQuery encodes offset and limit, leaving unset ones to the defaults of
+// the service.
+func pageQuery(offset, limit uint64) url.Values {
+	q := url.Values{}
+	if offset > 0 {
+		q.Set("offset", strconv.FormatUint(offset, 10))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+	return q
+}
+
+// fromPaging returns the offset, limit and total of paging.
+func fromPaging(paging *responses.Paging) (offset, limit, total uint64) {
+	if paging == nil {
+		return 0, 0, 0
+	}
+	return uint64(paging.StartIndex), uint64(paging.ItemsPage), uint64(paging.TotalItems)
+}
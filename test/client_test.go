@@ -0,0 +1,146 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cage1016/mask/pkg/client"
+)
+
+func TestClientPharmacy(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+	c, err := client.NewPharmacy(srv.URL, client.Config{Token: adminToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var req client.QueryRequest
+	if err := json.Unmarshal(fixture(t, "test.json"), &req); err != nil {
+		t.Fatal(err)
+	}
+	req.Max = 10
+	items, clusters, err := c.Query(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || clusters != nil {
+		t.Fatalf("query: got %d pharmacies and clusters %+v", len(items), clusters)
+	}
+
+	_, _, err = c.Query(ctx, client.QueryRequest{Zoom: 23})
+	if !errors.Is(err, client.ErrMalformedEntity) {
+		t.Errorf("zoom out of range: got %v, want %v", err, client.ErrMalformedEntity)
+	}
+	if se, ok := err.(client.StatusError); !ok || se.Code != 400 {
+		t.Errorf("zoom out of range: got %#v, want a 400 StatusError", err)
+	}
+
+	body, err := c.Export(ctx, "ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "\n"); n != len(pharmacies) {
+		t.Errorf("export: got %d lines, want %d", n, len(pharmacies))
+	}
+
+	created, err := c.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "https://example.com/hooks/mask"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks, err := c.ListWebhooks(ctx)
+	if err != nil || len(hooks) != 1 || hooks[0].ID != created.ID {
+		t.Fatalf("list: got %+v, %v", hooks, err)
+	}
+	page, err := c.WebhookDeliveries(ctx, client.WebhookDeliveriesRequest{ID: created.ID, Limit: 5})
+	if err != nil || page.Items == nil || page.Limit != 5 {
+		t.Errorf("deliveries: got %+v, %v", page, err)
+	}
+	if err := c.RemoveWebhook(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveWebhook(ctx, created.ID); !errors.Is(err, client.ErrWebhookNotFound) {
+		t.Errorf("remove again: got %v, want %v", err, client.ErrWebhookNotFound)
+	}
+
+	const user = "DT6zkUaztUSFjjIe8IhCO2cDoyL2"
+	if err := c.Follow(ctx, client.FollowRequest{UserID: user, PharmacyID: "5901024883", MaskChild: 20}); err != nil {
+		t.Fatal(err)
+	}
+	watches, err := c.Watches(ctx, user)
+	if err != nil || len(watches) != 1 || watches[0].MaskChild != 20 {
+		t.Fatalf("watches: got %+v, %v", watches, err)
+	}
+	if err := c.Unfollow(ctx, user, "5901024883"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Unfollow(ctx, user, "5901024883"); !errors.Is(err, client.ErrWatchNotFound) {
+		t.Errorf("unfollow again: got %v, want %v", err, client.ErrWatchNotFound)
+	}
+}
+
+func TestClientFeedback(t *testing.T) {
+	srv := newFeedbackServer(t)
+	c, err := client.NewFeedback(srv.URL, client.Config{Token: adminToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	options, err := c.Options(ctx)
+	if err != nil || len(options) == 0 {
+		t.Fatalf("options: got %+v, %v", options, err)
+	}
+
+	var req client.FeedBackRequest
+	if err := json.Unmarshal(fixture(t, "feedback.json"), &req); err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.FeedBack(ctx, req)
+	if err != nil || id == "" {
+		t.Fatalf("insert: got %q, %v", id, err)
+	}
+
+	page, err := c.PharmacyFeedBacks(ctx, client.PharmacyFeedBacksRequest{PharmacyID: req.PharmacyID, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != id || page.Limit != 100 || page.Total != 1 {
+		t.Errorf("by pharmacy: got %+v", page)
+	}
+	if page.Items[0].CreatedAt.IsZero() {
+		t.Error("by pharmacy: got no creation time")
+	}
+
+	_, err = c.UserFeedBacks(ctx, client.UserFeedBacksRequest{UserID: req.UserID, Limit: 101})
+	if !errors.Is(err, client.ErrMalformedEntity) {
+		t.Errorf("limit out of range: got %v, want %v", err, client.ErrMalformedEntity)
+	}
+
+	_, err = c.FeedBack(ctx, client.FeedBackRequest{UserID: req.UserID})
+	if !errors.Is(err, client.ErrMalformedEntity) {
+		t.Errorf("missing pharmacy: got %v, want %v", err, client.ErrMalformedEntity)
+	}
+
+	body, err := c.Export(ctx, client.FeedbackExportRequest{Format: "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), id) {
+		t.Errorf("export: %s missing from %s", id, b)
+	}
+}
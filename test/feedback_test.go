@@ -0,0 +1,206 @@
+package test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/feedback/model"
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+// page is the JSON shape of model.FeedbackItemPage.
+type page struct {
+	model.PageMetadata
+	Items []struct {
+		ID         string `json:"id"`
+		UserID     string `json:"userId"`
+		PharmacyID string `json:"pharmacyId"`
+		OptionID   string `json:"optionId"`
+		CreatedAt  string `json:"createdAt"`
+	} `json:"items"`
+}
+
+func (p page) contains(id string) bool {
+	for _, item := range p.Items {
+		if item.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFeedbackOptions(t *testing.T) {
+	srv := newFeedbackServer(t)
+
+	var res struct {
+		Items []model.Option `json:"items"`
+	}
+	expectData(t, "options", do(t, srv, http.MethodGet, "/api/feedback/options", nil), http.StatusOK, &res)
+	if len(res.Items) == 0 {
+		t.Fatal("options: got none")
+	}
+	for _, o := range res.Items {
+		if o.ID == "" || o.Name == "" {
+			t.Errorf("options: malformed option %+v", o)
+		}
+	}
+}
+
+func TestFeedbackInsertAndRetrieve(t *testing.T) {
+	srv := newFeedbackServer(t)
+	payload := fixture(t, "feedback.json")
+
+	var fb struct {
+		UserID     string `json:"userId"`
+		PharmacyID string `json:"pharmacyId"`
+	}
+	if err := json.Unmarshal(payload, &fb); err != nil {
+		t.Fatal(err)
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	expectData(t, "insert", do(t, srv, http.MethodPost, "/api/feedback", payload), http.StatusOK, &created)
+	if created.ID == "" {
+		t.Fatal("insert: got no id")
+	}
+
+	today := time.Now().In(util.Location).Format(service.QueryDatefmt)
+
+	var res page
+	expectData(t, "by user", do(t, srv, http.MethodGet, "/api/feedback/users/"+fb.UserID+"?date="+today, nil), http.StatusOK, &res)
+	if !res.contains(created.ID) || res.Limit == 0 {
+		t.Errorf("by user: %s missing from %+v", created.ID, res)
+	}
+
+	res = page{}
+	expectData(t, "by pharmacy", do(t, srv, http.MethodGet, "/api/feedback/pharmacies/"+fb.PharmacyID+"?date="+today+"&limit=100", nil), http.StatusOK, &res)
+	if !res.contains(created.ID) || res.Limit != 100 {
+		t.Errorf("by pharmacy: %s missing from %+v", created.ID, res)
+	}
+
+	expectError(t, "malformed date", do(t, srv, http.MethodGet, "/api/feedback/users/"+fb.UserID+"?date=2020-03-20", nil), http.StatusBadRequest)
+	expectError(t, "limit out of range", do(t, srv, http.MethodGet, "/api/feedback/pharmacies/"+fb.PharmacyID+"?limit=101", nil), http.StatusBadRequest)
+	expectError(t, "malformed limit", do(t, srv, http.MethodGet, "/api/feedback/pharmacies/"+fb.PharmacyID+"?limit=ten", nil), http.StatusBadRequest)
+}
+
+func TestFeedbackInsertMalformed(t *testing.T) {
+	srv := newFeedbackServer(t)
+
+	for desc, body := range map[string]string{
+		"truncated body":      `{"userId": "DT6zkUaztUSFjjIe8IhCO2cDoyL2"`,
+		"missing pharmacy":    `{"userId": "DT6zkUaztUSFjjIe8IhCO2cDoyL2", "optionId": "ddCp1m88O4g5SU1GDJRPi"}`,
+		"custom without text": `{"userId": "DT6zkUaztUSFjjIe8IhCO2cDoyL2", "pharmacyId": "5901024883", "optionId": "IRESxM58KC~dqg5XLCH~n"}`,
+		"mistyped longitude":  `{"userId": "DT6zkUaztUSFjjIe8IhCO2cDoyL2", "pharmacyId": "5901024883", "optionId": "ddCp1m88O4g5SU1GDJRPi", "longitude": "121.5"}`,
+	} {
+		expectError(t, desc, do(t, srv, http.MethodPost, "/api/feedback", []byte(body)), http.StatusBadRequest)
+	}
+}
+
+func TestFeedbackExport(t *testing.T) {
+	srv := newFeedbackServer(t)
+	payload := fixture(t, "feedback.json")
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	expectData(t, "insert", do(t, srv, http.MethodPost, "/api/feedback", payload), http.StatusOK, &created)
+
+	today := time.Now().In(util.Location).Format(service.QueryDatefmt)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	for desc, header := range map[string][]string{
+		"without token":  nil,
+		"wrong token":    {"Authorization", "Bearer guess"},
+		"token as basic": {"Authorization", "Basic " + adminToken},
+	} {
+		expectError(t, desc, do(t, srv, http.MethodGet, "/api/feedback/export?from="+today+"&to="+today+"&format=csv", nil, header...), http.StatusUnauthorized)
+	}
+
+	res := do(t, srv, http.MethodGet, "/api/feedback/export?from="+today+"&to="+today+"&format=csv", nil, auth...)
+	if res.status != http.StatusOK {
+		t.Fatalf("csv: got status %d: %s", res.status, res.body)
+	}
+	if ct := res.header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("csv: got content type %q", ct)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(res.body))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 || strings.Join(records[0], ",") != strings.Join(model.FeedbackCSVHeader, ",") {
+		t.Fatalf("csv: got %v", records)
+	}
+
+	res = do(t, srv, http.MethodGet, "/api/feedback/export?from="+today+"&to="+today+"&format=ndjson", nil, auth...)
+	if res.status != http.StatusOK {
+		t.Fatalf("ndjson: got status %d: %s", res.status, res.body)
+	}
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(string(res.body)), "\n") {
+		var f struct {
+			ID     string `json:"id"`
+			UserID string `json:"userId"`
+		}
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			t.Fatalf("ndjson: %s: %s", err, line)
+		}
+		if f.ID == created.ID {
+			found = true
+			if f.UserID == "" || f.UserID == "DT6zkUaztUSFjjIe8IhCO2cDoyL2" {
+				t.Errorf("ndjson: user id %q not pseudonymized", f.UserID)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("ndjson: %s missing from export", created.ID)
+	}
+
+	for desc, query := range map[string]string{
+		"unsupported format": "?from=" + today + "&to=" + today + "&format=xml",
+		"malformed from":     "?from=2020-03-20&to=" + today + "&format=csv",
+		"reversed range":     "?from=2020_0322&to=2020_0320&format=csv",
+		"range too long":     "?from=2020_0101&to=2020_0601&format=csv",
+	} {
+		expectError(t, desc, do(t, srv, http.MethodGet, "/api/feedback/export"+query, nil, auth...), http.StatusBadRequest)
+	}
+}
+
+func TestFeedbackSuspects(t *testing.T) {
+	srv := newFeedbackServer(t)
+	payload := fixture(t, "feedback.json")
+	expectData(t, "insert", do(t, srv, http.MethodPost, "/api/feedback", payload), http.StatusOK, nil)
+
+	var fb struct {
+		UserID string `json:"userId"`
+	}
+	if err := json.Unmarshal(payload, &fb); err != nil {
+		t.Fatal(err)
+	}
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	expectError(t, "list without token", do(t, srv, http.MethodGet, "/api/feedback/suspects", nil), http.StatusUnauthorized)
+	expectError(t, "unban without token", do(t, srv, http.MethodDelete, "/api/feedback/suspects/"+fb.UserID, nil), http.StatusUnauthorized)
+	expectError(t, "limit too large", do(t, srv, http.MethodGet, "/api/feedback/suspects?limit=101", nil, auth...), http.StatusBadRequest)
+
+	var suspects struct {
+		model.PageMetadata
+		Items []model.Suspect `json:"items"`
+	}
+	expectData(t, "list", do(t, srv, http.MethodGet, "/api/feedback/suspects", nil, auth...), http.StatusOK, &suspects)
+	if suspects.Limit != 10 {
+		t.Errorf("list: got limit %d, want the default 10", suspects.Limit)
+	}
+
+	// the report scored the user, so they have a standing to clear
+	if res := do(t, srv, http.MethodDelete, "/api/feedback/suspects/"+fb.UserID, nil, auth...); res.status != http.StatusNoContent {
+		t.Errorf("unban: got status %d: %s", res.status, res.body)
+	}
+	expectError(t, "unban twice", do(t, srv, http.MethodDelete, "/api/feedback/suspects/"+fb.UserID, nil, auth...), http.StatusNotFound)
+}
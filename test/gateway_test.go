@@ -0,0 +1,309 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/cage1016/mask/internal/app/gateway/graph"
+	"github.com/cage1016/mask/internal/app/gateway/transports"
+	"github.com/cage1016/mask/pkg/client"
+)
+
+// countingPharmacies counts the calls of the gateway to the pharmacy service.
+type countingPharmacies struct {
+	graph.PharmacyService
+	queries, exports int32
+}
+
+func (c *countingPharmacies) Query(ctx context.Context, req client.QueryRequest) ([]client.Pharmacy, []client.Cluster, error) {
+	atomic.AddInt32(&c.queries, 1)
+	return c.PharmacyService.Query(ctx, req)
+}
+
+func (c *countingPharmacies) Export(ctx context.Context, format string) (io.ReadCloser, error) {
+	atomic.AddInt32(&c.exports, 1)
+	return c.PharmacyService.Export(ctx, format)
+}
+
+// countingFeedback counts the calls of the gateway to the feedback service.
+type countingFeedback struct {
+	graph.FeedbackService
+	options, pages int32
+}
+
+func (c *countingFeedback) Options(ctx context.Context) ([]client.Option, error) {
+	atomic.AddInt32(&c.options, 1)
+	return c.FeedbackService.Options(ctx)
+}
+
+func (c *countingFeedback) PharmacyFeedBacks(ctx context.Context, req client.PharmacyFeedBacksRequest) (client.FeedbackItemPage, error) {
+	atomic.AddInt32(&c.pages, 1)
+	return c.FeedbackService.PharmacyFeedBacks(ctx, req)
+}
+
+// newGateway boots the gateway over the pharmacy and feedback servers.
+func newGateway(t *testing.T) (*httptest.Server, *countingPharmacies, *countingFeedback) {
+	pc, err := client.NewPharmacy(newPharmacyServer(t, pharmacies).URL, client.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, err := client.NewFeedback(newFeedbackServer(t).URL, client.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, f := &countingPharmacies{PharmacyService: pc}, &countingFeedback{FeedbackService: fc}
+	srv := httptest.NewServer(transports.NewHTTPHandler(graph.NewResolver(p, f, time.Minute), log.NewNopLogger()))
+	t.Cleanup(srv.Close)
+	return srv, p, f
+}
+
+// graphQL runs query and decodes its data into v, failing on any error.
+func graphQL(t *testing.T, srv *httptest.Server, query string, variables map[string]interface{}, v interface{}) {
+	t.Helper()
+	if errs := graphQLErrors(t, srv, query, variables, v); len(errs) > 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+}
+
+// graphQLErrors runs query, decodes its data into v and returns its errors.
+func graphQLErrors(t *testing.T, srv *httptest.Server, query string, variables map[string]interface{}, v interface{}) []string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	res := do(t, srv, http.MethodPost, "/graphql", body)
+	if res.status != http.StatusOK {
+		t.Fatalf("got status %d: %s", res.status, res.body)
+	}
+
+	var out struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(res.body, &out); err != nil {
+		t.Fatal(err)
+	}
+	if v != nil && len(out.Data) > 0 && string(out.Data) != "null" {
+		if err := json.Unmarshal(out.Data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var errs []string
+	for _, e := range out.Errors {
+		errs = append(errs, e.Message)
+	}
+	return errs
+}
+
+func TestGatewayPharmacies(t *testing.T) {
+	srv, p, f := newGateway(t)
+
+	var id struct {
+		SubmitFeedback string `json:"submitFeedback"`
+	}
+	graphQL(t, srv, `mutation($in: FeedbackInput!) { submitFeedback(input: $in) }`, map[string]interface{}{
+		"in": map[string]interface{}{"userId": "DT6zkUaztUSFjjIe8IhCO2cDoyL2", "pharmacyId": "5901024883", "optionId": "ddCp1m88O4g5SU1GDJRPi"},
+	}, &id)
+	if id.SubmitFeedback == "" {
+		t.Fatal("submitFeedback: got no id")
+	}
+
+	var res struct {
+		Pharmacies []struct {
+			ID       string   `json:"id"`
+			Distance *float64 `json:"distance"`
+			Feedback struct {
+				Total int `json:"total"`
+				Items []struct {
+					ID     string `json:"id"`
+					Option *struct {
+						Name string `json:"name"`
+					} `json:"option"`
+				} `json:"items"`
+			} `json:"feedback"`
+		} `json:"pharmacies"`
+		Options []struct {
+			ID string `json:"id"`
+		} `json:"options"`
+	}
+	graphQL(t, srv, `{
+		pharmacies(bounds: {ne: {lat: 25.03, lng: 121.56}, sw: {lat: 25.01, lng: 121.54}}) {
+			id distance
+			feedback(limit: 5) { total items { id option { name } } }
+		}
+		options { id }
+	}`, nil, &res)
+
+	if len(res.Pharmacies) != 2 {
+		t.Fatalf("pharmacies: got %+v, want the two stores within bounds", res.Pharmacies)
+	}
+	for _, ph := range res.Pharmacies {
+		if ph.Distance == nil {
+			t.Errorf("pharmacies: %s has no distance", ph.ID)
+		}
+		if ph.ID != "5901024883" {
+			continue
+		}
+		items := ph.Feedback.Items
+		if ph.Feedback.Total != 1 || len(items) != 1 || items[0].ID != id.SubmitFeedback || items[0].Option == nil || items[0].Option.Name == "" {
+			t.Errorf("feedback: got %+v", ph.Feedback)
+		}
+	}
+	if len(res.Options) == 0 {
+		t.Error("options: got none")
+	}
+
+	if p.queries != 1 || f.pages != 2 || f.options != 1 {
+		t.Errorf("got %d queries, %d feedback pages and %d option lists, want 1, 2 and 1", p.queries, f.pages, f.options)
+	}
+
+	var filtered struct {
+		Pharmacies []struct {
+			ID string `json:"id"`
+		} `json:"pharmacies"`
+	}
+	graphQL(t, srv, `{ pharmacies(bounds: {ne: {lat: 25.03, lng: 121.56}, sw: {lat: 25.01, lng: 121.54}}, filters: {minMaskAdult: 1}) { id } }`, nil, &filtered)
+	if len(filtered.Pharmacies) != 1 || filtered.Pharmacies[0].ID != "5901024883" {
+		t.Errorf("filtered: got %+v, want the stocked store only", filtered.Pharmacies)
+	}
+
+	if errs := graphQLErrors(t, srv, `{ pharmacies(bounds: {ne: {lat: 25.03, lng: 121.56}, sw: {lat: 25.01, lng: 121.54}}, max: 0) { id } }`, nil, nil); len(errs) == 0 {
+		t.Error("max 0: got no error")
+	}
+}
+
+func TestGatewayPharmacy(t *testing.T) {
+	srv, p, _ := newGateway(t)
+
+	var res struct {
+		A *struct {
+			Name     string   `json:"name"`
+			Distance *float64 `json:"distance"`
+		} `json:"a"`
+		B *struct {
+			Name string `json:"name"`
+		} `json:"b"`
+		Missing *struct {
+			Name string `json:"name"`
+		} `json:"missing"`
+	}
+	graphQL(t, srv, `{
+		a: pharmacy(id: "5901024883") { name distance }
+		b: pharmacy(id: "5917010011") { name }
+		missing: pharmacy(id: "0000000000") { name }
+	}`, nil, &res)
+
+	if res.A == nil || res.A.Name != "大安藥局" || res.A.Distance != nil {
+		t.Errorf("a: got %+v", res.A)
+	}
+	if res.B == nil || res.B.Name != "基隆藥局" {
+		t.Errorf("b: got %+v", res.B)
+	}
+	if res.Missing != nil {
+		t.Errorf("missing: got %+v", res.Missing)
+	}
+	if p.exports != 1 {
+		t.Errorf("got %d exports, want one for all three lookups", p.exports)
+	}
+
+	graphQL(t, srv, `{ pharmacy(id: "5901024883") { name } }`, nil, nil)
+	if p.exports != 1 {
+		t.Errorf("got %d exports, want the next request to reuse the index", p.exports)
+	}
+
+	errs := graphQLErrors(t, srv, `mutation { submitFeedback(input: {userId: "DT6zkUaztUSFjjIe8IhCO2cDoyL2", pharmacyId: "", optionId: "ddCp1m88O4g5SU1GDJRPi"}) }`, nil, nil)
+	if len(errs) == 0 {
+		t.Error("submitFeedback without pharmacy: got no error")
+	}
+}
+
+// dayFeedback pages through totals[date] feedbacks of any pharmacy, with ids
+// made of the date and the position in the day.
+type dayFeedback struct {
+	graph.FeedbackService
+	totals map[string]uint64
+}
+
+func (d dayFeedback) PharmacyFeedBacks(_ context.Context, req client.PharmacyFeedBacksRequest) (client.FeedbackItemPage, error) {
+	page := client.FeedbackItemPage{Items: []client.Feedback{}}
+	page.Total, page.Offset, page.Limit = d.totals[req.Date], req.Offset, req.Limit
+	for i := req.Offset; i < page.Total && i < req.Offset+req.Limit; i++ {
+		page.Items = append(page.Items, client.Feedback{ID: fmt.Sprintf("%s-%d", req.Date, i)})
+	}
+	return page, nil
+}
+
+func TestGatewayFeedbackRange(t *testing.T) {
+	pc, err := client.NewPharmacy(newPharmacyServer(t, pharmacies).URL, client.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &countingFeedback{FeedbackService: dayFeedback{totals: map[string]uint64{
+		"2020_0301": 3, "2020_0303": 2, "2020_0304": 1, "2020_0305": 150,
+	}}}
+	srv := httptest.NewServer(transports.NewHTTPHandler(graph.NewResolver(pc, f, time.Minute), log.NewNopLogger()))
+	t.Cleanup(srv.Close)
+
+	for _, tc := range []struct {
+		name, args string
+		pages      int32
+		total      int
+		want       []string
+	}{
+		{name: "across days", args: `from: "2020_0301", to: "2020_0303", offset: 1, limit: 3`, pages: 3, total: 5,
+			want: []string{"2020_0303-1", "2020_0301-0", "2020_0301-1"}},
+		{name: "deeper than a page", args: `from: "2020_0304", to: "2020_0305", offset: 149, limit: 2`, pages: 3, total: 151,
+			want: []string{"2020_0305-149", "2020_0304-0"}},
+		{name: "past the end", args: `from: "2020_0301", to: "2020_0302", offset: 3`, pages: 2, total: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&f.pages, 0)
+
+			var res struct {
+				Pharmacy struct {
+					Feedback struct {
+						Total int `json:"total"`
+						Items []struct {
+							ID string `json:"id"`
+						} `json:"items"`
+					} `json:"feedback"`
+				} `json:"pharmacy"`
+			}
+			graphQL(t, srv, `{ pharmacy(id: "5901024883") { feedback(`+tc.args+`) { total items { id } } } }`, nil, &res)
+
+			var got []string
+			for _, item := range res.Pharmacy.Feedback.Items {
+				got = append(got, item.ID)
+			}
+			if res.Pharmacy.Feedback.Total != tc.total || fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("got total %d and %v, want %d and %v", res.Pharmacy.Feedback.Total, got, tc.total, tc.want)
+			}
+			if f.pages != tc.pages {
+				t.Errorf("got %d feedback pages, want %d", f.pages, tc.pages)
+			}
+		})
+	}
+
+	for _, args := range []string{
+		`from: "2020_0301"`,
+		`date: "2020_0301", from: "2020_0301", to: "2020_0302"`,
+		`from: "2020_0302", to: "2020_0301"`,
+		`from: "2020_0101", to: "2020_0201"`,
+		`from: "2020-03-01", to: "2020-03-02"`,
+	} {
+		if errs := graphQLErrors(t, srv, `{ pharmacy(id: "5901024883") { feedback(`+args+`) { total } } }`, nil, nil); len(errs) == 0 {
+			t.Errorf("feedback(%s): got no error", args)
+		}
+	}
+}
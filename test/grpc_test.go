@@ -0,0 +1,201 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	feedbackAbuse "github.com/cage1016/mask/internal/app/feedback/abuse"
+	feedbackEndpoints "github.com/cage1016/mask/internal/app/feedback/endpoints"
+	feedbackMemory "github.com/cage1016/mask/internal/app/feedback/memory"
+	feedbackNanoid "github.com/cage1016/mask/internal/app/feedback/nanoid"
+	"github.com/cage1016/mask/internal/app/feedback/pseudonym"
+	feedbackService "github.com/cage1016/mask/internal/app/feedback/service"
+	feedbackTransports "github.com/cage1016/mask/internal/app/feedback/transports"
+	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/memory"
+	"github.com/cage1016/mask/internal/app/pharmacy/nanoid"
+	"github.com/cage1016/mask/internal/app/pharmacy/notify"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/app/pharmacy/stream"
+	"github.com/cage1016/mask/internal/app/pharmacy/transports"
+	"github.com/cage1016/mask/internal/app/pharmacy/webhook"
+	"github.com/cage1016/mask/internal/pkg/auth"
+	"github.com/cage1016/mask/internal/pkg/logging"
+	"github.com/cage1016/mask/internal/pkg/util"
+	feedbackpb "github.com/cage1016/mask/pb/feedback"
+	pharmacypb "github.com/cage1016/mask/pb/pharmacy"
+)
+
+// dialGRPC serves register on an in-process listener, behind the
+// interceptors of the services, and dials it.
+func dialGRPC(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(), auth.UnaryServerInterceptor(adminToken)),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(), auth.StreamServerInterceptor(adminToken)),
+	)
+	register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPCPharmacyQuery(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := memory.New()
+	repo.AddSnapshot(snapshotTable, pharmacies)
+	webhooks := memory.NewWebhookRepository()
+	svc := service.New(repo, webhooks, memory.NewWatchRepository(), nanoid.New(), webhook.New(webhooks, nil, 1, time.Millisecond, logger), notify.NewLogSender(logger), stream.New(), logger)
+
+	conn := dialGRPC(t, func(s *grpc.Server) {
+		pharmacypb.RegisterPharmacyServiceServer(s, transports.NewGRPCServer(endpoints.New(svc, adminToken, logger), logger))
+	})
+	client := pharmacypb.NewPharmacyServiceClient(conn)
+
+	if _, err := client.Query(context.Background(), &pharmacypb.QueryRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without token: got %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+adminToken)
+
+	bounds := &pharmacypb.Bounds{
+		Ne: &pharmacypb.LatLng{Lat: 25.030010121228962, Lng: 121.55707598240215},
+		Se: &pharmacypb.LatLng{Lat: 25.01188837082065, Lng: 121.55707598240215},
+		Sw: &pharmacypb.LatLng{Lat: 25.01188837082065, Lng: 121.53915882618267},
+		Nw: &pharmacypb.LatLng{Lat: 25.030010121228962, Lng: 121.53915882618267},
+	}
+	rep, err := client.Query(ctx, &pharmacypb.QueryRequest{
+		Center: &pharmacypb.LatLng{Lat: 25.02094958043129, Lng: 121.54811740429241},
+		Bounds: bounds,
+		Max:    10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Items) != 2 || rep.Items[0].Id != "5901024883" || rep.Items[0].MaskAdult != 120 || len(rep.Clusters) != 0 {
+		t.Errorf("query: got %v", rep)
+	}
+
+	rep, err = client.Query(ctx, &pharmacypb.QueryRequest{Bounds: bounds, Zoom: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Items) != 0 || len(rep.Clusters) == 0 {
+		t.Errorf("clusters: got %v", rep)
+	}
+
+	_, err = client.Query(ctx, &pharmacypb.QueryRequest{Zoom: 23})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("zoom out of range: got %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCFeedback(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := feedbackMemory.New()
+	suspects := feedbackMemory.NewSuspectRepository(repo)
+	scorer := feedbackAbuse.New(repo, suspects, nil, feedbackAbuse.Config{})
+	svc := feedbackService.New(repo, suspects, feedbackNanoid.New(), pseudonym.New("key"), scorer, logger)
+
+	conn := dialGRPC(t, func(s *grpc.Server) {
+		feedbackpb.RegisterFeedbackServiceServer(s, feedbackTransports.NewGRPCServer(feedbackEndpoints.New(svc, adminToken, logger), logger))
+	})
+	client := feedbackpb.NewFeedbackServiceClient(conn)
+
+	if _, err := client.Options(context.Background(), &feedbackpb.OptionsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without token: got %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+adminToken)
+
+	options, err := client.Options(ctx, &feedbackpb.OptionsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options.Items) == 0 {
+		t.Fatal("options: got none")
+	}
+
+	const user = "DT6zkUaztUSFjjIe8IhCO2cDoyL2"
+	inserted, err := client.InsertFeedBack(ctx, &feedbackpb.InsertFeedBackRequest{UserId: user, PharmacyId: "5901024883", OptionId: "ddCp1m88O4g5SU1GDJRPi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.InsertFeedBack(ctx, &feedbackpb.InsertFeedBackRequest{UserId: user}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("insert malformed: got %v, want InvalidArgument", err)
+	}
+
+	today := time.Now().In(util.Location).Format(feedbackService.QueryDatefmt)
+	byUser, err := client.UserFeedBacks(ctx, &feedbackpb.UserFeedBacksRequest{UserId: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPharmacy, err := client.PharmacyFeedBacks(ctx, &feedbackpb.PharmacyFeedBacksRequest{PharmacyId: "5901024883", Date: today, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for desc, page := range map[string]*feedbackpb.FeedbackPage{"by user": byUser, "by pharmacy": byPharmacy} {
+		if page.Total != 1 || len(page.Items) != 1 || page.Items[0].Id != inserted.Id || page.Items[0].CreatedAt == "" {
+			t.Errorf("%s: got %v", desc, page)
+		}
+	}
+	if byUser.Limit != 10 || byPharmacy.Limit != 100 {
+		t.Errorf("got limits %d and %d, want 10 and 100", byUser.Limit, byPharmacy.Limit)
+	}
+	if _, err := client.UserFeedBacks(ctx, &feedbackpb.UserFeedBacksRequest{UserId: user, Date: "2020-03-20"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("malformed date: got %v, want InvalidArgument", err)
+	}
+
+	stream, err := client.Export(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer guess"), &feedbackpb.ExportRequest{From: today, To: today})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("export with a wrong token: got %v, want Unauthenticated", err)
+	}
+
+	stream, err = client.Export(ctx, &feedbackpb.ExportRequest{From: today, To: today})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []*feedbackpb.FeedbackItem
+	for {
+		f, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, f)
+	}
+	if len(exported) != 1 || exported[0].Id != inserted.Id || exported[0].UserId == user {
+		t.Errorf("export: got %v", exported)
+	}
+
+	stream, err = client.Export(ctx, &feedbackpb.ExportRequest{From: "2020_0322", To: "2020_0320"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("reversed range: got %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,298 @@
+// Package test replays the request payloads of this directory against the
+// HTTP handlers of the services and asserts their contract: status codes,
+// envelopes and response shapes.
+//
+// The services run on the in-memory repositories, or on the pgtest database
+// when MASK_TEST_DB_* is set.
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gomurphyx/sqlx"
+
+	feedbackAbuse "github.com/cage1016/mask/internal/app/feedback/abuse"
+	feedbackEndpoints "github.com/cage1016/mask/internal/app/feedback/endpoints"
+	feedbackMemory "github.com/cage1016/mask/internal/app/feedback/memory"
+	feedbackModel "github.com/cage1016/mask/internal/app/feedback/model"
+	feedbackNanoid "github.com/cage1016/mask/internal/app/feedback/nanoid"
+	feedbackPostgres "github.com/cage1016/mask/internal/app/feedback/postgres"
+	"github.com/cage1016/mask/internal/app/feedback/pseudonym"
+	feedbackService "github.com/cage1016/mask/internal/app/feedback/service"
+	feedbackTransports "github.com/cage1016/mask/internal/app/feedback/transports"
+	"github.com/cage1016/mask/internal/app/pharmacy/endpoints"
+	"github.com/cage1016/mask/internal/app/pharmacy/memory"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/app/pharmacy/nanoid"
+	"github.com/cage1016/mask/internal/app/pharmacy/notify"
+	"github.com/cage1016/mask/internal/app/pharmacy/postgres"
+	"github.com/cage1016/mask/internal/app/pharmacy/service"
+	"github.com/cage1016/mask/internal/app/pharmacy/stream"
+	"github.com/cage1016/mask/internal/app/pharmacy/transports"
+	"github.com/cage1016/mask/internal/app/pharmacy/webhook"
+	"github.com/cage1016/mask/internal/pkg/postgres/pgtest"
+)
+
+// adminToken guards the admin routes of the services under test.
+const adminToken = "admin-token"
+
+// snapshotTable outranks the real snapshots in latest_pharmacy_table.
+const snapshotTable = "pharmacy_9999_0101"
+
+// testDB connects to the pgtest database, or returns nil when none is
+// configured.
+func testDB(t *testing.T) *sqlx.DB {
+	db := pgtest.Open(t)
+	if db == nil {
+		return nil
+	}
+
+	if err := feedbackPostgres.CreatePartitions(context.Background(), db, time.Now(), 1); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newPharmacyServer boots the pharmacy handler on snapshotTable holding
+// pharmacies.
+func newPharmacyServer(t *testing.T, pharmacies []model.Pharmacy) *httptest.Server {
+	logger := log.NewNopLogger()
+
+	var (
+		repo     model.PharmacyRepository
+		webhooks model.WebhookRepository
+		watches  model.WatchRepository
+	)
+	if db := testDB(t); db != nil {
+		repo = postgres.New(db, logger)
+		webhooks = postgres.NewWebhookRepository(db, logger)
+		watches = postgres.NewWatchRepository(db, logger)
+		addSnapshot(t, db, pharmacies)
+	} else {
+		mem := memory.New()
+		mem.AddSnapshot(snapshotTable, pharmacies)
+		repo = mem
+		webhooks = memory.NewWebhookRepository()
+		watches = memory.NewWatchRepository()
+	}
+
+	publisher := webhook.New(webhooks, &http.Client{Timeout: time.Second}, 1, time.Millisecond, logger)
+	svc := service.New(repo, webhooks, watches, nanoid.New(), publisher, notify.NewLogSender(logger), stream.New(), logger)
+	srv := httptest.NewServer(transports.NewHTTPHandler(endpoints.New(svc, adminToken, logger), adminToken, logger))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func addSnapshot(t *testing.T, db *sqlx.DB, pharmacies []model.Pharmacy) {
+	for _, q := range []string{
+		`drop table if exists ` + snapshotTable,
+		`create table ` + snapshotTable + ` (like pharmacies including all)`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { db.Exec(`drop table if exists ` + snapshotTable) })
+
+	q := `insert into ` + snapshotTable + ` (id, name, address, mask_adult, mask_child, longitude, latitude)
+		values (:id, :name, :address, :mask_adult, :mask_child, :longitude, :latitude)`
+	for _, p := range pharmacies {
+		if _, err := db.NamedExec(q, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newFeedbackServer boots the feedback handler.
+func newFeedbackServer(t *testing.T) *httptest.Server {
+	logger := log.NewNopLogger()
+
+	var repo feedbackModel.FeedbackRepository
+	var suspects feedbackModel.SuspectRepository
+	if db := testDB(t); db != nil {
+		repo = feedbackPostgres.New(db, logger)
+		suspects = feedbackPostgres.NewSuspectRepository(db, logger)
+	} else {
+		repo = feedbackMemory.New()
+		suspects = feedbackMemory.NewSuspectRepository(repo)
+	}
+
+	scorer := feedbackAbuse.New(repo, suspects, nil, feedbackAbuse.Config{})
+	svc := feedbackService.New(repo, suspects, feedbackNanoid.New(), pseudonym.New("key"), scorer, logger)
+	srv := httptest.NewServer(feedbackTransports.NewHTTPHandler(feedbackEndpoints.New(svc, adminToken, logger), adminToken, logger))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fixture reads a request payload of this directory.
+func fixture(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do sends a request and reads the whole response.
+func do(t *testing.T, srv *httptest.Server, method, path string, body []byte, header ...string) response {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response{res.StatusCode, res.Header, b}
+}
+
+// dataEnvelope is the success envelope of the JSON responses.
+type dataEnvelope struct {
+	APIVersion *string         `json:"apiVersion"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// errorEnvelope is the error envelope of the JSON responses.
+type errorEnvelope struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// expectData asserts a JSON success envelope with status and decodes its
+// data into v, when not nil.
+func expectData(t *testing.T, desc string, res response, status int, v interface{}) {
+	t.Helper()
+	if res.status != status {
+		t.Fatalf("%s: got status %d, want %d: %s", desc, res.status, status, res.body)
+	}
+	if ct := res.header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("%s: got content type %q", desc, ct)
+	}
+
+	var env dataEnvelope
+	if err := json.Unmarshal(res.body, &env); err != nil {
+		t.Fatalf("%s: %s: %s", desc, err, res.body)
+	}
+	if env.APIVersion == nil {
+		t.Errorf("%s: apiVersion missing from %s", desc, res.body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(env.Data, v); err != nil {
+			t.Fatalf("%s: data %s: %s", desc, err, env.Data)
+		}
+	}
+}
+
+// expectError asserts a JSON error envelope carrying status.
+func expectError(t *testing.T, desc string, res response, status int) {
+	t.Helper()
+	if res.status != status {
+		t.Fatalf("%s: got status %d, want %d: %s", desc, res.status, status, res.body)
+	}
+
+	var env errorEnvelope
+	if err := json.Unmarshal(res.body, &env); err != nil {
+		t.Fatalf("%s: %s: %s", desc, err, res.body)
+	}
+	if env.Error == nil || env.Error.Code != status || env.Error.Message == "" || len(env.Error.Errors) == 0 {
+		t.Errorf("%s: malformed error envelope %s", desc, res.body)
+	}
+}
+
+// envelope is the single envelope of the /api/v2 routes.
+type envelope struct {
+	APIVersion *string         `json:"apiVersion"`
+	Data       json.RawMessage `json:"data"`
+	Paging     *struct {
+		CurrentItemCount int64 `json:"currentItemCount"`
+		ItemsPage        int64 `json:"itemsPage"`
+		StartIndex       int64 `json:"startIndex"`
+		TotalItems       int64 `json:"totalItems"`
+	} `json:"paging"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// expectEnvelope asserts a successful /api/v2 envelope with status, decodes
+// its data into v, when not nil, and returns the envelope.
+func expectEnvelope(t *testing.T, desc string, res response, status int, v interface{}) envelope {
+	t.Helper()
+	if res.status != status {
+		t.Fatalf("%s: got status %d, want %d: %s", desc, res.status, status, res.body)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(res.body, &env); err != nil {
+		t.Fatalf("%s: %s: %s", desc, err, res.body)
+	}
+	if env.APIVersion == nil || env.Data == nil || env.Error != nil {
+		t.Fatalf("%s: malformed envelope %s", desc, res.body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(env.Data, v); err != nil {
+			t.Fatalf("%s: data %s: %s", desc, err, env.Data)
+		}
+	}
+	return env
+}
+
+// expectEnvelopeError asserts a failed /api/v2 envelope carrying status.
+func expectEnvelopeError(t *testing.T, desc string, res response, status int) {
+	t.Helper()
+	if res.status != status {
+		t.Fatalf("%s: got status %d, want %d: %s", desc, res.status, status, res.body)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(res.body, &env); err != nil {
+		t.Fatalf("%s: %s: %s", desc, err, res.body)
+	}
+	if env.APIVersion == nil || env.Data != nil || env.Paging != nil {
+		t.Errorf("%s: malformed envelope %s", desc, res.body)
+	}
+	if env.Error == nil || env.Error.Code != status || env.Error.Message == "" || len(env.Error.Errors) == 0 {
+		t.Errorf("%s: malformed error %s", desc, res.body)
+	}
+}
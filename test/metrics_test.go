@@ -0,0 +1,31 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsRequireAdminToken(t *testing.T) {
+	servers := map[string]*httptest.Server{
+		"pharmacy": newPharmacyServer(t, pharmacies),
+		"feedback": newFeedbackServer(t),
+	}
+	cases := []struct {
+		desc   string
+		header []string
+		status int
+	}{
+		{"without token", nil, http.StatusUnauthorized},
+		{"wrong token", []string{"Authorization", "Bearer guess"}, http.StatusUnauthorized},
+		{"admin token", []string{"Authorization", "Bearer " + adminToken}, http.StatusOK},
+	}
+
+	for name, srv := range servers {
+		for _, c := range cases {
+			if res := do(t, srv, http.MethodGet, "/metrics", nil, c.header...); res.status != c.status {
+				t.Errorf("%s %s: got status %d, want %d", name, c.desc, res.status, c.status)
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+)
+
+// pharmacies lie within the bounds of test.json, except for Keelung.
+var pharmacies = []model.Pharmacy{
+	{Id: "5901024883", Name: "大安藥局", Address: "臺北市大安區", MaskAdult: 120, MaskChild: 20, Longitude: 121.5481, Latitude: 25.0209},
+	{Id: "5901024884", Name: "和平藥局", Address: "臺北市大安區", MaskAdult: 0, MaskChild: 0, Longitude: 121.5501, Latitude: 25.0251},
+	{Id: "5917010011", Name: "基隆藥局", Address: "基隆市仁愛區", MaskAdult: 50, MaskChild: 10, Longitude: 121.7420, Latitude: 25.1283},
+}
+
+// pharmacy is the JSON shape of model.Pharmacy, whose updated time is
+// rendered as RFC 3339 text.
+type pharmacy struct {
+	ID        string  `json:"id"`
+	Distance  float64 `json:"distance"`
+	Name      string  `json:"name"`
+	MaskAdult uint64  `json:"maskAdult"`
+	MaskChild uint64  `json:"maskChild"`
+	Updated   string  `json:"updated"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+func TestPharmacyQuery(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+	payload := fixture(t, "test.json")
+
+	var res struct {
+		Items    []pharmacy      `json:"items"`
+		Clusters []model.Cluster `json:"clusters"`
+	}
+	expectData(t, "query", do(t, srv, http.MethodPost, "/api/pharmacies", payload), http.StatusOK, &res)
+	if len(res.Items) != 1 || res.Items[0].ID != "5901024883" {
+		t.Fatalf("query: got %+v, want the nearest pharmacy only", res.Items)
+	}
+	if res.Clusters != nil {
+		t.Errorf("query: got clusters %+v without zoom", res.Clusters)
+	}
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		t.Fatal(err)
+	}
+	req["max"] = 10
+	b, _ := json.Marshal(req)
+	expectData(t, "query within bounds", do(t, srv, http.MethodPost, "/api/pharmacies", b), http.StatusOK, &res)
+	if len(res.Items) != 2 {
+		t.Errorf("query within bounds: got %d pharmacies, want 2", len(res.Items))
+	}
+
+	expectError(t, "truncated body", do(t, srv, http.MethodPost, "/api/pharmacies", payload[:len(payload)/2]), http.StatusBadRequest)
+	expectError(t, "zoom out of range", do(t, srv, http.MethodPost, "/api/pharmacies", []byte(`{"zoom": 23}`)), http.StatusBadRequest)
+}
+
+func TestPharmacyClusters(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+
+	body := []byte(`{
+		"bounds": {
+			"ne": {"lat": 25.3, "lng": 122.0},
+			"se": {"lat": 21.9, "lng": 122.0},
+			"sw": {"lat": 21.9, "lng": 120.0},
+			"nw": {"lat": 25.3, "lng": 120.0}
+		},
+		"zoom": 8
+	}`)
+
+	var res struct {
+		Items    []pharmacy      `json:"items"`
+		Clusters []model.Cluster `json:"clusters"`
+	}
+	expectData(t, "clusters", do(t, srv, http.MethodPost, "/api/pharmacies", body), http.StatusOK, &res)
+	if len(res.Clusters) == 0 {
+		t.Fatal("clusters: got none")
+	}
+
+	var count, adult uint64
+	for _, c := range res.Clusters {
+		count += c.Count
+		adult += c.MaskAdult
+	}
+	if count != 3 || adult != 170 {
+		t.Errorf("clusters: got %d pharmacies with %d adult masks, want 3 with 170", count, adult)
+	}
+}
+
+func TestPharmacyGeoJSON(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+
+	res := do(t, srv, http.MethodPost, "/api/pharmacies", fixture(t, "test.json"), "Accept", "application/geo+json")
+	if res.status != http.StatusOK {
+		t.Fatalf("got status %d: %s", res.status, res.body)
+	}
+	if ct := res.header.Get("Content-Type"); !strings.HasPrefix(ct, "application/geo+json") {
+		t.Errorf("got content type %q", ct)
+	}
+
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(res.body, &fc); err != nil {
+		t.Fatalf("%s: %s", err, res.body)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 1 {
+		t.Fatalf("got %s", res.body)
+	}
+	if f := fc.Features[0]; f.Type != "Feature" || f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) != 2 {
+		t.Errorf("malformed feature %+v", f)
+	}
+}
+
+func TestPharmacyExport(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+
+	res := do(t, srv, http.MethodGet, "/api/pharmacies/export?format=csv", nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("got status %d: %s", res.status, res.body)
+	}
+	if ct := res.header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("got content type %q", ct)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(res.body))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(pharmacies)+1 || strings.Join(records[0], ",") != strings.Join(model.PharmacyCSVHeader, ",") {
+		t.Errorf("got %v", records)
+	}
+
+	res = do(t, srv, http.MethodGet, "/api/pharmacies/export?format=ndjson", nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("ndjson: got status %d: %s", res.status, res.body)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(res.body)), "\n"); len(lines) != len(pharmacies) {
+		t.Errorf("ndjson: got %d lines, want %d", len(lines), len(pharmacies))
+	}
+
+	expectError(t, "unsupported format", do(t, srv, http.MethodGet, "/api/pharmacies/export?format=xml", nil), http.StatusBadRequest)
+}
+
+func TestPharmacyWebhooks(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	for desc, header := range map[string][]string{
+		"without token": nil,
+		"wrong token":   {"Authorization", "Bearer guess"},
+	} {
+		expectError(t, desc, do(t, srv, http.MethodGet, "/api/pharmacies/webhooks", nil, header...), http.StatusUnauthorized)
+	}
+
+	var created model.Webhook
+	body := []byte(`{"url": "https://example.com/hooks/mask", "events": ["restocked", "sold_out"]}`)
+	expectData(t, "create", do(t, srv, http.MethodPost, "/api/pharmacies/webhooks", body, auth...), http.StatusCreated, &created)
+	if created.ID == "" || created.Secret == "" || created.URL != "https://example.com/hooks/mask" {
+		t.Fatalf("create: got %+v", created)
+	}
+
+	var list struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	expectData(t, "list", do(t, srv, http.MethodGet, "/api/pharmacies/webhooks", nil, auth...), http.StatusOK, &list)
+	if len(list.Items) != 1 || list.Items[0]["id"] != created.ID {
+		t.Fatalf("list: got %+v", list.Items)
+	}
+	if _, ok := list.Items[0]["secret"]; ok {
+		t.Error("list: secret disclosed")
+	}
+
+	var deliveries model.DeliveryPage
+	expectData(t, "deliveries", do(t, srv, http.MethodGet, "/api/pharmacies/webhooks/"+created.ID+"/deliveries", nil, auth...), http.StatusOK, &deliveries)
+	if deliveries.Items == nil || deliveries.Total != 0 {
+		t.Errorf("deliveries: got %+v", deliveries)
+	}
+
+	res := do(t, srv, http.MethodDelete, "/api/pharmacies/webhooks/"+created.ID, nil, auth...)
+	if res.status != http.StatusNoContent || len(res.body) != 0 {
+		t.Fatalf("remove: got status %d: %s", res.status, res.body)
+	}
+	expectError(t, "remove again", do(t, srv, http.MethodDelete, "/api/pharmacies/webhooks/"+created.ID, nil, auth...), http.StatusNotFound)
+
+	expectError(t, "malformed url", do(t, srv, http.MethodPost, "/api/pharmacies/webhooks", []byte(`{"url": "ftp://example.com"}`), auth...), http.StatusBadRequest)
+	expectError(t, "unknown event", do(t, srv, http.MethodPost, "/api/pharmacies/webhooks", []byte(`{"url": "https://example.com", "events": ["opened"]}`), auth...), http.StatusBadRequest)
+}
+
+func TestPharmacyWatches(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+
+	const user = "DT6zkUaztUSFjjIe8IhCO2cDoyL2"
+	body := []byte(`{"userId": "` + user + `", "pharmacyId": "5901024883", "maskAdult": 0, "maskChild": 20}`)
+	res := do(t, srv, http.MethodPost, "/api/pharmacies/watches", body)
+	if res.status != http.StatusNoContent || len(res.body) != 0 {
+		t.Fatalf("follow: got status %d: %s", res.status, res.body)
+	}
+
+	var watches struct {
+		Items []model.Watch `json:"items"`
+	}
+	expectData(t, "watches", do(t, srv, http.MethodGet, "/api/pharmacies/watches/"+user, nil), http.StatusOK, &watches)
+	if len(watches.Items) != 1 || watches.Items[0].PharmacyID != "5901024883" || watches.Items[0].MaskChild != 20 {
+		t.Fatalf("watches: got %+v", watches.Items)
+	}
+
+	res = do(t, srv, http.MethodDelete, "/api/pharmacies/watches/"+user+"/5901024883", nil)
+	if res.status != http.StatusNoContent {
+		t.Fatalf("unfollow: got status %d: %s", res.status, res.body)
+	}
+	expectError(t, "unfollow again", do(t, srv, http.MethodDelete, "/api/pharmacies/watches/"+user+"/5901024883", nil), http.StatusNotFound)
+
+	expectData(t, "no watches", do(t, srv, http.MethodGet, "/api/pharmacies/watches/"+user, nil), http.StatusOK, &watches)
+	if watches.Items == nil || len(watches.Items) != 0 {
+		t.Errorf("no watches: got %+v", watches.Items)
+	}
+
+	expectError(t, "missing threshold", do(t, srv, http.MethodPost, "/api/pharmacies/watches", []byte(`{"userId": "`+user+`", "pharmacyId": "5901024883"}`)), http.StatusBadRequest)
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cage1016/mask/internal/app/feedback/service"
+	"github.com/cage1016/mask/internal/app/pharmacy/model"
+	"github.com/cage1016/mask/internal/pkg/util"
+)
+
+func TestV2Pharmacy(t *testing.T) {
+	srv := newPharmacyServer(t, pharmacies)
+
+	var query struct {
+		Items []pharmacy `json:"items"`
+	}
+	expectEnvelope(t, "query", do(t, srv, http.MethodPost, "/api/v2/pharmacies", fixture(t, "test.json")), http.StatusOK, &query)
+	if len(query.Items) != 1 || query.Items[0].ID != "5901024883" {
+		t.Errorf("query: got %+v", query.Items)
+	}
+	expectEnvelopeError(t, "query", do(t, srv, http.MethodPost, "/api/v2/pharmacies", []byte(`{"zoom": 23}`)), http.StatusBadRequest)
+
+	res := do(t, srv, http.MethodPost, "/api/v2/pharmacies", fixture(t, "test.json"), "Accept", "application/geo+json")
+	if ct := res.header.Get("Content-Type"); res.status != http.StatusOK || !strings.HasPrefix(ct, "application/geo+json") {
+		t.Errorf("geojson: got status %d, content type %q", res.status, ct)
+	}
+
+	var webhook model.Webhook
+	body := []byte(`{"url": "https://example.com/hooks/mask"}`)
+	expectEnvelope(t, "create webhook", do(t, srv, http.MethodPost, "/api/v2/pharmacies/webhooks", body, "Authorization", "Bearer "+adminToken), http.StatusCreated, &webhook)
+
+	var webhooks []model.Webhook
+	env := expectEnvelope(t, "list webhooks", do(t, srv, http.MethodGet, "/api/v2/pharmacies/webhooks", nil, "Authorization", "Bearer "+adminToken), http.StatusOK, &webhooks)
+	if len(webhooks) != 1 || webhooks[0].ID != webhook.ID || env.Paging != nil {
+		t.Errorf("list webhooks: got %+v", webhooks)
+	}
+
+	var deliveries []model.Delivery
+	env = expectEnvelope(t, "deliveries", do(t, srv, http.MethodGet, "/api/v2/pharmacies/webhooks/"+webhook.ID+"/deliveries?offset=5&limit=20", nil, "Authorization", "Bearer "+adminToken), http.StatusOK, &deliveries)
+	if p := env.Paging; p == nil || p.CurrentItemCount != 0 || p.ItemsPage != 20 || p.StartIndex != 5 || p.TotalItems != 0 {
+		t.Errorf("deliveries: got paging %+v", p)
+	}
+
+	if res := do(t, srv, http.MethodDelete, "/api/v2/pharmacies/webhooks/"+webhook.ID, nil, "Authorization", "Bearer "+adminToken); res.status != http.StatusNoContent {
+		t.Errorf("remove webhook: got status %d: %s", res.status, res.body)
+	}
+	expectEnvelopeError(t, "remove webhook", do(t, srv, http.MethodDelete, "/api/v2/pharmacies/webhooks/"+webhook.ID, nil, "Authorization", "Bearer "+adminToken), http.StatusNotFound)
+
+	var watches []model.Watch
+	expectEnvelope(t, "watches", do(t, srv, http.MethodGet, "/api/v2/pharmacies/watches/nobody", nil), http.StatusOK, &watches)
+	if watches == nil || len(watches) != 0 {
+		t.Errorf("watches: got %+v", watches)
+	}
+
+	expectEnvelopeError(t, "export", do(t, srv, http.MethodGet, "/api/v2/pharmacies/export?format=xml", nil), http.StatusBadRequest)
+}
+
+func TestV2Feedback(t *testing.T) {
+	srv := newFeedbackServer(t)
+
+	var options []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	expectEnvelope(t, "options", do(t, srv, http.MethodGet, "/api/v2/feedback/options", nil), http.StatusOK, &options)
+	if len(options) == 0 {
+		t.Error("options: got none")
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	expectEnvelope(t, "insert", do(t, srv, http.MethodPost, "/api/v2/feedback", fixture(t, "feedback.json")), http.StatusOK, &created)
+	expectEnvelopeError(t, "insert", do(t, srv, http.MethodPost, "/api/v2/feedback", []byte(`{}`)), http.StatusBadRequest)
+
+	var fb struct {
+		UserID     string `json:"userId"`
+		PharmacyID string `json:"pharmacyId"`
+	}
+	if err := json.Unmarshal(fixture(t, "feedback.json"), &fb); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().In(util.Location).Format(service.QueryDatefmt)
+
+	for desc, path := range map[string]string{
+		"by user":     "/api/v2/feedback/users/" + fb.UserID + "?date=" + today + "&limit=100",
+		"by pharmacy": "/api/v2/feedback/pharmacies/" + fb.PharmacyID + "?date=" + today + "&limit=100",
+	} {
+		var items []struct {
+			ID string `json:"id"`
+		}
+		env := expectEnvelope(t, desc, do(t, srv, http.MethodGet, path, nil), http.StatusOK, &items)
+
+		var found bool
+		for _, item := range items {
+			found = found || item.ID == created.ID
+		}
+		if !found {
+			t.Errorf("%s: %s missing from %s", desc, created.ID, env.Data)
+		}
+		if p := env.Paging; p == nil || p.CurrentItemCount != int64(len(items)) || p.ItemsPage != 100 || p.StartIndex != 0 || p.TotalItems < 1 {
+			t.Errorf("%s: got paging %+v", desc, p)
+		}
+	}
+
+	expectEnvelopeError(t, "by user", do(t, srv, http.MethodGet, "/api/v2/feedback/users/"+fb.UserID+"?limit=ten", nil), http.StatusBadRequest)
+	expectEnvelopeError(t, "export", do(t, srv, http.MethodGet, "/api/v2/feedback/export?from=2020_0322&to=2020_0320&format=csv", nil, "Authorization", "Bearer "+adminToken), http.StatusBadRequest)
+}